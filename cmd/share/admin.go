@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	adminRevokeActionID = "admin_revoke"
+	adminCancelActionID = "admin_cancel"
+)
+
+const adminUsage = "Usage: `/share-admin list [page]` or `/share-admin revoke <age>`, such as `/share-admin revoke 7d`"
+
+// userIDPattern matches the Slack user IDs ADMIN_USERS may list.
+var userIDPattern = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// isAdmin reports whether userID is one of the ADMIN_USERS.
+func (c *Config) isAdmin(userID string) bool {
+	return slices.Contains(c.AdminUsers, userID)
+}
+
+// handleAdminCommand runs `/share-admin list`, which shows every secret
+// shared in the workspace, and `/share-admin revoke`, which revokes those
+// shared longer ago than an age once confirmed. Only ADMIN_USERS may use
+// it.
+func (b *bot) handleAdminCommand(ctx context.Context, cmd slack.SlashCommand) {
+	if !b.cfg.isAdmin(cmd.UserID) {
+		slog.Warn("Admin command refused", "event", "admin", "user_id", cmd.UserID)
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Only admins can use `/share-admin`.")
+		return
+	}
+
+	sub, rest, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	rest = strings.TrimSpace(rest)
+	switch sub {
+	case "list":
+		b.adminList(ctx, cmd, rest)
+	case "revoke":
+		b.confirmAdminRevoke(ctx, cmd, rest)
+	default:
+		sendSlackResponse(b.slack, cmd.ResponseURL, adminUsage)
+	}
+}
+
+// adminList shows one page of every secret shared in the workspace, oldest
+// first.
+func (b *bot) adminList(ctx context.Context, cmd slack.SlashCommand, arg string) {
+	page := 1
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Invalid page %q. %s", arg, adminUsage))
+			return
+		}
+		page = n
+	}
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	secrets, err := b.storedSecrets(ctx, cmd.TeamID)
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Failed to list shared secrets in Vault", storeFailure("list the shared secrets", err), "event", "admin", "user_id", cmd.UserID)
+		return
+	}
+	sendSlackResponse(b.slack, cmd.ResponseURL, formatAdminList(secrets, page, time.Now()))
+}
+
+// storedSecrets returns every secret shared in teamID's workspace, oldest
+// first, with those whose creation time is unknown at the end. Secrets whose
+// metadata cannot be read are logged and left out.
+func (b *bot) storedSecrets(ctx context.Context, teamID string) ([]listedSecret, error) {
+	paths := b.cfg.kvPaths(teamID)
+	ids, err := listSecretIDs(ctx, b.secrets, paths)
+	if err != nil {
+		return nil, err
+	}
+	var secrets []listedSecret
+	for _, id := range ids {
+		meta, err := readSecretMetadata(ctx, b.secrets, paths, id)
+		switch {
+		case errors.Is(err, errSecretNotFound):
+		case err != nil:
+			slog.Error("Failed to read secret metadata from Vault", "event", "admin", "secret_id", id, "error", err)
+		default:
+			secrets = append(secrets, listedSecret{id: id, meta: meta})
+		}
+	}
+	slices.SortStableFunc(secrets, func(x, y listedSecret) int {
+		switch xUnknown, yUnknown := x.meta.CreatedAt.IsZero(), y.meta.CreatedAt.IsZero(); {
+		case xUnknown && yUnknown:
+			return 0
+		case xUnknown:
+			return 1
+		case yUnknown:
+			return -1
+		}
+		return x.meta.CreatedAt.Compare(y.meta.CreatedAt)
+	})
+	return secrets, nil
+}
+
+// formatAdminList renders one page of secrets with who shared each, how old
+// it is and whether it can still be retrieved.
+func formatAdminList(secrets []listedSecret, page int, now time.Time) string {
+	if len(secrets) == 0 {
+		return "No secrets are stored for this workspace."
+	}
+	pages := (len(secrets) + listPageSize - 1) / listPageSize
+	if page > pages {
+		return fmt.Sprintf("There is no page %d. There %s %d %s of secrets.", page, plural(pages, "is", "are"), pages, plural(pages, "page", "pages"))
+	}
+	start := (page - 1) * listPageSize
+	end := min(start+listPageSize, len(secrets))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*Shared secrets in this workspace*, oldest first (%d, page %d of %d)\n", len(secrets), page, pages)
+	for _, s := range secrets[start:end] {
+		fmt.Fprintf(&sb, "• %s", describeSecret(s.id, s.meta.Label))
+		if s.meta.SharedBy != "" {
+			fmt.Fprintf(&sb, " by <@%s>", s.meta.SharedBy)
+		}
+		if s.meta.CreatedAt.IsZero() {
+			sb.WriteString(", age unknown")
+		} else {
+			fmt.Fprintf(&sb, ", %s old", formatAge(now.Sub(s.meta.CreatedAt)))
+		}
+		if s.meta.expired(now) {
+			sb.WriteString(", expired and awaiting the sweep\n")
+		} else {
+			fmt.Fprintf(&sb, ", expires in %s, %s\n", formatRemaining(s.meta.ExpiresAt.Sub(now)), formatUsesLeft(s.meta.UsesRemaining))
+		}
+	}
+	if page < pages {
+		fmt.Fprintf(&sb, "Run `/share-admin list %d` for more.", page+1)
+	}
+	return sb.String()
+}
+
+// formatAge renders how long ago a secret was shared, in days once it is at
+// least a day.
+func formatAge(d time.Duration) string {
+	if days := int(d / (24 * time.Hour)); days > 0 {
+		return fmt.Sprintf("%d %s", days, plural(days, "day", "days"))
+	}
+	return formatRemaining(d)
+}
+
+// parseAge parses the age of the secrets to revoke, a duration such as `12h`
+// or a whole number of days such as `7d`.
+func parseAge(value string) (time.Duration, error) {
+	age, err := time.ParseDuration(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		age = time.Duration(n) * 24 * time.Hour
+	}
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("Invalid age %q. %s", value, adminUsage)
+	}
+	return age, nil
+}
+
+// olderThan returns the secrets shared before cutoff, reusing the storage
+// of secrets.
+func olderThan(secrets []listedSecret, cutoff time.Time) []listedSecret {
+	return slices.DeleteFunc(secrets, func(s listedSecret) bool {
+		return s.meta.CreatedAt.IsZero() || !s.meta.CreatedAt.Before(cutoff)
+	})
+}
+
+// confirmAdminRevoke counts the secrets shared longer ago than the age in
+// arg and asks the admin to confirm revoking them with a button. The button
+// carries the cutoff time, so only secrets that were already that old when
+// the command was run are revoked.
+func (b *bot) confirmAdminRevoke(ctx context.Context, cmd slack.SlashCommand, arg string) {
+	age, err := parseAge(arg)
+	if err != nil {
+		sendSlackResponse(b.slack, cmd.ResponseURL, err.Error())
+		return
+	}
+	cutoff := time.Now().Add(-age).Truncate(time.Second)
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	secrets, err := b.storedSecrets(ctx, cmd.TeamID)
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Failed to list shared secrets in Vault", storeFailure("list the shared secrets", err), "event", "admin", "user_id", cmd.UserID)
+		return
+	}
+	n := len(olderThan(secrets, cutoff))
+	if n == 0 {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secrets in this workspace were shared more than %s ago.", formatDuration(age)))
+		return
+	}
+
+	text := fmt.Sprintf("Revoke the %d %s in this workspace shared more than %s ago, before %s? Their links will stop working straight away.", n, plural(n, "secret", "secrets"), formatDuration(age), formatSlackDate(cutoff))
+	value := strconv.FormatInt(cutoff.Unix(), 10)
+	revoke := slack.NewButtonBlockElement(adminRevokeActionID, value, slack.NewTextBlockObject(slack.PlainTextType, "Revoke", false, false)).WithStyle(slack.StyleDanger)
+	keep := slack.NewButtonBlockElement(adminCancelActionID, value, slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false))
+	err = postSlack(
+		&b.slack.Client, "admin", "",
+		slack.MsgOptionResponseURL(cmd.ResponseURL, slack.ResponseTypeEphemeral),
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("", revoke, keep),
+		),
+	)
+	if err != nil {
+		slog.Error("Failed to send response to Slack", "error", err)
+	}
+}
+
+// handleAdminAction revokes, or leaves alone, the secrets a
+// confirmAdminRevoke button was posted for. Whoever clicks it must still be
+// an admin.
+func (b *bot) handleAdminAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	teamID, userID, responseURL := callback.Team.ID, callback.User.ID, callback.ResponseURL
+	if action.ActionID == adminCancelActionID {
+		replaceSlackResponse(b.slack, responseURL, "Cancelled. No secrets were revoked.")
+		return
+	}
+	if !b.cfg.isAdmin(userID) {
+		slog.Warn("Admin revoke refused", "event", "admin_revoke", "user_id", userID)
+		replaceSlackResponse(b.slack, responseURL, "Only admins can revoke other people's secrets.")
+		return
+	}
+	unix, err := strconv.ParseInt(action.Value, 10, 64)
+	if err != nil {
+		slog.Warn("Invalid admin revoke cutoff", "event", "admin_revoke", "user_id", userID, "value", action.Value)
+		return
+	}
+	cutoff := time.Unix(unix, 0)
+
+	replaceSlackResponse(b.slack, responseURL, "Revoking secrets...")
+	b.runHandler("admin_revoke", userID, responseURL, func(ctx context.Context) {
+		listCtx, cancel := vaultContext(ctx, b.cfg)
+		secrets, err := b.storedSecrets(listCtx, teamID)
+		cancel()
+		if err != nil {
+			b.reportFailure(responseURL, "Failed to list shared secrets in Vault", storeFailure("list the shared secrets", err), "event", "admin_revoke", "user_id", userID)
+			return
+		}
+
+		revoked, failed := 0, 0
+		for _, s := range olderThan(secrets, cutoff) {
+			if b.adminRevoke(ctx, teamID, userID, s) {
+				revoked++
+			} else {
+				failed++
+			}
+		}
+		slog.Info("Admin revoke finished", "event", "admin_revoke", "user_id", userID, "cutoff", cutoff, "revoked", revoked, "failed", failed)
+
+		message := fmt.Sprintf("Revoked %d %s shared before %s.", revoked, plural(revoked, "secret", "secrets"), formatSlackDate(cutoff))
+		if failed > 0 {
+			message += fmt.Sprintf(" %d could not be revoked; run the command again to retry them.", failed)
+		}
+		replaceSlackResponse(b.slack, responseURL, message)
+	})
+}
+
+// adminRevoke destroys s on behalf of the admin adminID and reports whether
+// it did. Each revocation is logged and audited with the admin as the actor.
+func (b *bot) adminRevoke(ctx context.Context, teamID, adminID string, s listedSecret) bool {
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	if err := b.destroySecret(ctx, teamID, s.id, s.meta); err != nil {
+		slog.Error("Failed to revoke secret", "event", "admin_revoke", "secret_id", s.id, "shared_by", s.meta.SharedBy, "admin_user_id", adminID, "error", err)
+		revocationsTotal.WithLabelValues(outcomeError).Inc()
+		return false
+	}
+	revocationsTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret revoked by admin", "event", "admin_revoke", "secret_id", s.id, "shared_by", s.meta.SharedBy, "admin_user_id", adminID)
+	audit(b.audit, AuditEvent{Action: auditRevoke, SecretID: s.id, SharedBy: s.meta.SharedBy, Actor: adminID})
+	return true
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestAdminCommand(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.AdminUsers = []string{"UADMIN"}
+	paths := b.cfg.kvPaths("")
+	now := time.Now()
+
+	share := func(id string, age time.Duration) {
+		t.Helper()
+		if err := storeSecret(context.Background(), store, paths, id, secretPayload{Text: "hunter2"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		meta := secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-" + id, CreatedAt: now.Add(-age), ExpiresAt: now.Add(time.Hour)}
+		if err := writeSecretMetadata(context.Background(), store, paths, id, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	share("fresh", time.Hour)
+	share("stale", 10*24*time.Hour)
+	share("ancient", 40*24*time.Hour)
+
+	admin := func(userID, text string) string {
+		t.Helper()
+		b.handleAdminCommand(context.Background(), slack.SlashCommand{Command: "/share-admin", Text: text, UserID: userID, ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	if got := admin("U1", "list"); !strings.Contains(got, "Only admins") {
+		t.Errorf("reply to a non-admin = %q, want it refused", got)
+	}
+	got := admin("UADMIN", "list")
+	if !strings.Contains(got, "(3, page 1 of 1)") || strings.Index(got, "`ancient`") > strings.Index(got, "`fresh`") || !strings.Contains(got, "by <@U1>, 40 days old") {
+		t.Errorf("list = %q, want every secret, oldest first, with its sharer and age", got)
+	}
+	if got := admin("UADMIN", "revoke soon"); !strings.Contains(got, "Invalid age") {
+		t.Errorf("reply to a bad age = %q, want it rejected", got)
+	}
+	if got := admin("UADMIN", "revoke 90d"); !strings.Contains(got, "No secrets") {
+		t.Errorf("reply to revoke 90d = %q, want nothing to revoke", got)
+	}
+	if got := admin("UADMIN", "revoke 7d"); !strings.Contains(got, "Revoke the 2 secrets") {
+		t.Errorf("reply to revoke 7d = %q, want a confirmation for two secrets", got)
+	}
+	if len(tokens.revoked) != 0 {
+		t.Fatalf("revoked = %q before the confirmation", tokens.revoked)
+	}
+
+	click := func(userID, actionID string) string {
+		t.Helper()
+		callback := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, User: slack.User{ID: userID}, ResponseURL: responseURL}
+		callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: actionID, Value: strconv.FormatInt(now.Add(-7*24*time.Hour).Unix(), 10)}}
+		b.handleBlockActions(callback)
+		b.inflight.Wait()
+		got := replies()
+		return got[len(got)-1]
+	}
+	if got := click("UADMIN", adminCancelActionID); !strings.Contains(got, "No secrets were revoked") || len(tokens.revoked) != 0 {
+		t.Errorf("cancel = %q, revoked %q, want nothing revoked", got, tokens.revoked)
+	}
+	if got := click("U1", adminRevokeActionID); !strings.Contains(got, "Only admins") || len(tokens.revoked) != 0 {
+		t.Errorf("click by a non-admin = %q, revoked %q, want it refused", got, tokens.revoked)
+	}
+	if got := click("UADMIN", adminRevokeActionID); !strings.Contains(got, "Revoked 2 secrets") {
+		t.Errorf("reply = %q, want two secrets revoked", got)
+	}
+	for _, id := range []string{"stale", "ancient"} {
+		if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+			t.Errorf("%s metadata read error = %v, want it deleted", id, err)
+		}
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, "fresh"); err != nil {
+		t.Errorf("fresh was revoked: %v", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"12h", 12 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"0d", 0},
+		{"-1h", 0},
+		{"1.5d", 0},
+		{"week", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.value)
+		if tt.want == 0 {
+			if err == nil {
+				t.Errorf("parseAge(%q) = %s, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseAge(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
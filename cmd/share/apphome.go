@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const (
+	homeRevokeActionID = "home_revoke"
+	homePageActionID   = "home_page"
+)
+
+// handleEventsAPI handles an Events API event. The only one the bot
+// subscribes to is app_home_opened, to render its Home tab.
+func (b *bot) handleEventsAPI(event slackevents.EventsAPIEvent) {
+	switch ev := event.InnerEvent.Data.(type) {
+	case *slackevents.AppHomeOpenedEvent:
+		if ev.Tab != "home" {
+			return
+		}
+		slog.Info("Event received", "event_type", event.InnerEvent.Type, "user_id", ev.User)
+		event.TeamID = b.workspaces.installation(event.EnterpriseID, event.TeamID)
+		if _, ok := b.workspaces.client(event.TeamID); !ok {
+			slog.Warn("Ignored event from unknown workspace", "event_type", event.InnerEvent.Type, "user_id", ev.User, "team_id", event.TeamID)
+			return
+		}
+		b.runHandler("home", ev.User, "", func(ctx context.Context) { b.publishHome(ctx, event.TeamID, ev.User, 1, "") })
+	default:
+		slog.Debug("Ignored unsupported event", "event_type", event.InnerEvent.Type)
+	}
+}
+
+// handleHomeAction handles a click on one of the Home tab's buttons. The
+// page being viewed is kept in the view's private metadata so that it is
+// shown again after a revocation.
+func (b *bot) handleHomeAction(callback slack.InteractionCallback, action *slack.BlockAction) {
+	teamID, userID := callback.Team.ID, callback.User.ID
+	b.runHandler("home", userID, "", func(ctx context.Context) {
+		switch action.ActionID {
+		case homePageActionID:
+			page, _ := strconv.Atoi(action.Value)
+			b.publishHome(ctx, teamID, userID, page, "")
+		case homeRevokeActionID:
+			page, _ := strconv.Atoi(callback.View.PrivateMetadata)
+			notice := fmt.Sprintf("No secret with ID `%s` was found.", action.Value)
+			if validSecretID(action.Value) {
+				vaultCtx, cancel := vaultContext(ctx, b.cfg)
+				notice, _ = b.revokeSecret(vaultCtx, teamID, userID, action.Value)
+				cancel()
+			}
+			b.publishHome(ctx, teamID, userID, page, notice)
+		}
+	})
+}
+
+// publishHome renders page of userID's active secrets in teamID's workspace
+// to their Home tab, with notice, when set, at the top.
+func (b *bot) publishHome(ctx context.Context, teamID, userID string, page int, notice string) {
+	vaultCtx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	now := time.Now()
+	secrets, err := b.activeSecrets(vaultCtx, teamID, userID, now)
+	cancel()
+
+	var view slack.HomeTabViewRequest
+	if err != nil {
+		slog.Error("Failed to read secret index from Vault", "event", "home", "user_id", userID, "error", err)
+		view = homeView(nil, 1, now, vaultFailure(err, "Failed to load your secrets. Open this tab again to retry."))
+	} else {
+		view = homeView(secrets, page, now, notice)
+	}
+
+	err = slackRetries.do(ctx, "home", func() error {
+		_, err := b.api(teamID).PublishView(userID, view, "")
+		return err
+	})
+	if err != nil {
+		slog.Error("Failed to publish Home tab", "event", "home", "user_id", userID, "error", err)
+	}
+}
+
+// homeView renders one page of secrets, newest first, each with a button
+// that revokes it. A page past the last shows the last.
+func homeView(secrets []listedSecret, page int, now time.Time, notice string) slack.HomeTabViewRequest {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, "Your shared secrets", false, false)),
+	}
+	if notice != "" {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, notice, false, false)))
+	}
+	if len(secrets) == 0 {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "You have no active shared secrets. Secrets you share with `/share` are listed here until they expire or run out of uses.", false, false), nil, nil))
+		return slack.HomeTabViewRequest{Type: slack.VTHomeTab, Blocks: slack.Blocks{BlockSet: blocks}, PrivateMetadata: "1"}
+	}
+
+	pages := (len(secrets) + listPageSize - 1) / listPageSize
+	page = min(max(page, 1), pages)
+	newest := slices.Clone(secrets)
+	slices.Reverse(newest)
+	start := (page - 1) * listPageSize
+	end := min(start+listPageSize, len(newest))
+
+	summary := fmt.Sprintf("%d active %s", len(secrets), plural(len(secrets), "secret", "secrets"))
+	if pages > 1 {
+		summary += fmt.Sprintf(", page %d of %d", page, pages)
+	}
+	blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType, summary, false, false)), slack.NewDividerBlock())
+	for _, s := range newest[start:end] {
+		blocks = append(blocks, homeSecretBlock(s, now))
+	}
+
+	var nav []slack.BlockElement
+	if page > 1 {
+		nav = append(nav, slack.NewButtonBlockElement(homePageActionID, strconv.Itoa(page-1), slack.NewTextBlockObject(slack.PlainTextType, "Previous", false, false)))
+	}
+	if page < pages {
+		nav = append(nav, slack.NewButtonBlockElement(homePageActionID, strconv.Itoa(page+1), slack.NewTextBlockObject(slack.PlainTextType, "Next", false, false)))
+	}
+	if len(nav) > 0 {
+		blocks = append(blocks, slack.NewActionBlock("", nav...))
+	}
+	return slack.HomeTabViewRequest{Type: slack.VTHomeTab, Blocks: slack.Blocks{BlockSet: blocks}, PrivateMetadata: strconv.Itoa(page)}
+}
+
+// homeSecretBlock describes s with a button, confirmed first, that revokes
+// it.
+func homeSecretBlock(s listedSecret, now time.Time) slack.Block {
+	details := []string{fmt.Sprintf("expires in %s", formatRemaining(s.meta.ExpiresAt.Sub(now))), formatUsesLeft(s.meta.UsesRemaining)}
+	if !s.meta.CreatedAt.IsZero() {
+		details = append([]string{"shared " + formatSlackDate(s.meta.CreatedAt)}, details...)
+	}
+	if len(s.meta.AllowedUsers) > 0 {
+		details = append(details, "for "+formatMentions(s.meta.AllowedUsers))
+	}
+	text := fmt.Sprintf("%s\n%s", describeSecret(s.id, s.meta.Label), strings.Join(details, " · "))
+
+	confirm := slack.NewConfirmationBlockObject(
+		slack.NewTextBlockObject(slack.PlainTextType, "Revoke this secret?", false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s will be destroyed and its link will stop working.", describeSecret(s.id, s.meta.Label)), false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Revoke", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+	)
+	revoke := slack.NewButtonBlockElement(homeRevokeActionID, s.id, slack.NewTextBlockObject(slack.PlainTextType, "Revoke", false, false)).
+		WithStyle(slack.StyleDanger).
+		WithConfirm(confirm)
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, slack.NewAccessory(revoke))
+}
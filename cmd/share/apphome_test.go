@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHomeView(t *testing.T) {
+	now := time.Now()
+	render := func(view slack.HomeTabViewRequest) string {
+		raw, err := json.Marshal(view)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(raw)
+	}
+
+	if got := render(homeView(nil, 1, now, "")); !strings.Contains(got, "You have no active shared secrets") {
+		t.Errorf("empty view = %s, want the empty state", got)
+	}
+
+	var secrets []listedSecret
+	for i := 1; i <= listPageSize+2; i++ {
+		secrets = append(secrets, listedSecret{id: fmt.Sprintf("secret-%d", i), meta: secretMetadata{ExpiresAt: now.Add(time.Hour), UsesRemaining: 1}})
+	}
+	first := homeView(secrets, 1, now, "Secret `secret-0` has been revoked")
+	got := render(first)
+	if !strings.Contains(got, "secret-12") || strings.Contains(got, "secret-2`") || !strings.Contains(got, "page 1 of 2") || !strings.Contains(got, "has been revoked") {
+		t.Errorf("first page = %s, want the newest secrets, the page count and the notice", got)
+	}
+	if !strings.Contains(got, `"action_id":"home_revoke","value":"secret-12"`) || !strings.Contains(got, `"action_id":"home_page","value":"2"`) {
+		t.Errorf("first page = %s, want revoke buttons and a next page button", got)
+	}
+	if first.PrivateMetadata != "1" {
+		t.Errorf("PrivateMetadata = %q, want the page", first.PrivateMetadata)
+	}
+
+	last := homeView(secrets, 5, now, "")
+	if got := render(last); !strings.Contains(got, "secret-1`") || !strings.Contains(got, `"value":"1"`) || last.PrivateMetadata != "2" {
+		t.Errorf("page past the end = %s, want the last page", got)
+	}
+}
+
+func TestHomeRevoke(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	id := tokens.created[0].Metadata["secret_id"]
+	paths := b.cfg.kvPaths("")
+
+	click := func(userID string) {
+		callback := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, User: slack.User{ID: userID}}
+		callback.View.PrivateMetadata = "1"
+		callback.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: homeRevokeActionID, Value: id}}
+		b.handleBlockActions(callback)
+		b.inflight.Wait()
+	}
+
+	click("U2")
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != nil {
+		t.Fatalf("another user revoked the secret: %v", err)
+	}
+	click("U1")
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+		t.Errorf("read after revoking = %v, want the secret destroyed", err)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-1"}) {
+		t.Errorf("revoked = %q, want the secret's token", tokens.revoked)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Audit actions.
+const (
+	auditShare    = "share"
+	auditRetrieve = "retrieve"
+	auditRevoke   = "revoke"
+	auditExpire   = "expire"
+	auditExtend   = "extend"
+	auditRotate   = "rotate"
+	// auditTransfer is a secret given a new owner, recorded as SharedBy.
+	auditTransfer = "transfer"
+	// auditLockout is a secret destroyed after too many wrong passphrases.
+	auditLockout = "lockout"
+)
+
+// AuditEvent records an operation on a shared secret. It identifies the
+// secret by ID and never contains its value.
+type AuditEvent struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	SecretID string    `json:"secret_id"`
+	SharedBy string    `json:"shared_by,omitempty"`
+	// Actor is the Slack user who performed the action, when known.
+	Actor     string `json:"actor,omitempty"`
+	TTL       string `json:"ttl,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// UsesRemaining is nil when the secret has unlimited uses or the count
+	// is unknown.
+	UsesRemaining *int `json:"uses_remaining,omitempty"`
+	// RemoteAddr is the address a retrieval came from.
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	// PreviousOwner is who shared a secret before it was transferred.
+	PreviousOwner string `json:"previous_owner,omitempty"`
+}
+
+// AuditLogger persists audit events.
+type AuditLogger interface {
+	Log(event AuditEvent) error
+}
+
+// newAuditLogger returns an AuditLogger writing to every sink configured in
+// cfg. With no sinks configured events are discarded.
+func newAuditLogger(cfg *Config, vaultClient *api.Client) (AuditLogger, error) {
+	var loggers multiAuditLogger
+	if cfg.AuditLogFile != "" {
+		f, err := newFileAuditLogger(cfg.AuditLogFile)
+		if err != nil {
+			return nil, err
+		}
+		loggers = append(loggers, f)
+	}
+	if cfg.AuditVaultPath != "" && !cfg.DryRun {
+		loggers = append(loggers, &vaultAuditLogger{store: vaultStore(vaultClient, cfg), path: cfg.AuditVaultPath, kvVersion: cfg.VaultKVVersion, timeout: cfg.VaultTimeout})
+	}
+	return loggers, nil
+}
+
+// audit logs event, stamping it with the current time. Failures are logged
+// rather than returned, since the operation being audited has already
+// happened.
+func audit(logger AuditLogger, event AuditEvent) {
+	event.Time = time.Now().UTC()
+	if err := logger.Log(event); err != nil {
+		slog.Error("Failed to write audit event", "action", event.Action, "secret_id", event.SecretID, "error", err)
+	}
+}
+
+// usesRemaining converts a count where zero means unlimited into the form
+// used by AuditEvent.
+func usesRemaining(n int, unlimited bool) *int {
+	if unlimited {
+		return nil
+	}
+	return &n
+}
+
+type multiAuditLogger []AuditLogger
+
+func (m multiAuditLogger) Log(event AuditEvent) error {
+	var errs []error
+	for _, l := range m {
+		errs = append(errs, l.Log(event))
+	}
+	return errors.Join(errs...)
+}
+
+// fileAuditLogger appends events to a file as JSON lines.
+type fileAuditLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	f   *os.File
+}
+
+func newFileAuditLogger(path string) (*fileAuditLogger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &fileAuditLogger{enc: json.NewEncoder(f), f: f}, nil
+}
+
+func (l *fileAuditLogger) Log(event AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(event); err != nil {
+		return err
+	}
+	return l.f.Sync()
+}
+
+// vaultAuditLogger writes each event to its own key under a KV path, so that
+// events are never overwritten.
+type vaultAuditLogger struct {
+	store     SecretStore
+	path      string
+	kvVersion int
+	timeout   time.Duration
+}
+
+func (l *vaultAuditLogger) Log(event AuditEvent) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s/%d-%s-%s", l.path, event.Time.UnixNano(), event.SecretID, event.Action)
+	body := map[string]interface{}{"data": fields}
+	if l.kvVersion == 1 {
+		body = fields
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	_, err = l.store.WriteWithContext(ctx, key, body)
+	return err
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileAuditLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := newFileAuditLogger(path)
+	if err != nil {
+		t.Fatalf("newFileAuditLogger() error = %v", err)
+	}
+
+	audit(logger, AuditEvent{Action: auditShare, SecretID: "secret-1", SharedBy: "U1", TTL: "1h0m0s", UsesRemaining: usesRemaining(2, false)})
+	audit(logger, AuditEvent{Action: auditRetrieve, SecretID: "secret-1", UsesRemaining: usesRemaining(0, true)})
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0]["action"] != auditShare || events[0]["secret_id"] != "secret-1" || events[0]["uses_remaining"] != 2.0 || events[0]["time"] == nil {
+		t.Errorf("share event = %v", events[0])
+	}
+	if _, ok := events[1]["uses_remaining"]; ok {
+		t.Errorf("unlimited retrieve event has uses_remaining: %v", events[1])
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// bot holds the clients and state shared by the Slack event handlers.
+type bot struct {
+	// slack is the socket mode client. In HTTP mode it is never connected
+	// and only its embedded API client is used.
+	slack *socketmode.Client
+	// workspaces holds the API client of each workspace the bot serves.
+	workspaces *workspaces
+	vault      *api.Client
+	secrets    SecretStore
+	tokens     TokenCreator
+	cfg        *Config
+	audit      AuditLogger
+
+	// router dispatches slash commands to their handlers.
+	router *CommandRouter
+
+	// state holds short-lived state, such as short codes, that may be
+	// shared with other replicas.
+	state StateStore
+
+	// shareLimiter bounds how often each user may share a secret, and
+	// emailLimiter how often they may send one by email.
+	shareLimiter limiter
+	emailLimiter limiter
+
+	// mailer sends --email links, and is nil when no SMTP relay is
+	// configured.
+	mailer Mailer
+
+	// commandsSeen recognises slash commands that Slack delivers more than
+	// once.
+	commandsSeen *dedupCache
+
+	// pending holds /share requests awaiting confirmation.
+	pending *pendingShares
+
+	// issued tracks the recipient tokens issued by this process.
+	issued *issuedTokens
+
+	// indexMu serialises read-modify-write updates of the per-user secret
+	// indexes.
+	indexMu sync.Mutex
+
+	// slots bounds how many handlers run at once.
+	slots *handlerSlots
+
+	// dmInterval paces the personal links of a large --group share.
+	dmInterval time.Duration
+
+	// timeoutGrace is how long a handler that ran past COMMAND_TIMEOUT has
+	// to reply before the user is told.
+	timeoutGrace time.Duration
+
+	// socket tracks the Socket Mode connection, and is nil in HTTP mode.
+	socket *socketStatus
+
+	// started is when the bot started, for /whoami.
+	started time.Time
+
+	// inflight tracks handlers that are still running so that shutdown can
+	// wait for them.
+	inflight sync.WaitGroup
+}
+
+func newBot(slackClient *socketmode.Client, ws *workspaces, vaultClient *api.Client, cfg *Config, auditLogger AuditLogger, state StateStore) *bot {
+	b := &bot{
+		slack:        slackClient,
+		workspaces:   ws,
+		vault:        vaultClient,
+		secrets:      vaultStore(vaultClient, cfg),
+		tokens:       vaultTokens(vaultClient, cfg),
+		cfg:          cfg,
+		audit:        auditLogger,
+		state:        state,
+		shareLimiter: newLimiter(state, cfg.ShareRateLimit, time.Minute),
+		emailLimiter: newLimiter(state, cfg.EmailRateLimit, time.Hour),
+		mailer:       newMailer(cfg),
+		commandsSeen: newDedupCache(state, commandDedupWindow),
+		pending:      newPendingShares(state, cfg.LinkSigningKey),
+		issued:       newIssuedTokens(),
+		slots:        newHandlerSlots(cfg.MaxInflight),
+		dmInterval:   defaultGroupDMInterval,
+		timeoutGrace: defaultTimeoutGrace,
+		started:      time.Now(),
+	}
+	b.router = newBotRouter(b)
+	return b
+}
+
+// api returns the Slack API client of teamID's workspace. Commands and
+// interactions from workspaces the bot is not configured for are turned away
+// before they reach a handler, so teamID is always known.
+func (b *bot) api(teamID string) *slack.Client {
+	if c, ok := b.workspaces.client(teamID); ok {
+		return c
+	}
+	return b.workspaces.fallback
+}
+
+// replyType returns the response type of command's confirmations:
+// in_channel if IN_CHANNEL_RESPONSES lists it, otherwise ephemeral. Only
+// replies that never carry a secret go through it.
+func (b *bot) replyType(command string) string {
+	if slices.Contains(b.cfg.InChannelResponses, command) {
+		return slack.ResponseTypeInChannel
+	}
+	return slack.ResponseTypeEphemeral
+}
+
+// unknownWorkspaceMessage is the reply to a command from a workspace that is
+// not listed in SLACK_WORKSPACES_FILE.
+const unknownWorkspaceMessage = "This bot is not set up for this workspace. Please ask an admin to add it."
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// errVaultUnavailable is returned without calling Vault while the circuit
+// breaker is open.
+var errVaultUnavailable = errors.New("vault circuit breaker is open")
+
+// vaultUnavailableMessage is the reply to a command that was turned away by
+// the circuit breaker.
+const vaultUnavailableMessage = "The secret store is temporarily unavailable. Please try again in a minute."
+
+// Circuit breaker states, which are also the values of the
+// hush_vault_breaker_state gauge.
+const (
+	breakerClosed = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+// circuitBreaker stops calling Vault once threshold Vault operations in a
+// row have failed in a way that points to an outage, and fails fast with
+// errVaultUnavailable instead. After cooldown it lets one operation through
+// to probe whether Vault has recovered: if it succeeds the breaker closes,
+// otherwise it opens again. Errors that Vault answered deliberately, such as
+// a 403 or 404, are not failures. A nil breaker never opens.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open probe is in flight.
+	probing bool
+	// held keeps the breaker open, without probes, until release is
+	// called.
+	held bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	vaultBreakerState.Set(breakerClosed)
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// do calls fn unless the breaker is open, and records how it went.
+func (cb *circuitBreaker) do(operation string, fn func() error) error {
+	if cb == nil {
+		return fn()
+	}
+	if !cb.allow() {
+		vaultBreakerRejections.Inc()
+		return errVaultUnavailable
+	}
+	err := fn()
+	cb.record(operation, outageError(err), err)
+	return err
+}
+
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.held {
+		return false
+	}
+	switch cb.state {
+	case breakerOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.setState(breakerHalfOpen)
+		cb.probing = true
+		return true
+	case breakerHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	}
+	return true
+}
+
+func (cb *circuitBreaker) record(operation string, failed bool, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case breakerHalfOpen:
+		cb.probing = false
+		if errors.Is(err, context.Canceled) {
+			// The caller gave up, which says nothing about Vault; let the
+			// next operation probe instead.
+			return
+		}
+		if failed {
+			cb.openedAt = cb.now()
+			cb.setState(breakerOpen)
+			slog.Warn("Vault is still failing, circuit breaker reopened", "event", "vault_breaker", "operation", operation, "error", err)
+			return
+		}
+		cb.failures = 0
+		cb.setState(breakerClosed)
+		slog.Info("Vault recovered, circuit breaker closed", "event", "vault_breaker", "operation", operation)
+	case breakerClosed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+		if cb.failures++; cb.failures >= cb.threshold {
+			cb.openedAt = cb.now()
+			cb.setState(breakerOpen)
+			slog.Error("Vault failed repeatedly, circuit breaker opened", "event", "vault_breaker", "failures", cb.failures, "cooldown", cb.cooldown, "operation", operation, "error", err)
+		}
+	}
+}
+
+// hold opens the breaker until release is called, for a bot that started
+// before Vault could be reached.
+func (cb *circuitBreaker) hold() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.held = true
+	cb.setState(breakerOpen)
+}
+
+// release closes a breaker opened by hold.
+func (cb *circuitBreaker) release() {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.held, cb.probing, cb.failures = false, false, 0
+	cb.setState(breakerClosed)
+}
+
+func (cb *circuitBreaker) setState(state int) {
+	cb.state = state
+	vaultBreakerState.Set(float64(state))
+}
+
+// outageError reports whether err suggests Vault is down rather than that
+// it refused the request: a network failure, a 5xx or 429, or no response
+// in time.
+func outageError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return errors.Is(err, context.DeadlineExceeded) || vaultSealed(err) || retryable(err)
+}
+
+// breakerStore is a SecretStore whose requests go through a circuit
+// breaker.
+type breakerStore struct {
+	SecretStore
+	breaker *circuitBreaker
+}
+
+func (s breakerStore) ReadWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.breaker.do("read", func() error {
+		resp, err = s.SecretStore.ReadWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+func (s breakerStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (resp *api.Secret, err error) {
+	err = s.breaker.do("write", func() error {
+		resp, err = s.SecretStore.WriteWithContext(ctx, path, data)
+		return err
+	})
+	return resp, err
+}
+
+func (s breakerStore) DeleteWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.breaker.do("delete", func() error {
+		resp, err = s.SecretStore.DeleteWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+func (s breakerStore) ListWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.breaker.do("list", func() error {
+		resp, err = s.SecretStore.ListWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+// breakerTokens is a TokenCreator whose requests go through a circuit
+// breaker.
+type breakerTokens struct {
+	TokenCreator
+	breaker *circuitBreaker
+}
+
+func (t breakerTokens) CreateWithContext(ctx context.Context, opts *api.TokenCreateRequest) (resp *api.Secret, err error) {
+	err = t.breaker.do("token_create", func() error {
+		resp, err = t.TokenCreator.CreateWithContext(ctx, opts)
+		return err
+	})
+	return resp, err
+}
+
+func (t breakerTokens) RevokeAccessorWithContext(ctx context.Context, accessor string) error {
+	return t.breaker.do("token_revoke", func() error {
+		return t.TokenCreator.RevokeAccessorWithContext(ctx, accessor)
+	})
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	breaker := newCircuitBreaker(2, time.Minute)
+	breaker.now = func() time.Time { return now }
+	vault := newFakeSecretStore()
+	vault.failWrites = "secrets/"
+	store := breakerStore{vault, breaker}
+	write := func() error {
+		_, err := store.WriteWithContext(context.Background(), "secrets/data/shared/secret-1", map[string]interface{}{"data": "hunter2"})
+		return err
+	}
+
+	// Refusals are Vault working as intended, so they do not count.
+	for range 3 {
+		breaker.do("read", func() error { return &api.ResponseError{StatusCode: http.StatusForbidden} })
+	}
+	if err := write(); err == nil || errors.Is(err, errVaultUnavailable) {
+		t.Fatalf("first failing write = %v, want Vault's error", err)
+	}
+	write()
+	if got := testutil.ToFloat64(vaultBreakerState); got != breakerOpen {
+		t.Fatalf("state after 2 failures = %v, want open", got)
+	}
+
+	rejections := testutil.ToFloat64(vaultBreakerRejections)
+	called := false
+	if err := breaker.do("read", func() error { called = true; return nil }); !errors.Is(err, errVaultUnavailable) || called {
+		t.Errorf("call while open = %v, called %t, want a fast failure", err, called)
+	}
+	if got := testutil.ToFloat64(vaultBreakerRejections) - rejections; got != 1 {
+		t.Errorf("rejections = %v, want 1", got)
+	}
+	if got := vaultFailure(errVaultUnavailable, "Failed."); got != vaultUnavailableMessage {
+		t.Errorf("vaultFailure() = %q, want the unavailable message", got)
+	}
+
+	// After the cooldown a single probe goes through; a failing one reopens
+	// the breaker for another cooldown.
+	now = now.Add(time.Minute)
+	if err := write(); errors.Is(err, errVaultUnavailable) {
+		t.Fatal("probe after the cooldown was not let through")
+	}
+	if err := write(); !errors.Is(err, errVaultUnavailable) {
+		t.Errorf("write after a failed probe = %v, want the breaker open again", err)
+	}
+
+	now = now.Add(time.Minute)
+	vault.failWrites = ""
+	if err := write(); err != nil {
+		t.Fatalf("probe once Vault recovered = %v", err)
+	}
+	if got := testutil.ToFloat64(vaultBreakerState); got != breakerClosed {
+		t.Errorf("state after a successful probe = %v, want closed", got)
+	}
+	vault.failWrites = "secrets/"
+	if err := write(); errors.Is(err, errVaultUnavailable) {
+		t.Error("one failure after closing opened the breaker, want the count reset")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// shareChannelAllowed reports whether secrets may be shared from the channel
+// with channelID and channelName. Channel IDs are matched exactly and names
+// regardless of case.
+func (cfg *Config) shareChannelAllowed(channelID, channelName string) bool {
+	if len(cfg.ShareAllowedChannels) == 0 {
+		return true
+	}
+	for _, c := range cfg.ShareAllowedChannels {
+		if c == channelID || (channelName != "" && strings.EqualFold(c, channelName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowShareChannel checks that cmd was run in a channel secrets may be shared
+// from, and otherwise tells the user where they can share and returns false.
+func (b *bot) allowShareChannel(cmd slack.SlashCommand) bool {
+	if b.cfg.shareChannelAllowed(cmd.ChannelID, cmd.ChannelName) {
+		return true
+	}
+	slog.Warn("Share attempted from a channel that is not allowed", "event", "share", "user_id", cmd.UserID, "channel_id", cmd.ChannelID, "command", cmd.Command)
+	sharesTotal.WithLabelValues(outcomeDenied).Inc()
+	sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Secrets cannot be shared from this channel. Run `%s` in one of these instead: %s.", cmd.Command, formatChannels(b.cfg.ShareAllowedChannels)))
+	return false
+}
+
+// formatChannels renders channel IDs as Slack channel links and names with
+// their #.
+func formatChannels(channels []string) string {
+	out := make([]string, len(channels))
+	for i, c := range channels {
+		if isChannelID(c) {
+			out[i] = "<#" + c + ">"
+		} else {
+			out[i] = "#" + c
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// isChannelID reports whether s looks like a Slack channel ID rather than a
+// channel name, which is always lower case.
+func isChannelID(s string) bool {
+	return s != "" && strings.IndexByte("CGD", s[0]) >= 0 && strings.ToUpper(s) == s
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestShareAllowedChannels(t *testing.T) {
+	store := newFakeSecretStore()
+	b, responseURL, replies := newTestBot(t, store, &fakeTokenCreator{})
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify hunter2", UserID: "U1", ChannelID: "C9", ChannelName: "random", ResponseURL: responseURL})
+	if len(store.data) == 0 {
+		t.Fatal("share was rejected with no allowlist configured")
+	}
+
+	b.cfg.ShareAllowedChannels = []string{"C1", "Security"}
+	for _, cmd := range []slack.SlashCommand{
+		{Command: "/share", Text: "hunter2"},
+		{Command: "/share"},
+		{Command: "/share-channel", Text: "hunter2"},
+		{Command: "/generate"},
+	} {
+		cmd.UserID, cmd.ChannelID, cmd.ChannelName, cmd.ResponseURL = "U1", "C9", "random", responseURL
+		before := len(store.data)
+		b.router.Dispatch(context.Background(), cmd)
+		if len(store.data) != before {
+			t.Errorf("%s from a channel that is not allowed stored a secret", cmd.Command)
+		}
+		got := replies()
+		if reply := got[len(got)-1]; !strings.Contains(reply, "cannot be shared from this channel") || !strings.Contains(reply, "<#C1>, #Security") {
+			t.Errorf("%s reply = %q, want it to name the allowed channels", cmd.Command, reply)
+		}
+	}
+
+	for _, ch := range []slack.SlashCommand{{ChannelID: "C1", ChannelName: "ops"}, {ChannelID: "C2", ChannelName: "security"}} {
+		before := len(store.data)
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify hunter2", UserID: "U1", ChannelID: ch.ChannelID, ChannelName: ch.ChannelName, ResponseURL: responseURL})
+		if len(store.data) == before {
+			t.Errorf("share from %s (#%s) was rejected", ch.ChannelID, ch.ChannelName)
+		}
+	}
+}
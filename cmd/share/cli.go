@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// createUsage describes the create subcommand.
+const createUsage = "usage: share [-config file] create [--user <Slack user ID>] [--team <team ID>] [--ttl 30m | --expires-at <time>] [--uses 1] [--label <text>] [--passphrase <word>] [--no-notify] [--burn] < secret"
+
+// createFlags are the options of the create subcommand: those of /share
+// that work without Slack, and who to record as sharing the secret.
+var createFlags = func() map[string]bool {
+	flags := maps.Clone(shareFlags)
+	flags["--user"] = true
+	flags["--team"] = true
+	return flags
+}()
+
+// createResult is what the create subcommand prints. It holds everything a
+// script needs to hand the secret on, but neither the secret nor its token.
+type createResult struct {
+	SecretID      string `json:"secret_id"`
+	URL           string `json:"url"`
+	TTLSeconds    int64  `json:"ttl_seconds"`
+	ExpiresAt     string `json:"expires_at"`
+	UsesRemaining *int   `json:"uses_remaining,omitempty"`
+}
+
+// runCreateCommand runs the create subcommand with argv, sharing through
+// vaultClient, and returns the exit code. Slack is never contacted.
+func runCreateCommand(ctx context.Context, vaultClient *api.Client, cfg *Config, auditLogger AuditLogger, argv []string) int {
+	client := socketmode.New(slack.New(cfg.SlackBotToken))
+	b := newBot(client, newWorkspaces(cfg, &client.Client), vaultClient, cfg, auditLogger, newMemoryState())
+	if err := runCreate(ctx, b, argv, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "share create: %v\n%s\n", err, createUsage)
+		return 1
+	}
+	return 0
+}
+
+// runCreate shares the secret read from stdin with the options in argv, as
+// /share would, and writes a createResult to stdout as JSON.
+func runCreate(ctx context.Context, b *bot, argv []string, stdin io.Reader, stdout io.Writer) error {
+	flags, err := splitArgs(argv, createFlags)
+	if err != nil {
+		return err
+	}
+	var teamID, userID string
+	var options []commandFlag
+	for _, f := range flags {
+		switch f.name {
+		case "--user":
+			userID = f.value
+		case "--team":
+			teamID = f.value
+		case "--to", "--group", "--email", "--code":
+			return fmt.Errorf("%s needs Slack, so it is not available from the command line", f.name)
+		default:
+			options = append(options, f)
+		}
+	}
+	if userID != "" && !validSecretID(userID) {
+		return fmt.Errorf("--user %q is not a Slack user ID", userID)
+	}
+	if b.cfg.multiWorkspace() && b.cfg.SlackWorkspaces[teamID] == "" {
+		return fmt.Errorf("--team must name one of the workspaces in SLACK_WORKSPACES_FILE")
+	}
+	if b.cfg.teamScoped() && !teamIDPattern.MatchString(teamID) {
+		return fmt.Errorf("--team must be given, since SECRET_PATH_LAYOUT keeps secrets under their team ID")
+	}
+
+	secret, err := readSecretInput(stdin, b.cfg)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(secret) == "" {
+		return fmt.Errorf("no secret was given on standard input, or it is only whitespace")
+	}
+	args, err := newShareArgs(options, secret, b.cfg)
+	if err != nil {
+		return invalid(err)
+	}
+
+	req := shareRequest{shareArgs: args, teamID: teamID, userID: userID}
+	secretID, meta, _, err := b.createShare(ctx, &req, nil)
+	if err != nil {
+		return err
+	}
+	uses := meta.UsesRemaining
+	if meta.Burn {
+		uses = 1
+	}
+	return json.NewEncoder(stdout).Encode(createResult{
+		SecretID:      secretID,
+		URL:           secretPageURL(b.cfg, teamID, secretID),
+		TTLSeconds:    int64(req.ttl / time.Second),
+		ExpiresAt:     meta.ExpiresAt.UTC().Format(time.RFC3339),
+		UsesRemaining: usesRemaining(uses, uses == 0),
+	})
+}
+
+// readSecretInput reads a secret from r, reading no more than cfg allows a
+// secret to have so that the size check can reject it. The single newline
+// that ends most piped input is not part of the secret.
+func readSecretInput(r io.Reader, cfg *Config) (string, error) {
+	if cfg.MaxSecretBytes > 0 {
+		r = io.LimitReader(r, int64(cfg.MaxSecretBytes)+2)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("reading the secret: %w", err)
+	}
+	secret := strings.TrimSuffix(string(raw), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
+
+// splitArgs turns command-line arguments into flags, as splitFlags does for
+// the text of a slash command. valued names the flags accepted, mapped to
+// whether each takes a value, which follows either "=" or as the next
+// argument.
+func splitArgs(argv []string, valued map[string]bool) ([]commandFlag, error) {
+	var flags []commandFlag
+	for i := 0; i < len(argv); i++ {
+		name, value, hasValue := strings.Cut(argv[i], "=")
+		takesValue, ok := valued[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown option %q", argv[i])
+		}
+		if takesValue && !hasValue {
+			if i+1 == len(argv) {
+				return nil, fmt.Errorf("%s needs a value", name)
+			}
+			i++
+			value = argv[i]
+		} else if hasValue && !takesValue {
+			return nil, fmt.Errorf("%s does not take a value", name)
+		}
+		flags = append(flags, commandFlag{name: name, value: value})
+	}
+	return flags, nil
+}
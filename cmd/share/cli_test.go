@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCreate(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, _, _ := newTestBot(t, store, tokens)
+	b.cfg.MaxSecretBytes = 16
+	paths := b.cfg.kvPaths("")
+
+	var stdout strings.Builder
+	err := runCreate(context.Background(), b, []string{"--user", "U1", "--ttl=2h", "--uses", "3", "--label", "prod db"}, strings.NewReader("hunter2\n"), &stdout)
+	if err != nil {
+		t.Fatalf("runCreate() error = %v", err)
+	}
+	if out := stdout.String(); strings.Contains(out, "hunter2") || strings.Contains(out, "hvs.recipient") {
+		t.Errorf("output = %s, want neither the secret nor its token", out)
+	}
+	var result createResult
+	if err := json.Unmarshal([]byte(stdout.String()), &result); err != nil {
+		t.Fatalf("output %q is not JSON: %v", stdout.String(), err)
+	}
+	if result.URL != secretPageURL(b.cfg, "", result.SecretID) || result.TTLSeconds != 7200 || result.UsesRemaining == nil || *result.UsesRemaining != 3 {
+		t.Errorf("result = %+v, want the link, 2h and 3 uses", result)
+	}
+	if expires, err := time.Parse(time.RFC3339, result.ExpiresAt); err != nil || time.Until(expires) < 119*time.Minute {
+		t.Errorf("expires_at = %q, %v, want in 2h", result.ExpiresAt, err)
+	}
+	if stored, err := readSecret(context.Background(), store, paths, result.SecretID, nil); err != nil || stored.Text != "hunter2" {
+		t.Errorf("stored secret = %q, %v, want it without the trailing newline", stored.Text, err)
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, result.SecretID); err != nil || meta.SharedBy != "U1" || meta.Label != "prod db" {
+		t.Errorf("metadata = %+v, %v, want it shared by U1 with the label", meta, err)
+	}
+
+	for _, tt := range []struct {
+		argv    []string
+		stdin   string
+		wantErr string
+	}{
+		{[]string{"--to", "@alice"}, "hunter2", "needs Slack"},
+		{[]string{"--group", "@oncall"}, "hunter2", "needs Slack"},
+		{[]string{"--ttl"}, "hunter2", "needs a value"},
+		{[]string{"hunter2"}, "", "unknown option"},
+		{nil, "", "no secret"},
+		{[]string{"--uses", "0"}, "hunter2", "uses"},
+		{nil, strings.Repeat("x", 17), "more than the maximum"},
+	} {
+		err := runCreate(context.Background(), b, tt.argv, strings.NewReader(tt.stdin), &stdout)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("runCreate(%q) error = %v, want it to mention %q", tt.argv, err, tt.wantErr)
+		}
+	}
+	if len(tokens.created) != 1 {
+		t.Errorf("tokens created = %d, want only the first share", len(tokens.created))
+	}
+}
+
+func TestLoadCLIConfig(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SLACK_APP_TOKEN", "")
+	t.Setenv("SLACK_BOT_TOKEN", "")
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("LoadConfig() without Slack tokens succeeded")
+	}
+	if _, err := loadCLIConfig(); err != nil {
+		t.Errorf("loadCLIConfig() without Slack tokens error = %v", err)
+	}
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := loadCLIConfig(); err == nil || !strings.Contains(err.Error(), "VAULT_TOKEN") {
+		t.Errorf("loadCLIConfig() without a Vault token error = %v, want it to name VAULT_TOKEN", err)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// shortCodeLength is how many characters a short code has, each of
+	// which carries five bits.
+	shortCodeLength = 8
+	// shortCodeAlphabet is Crockford's base32, which leaves out I, L, O and
+	// U so that a code read out over the phone is not misheard.
+	shortCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+	// codeAttemptsPerMinute is how many codes each address may try a minute.
+	codeAttemptsPerMinute = 10
+	// codeMaxFailures wrong codes from one address lock it out for
+	// codeLockout.
+	codeMaxFailures = 5
+	codeLockout     = 15 * time.Minute
+)
+
+// newShortCode returns a random short code.
+func newShortCode() (string, error) {
+	b := make([]byte, shortCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = shortCodeAlphabet[b[i]&31]
+	}
+	return string(b), nil
+}
+
+// formatShortCode splits code in half to make it easier to read out.
+func formatShortCode(code string) string {
+	return code[:shortCodeLength/2] + "-" + code[shortCodeLength/2:]
+}
+
+// normalizeShortCode turns a code as typed by a recipient back into its
+// canonical form: upper case, without separators, and with the letters that
+// Crockford's base32 leaves out read as the digits they resemble. It reports
+// false if the result is not a valid code.
+func normalizeShortCode(typed string) (string, bool) {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(typed) {
+		switch r {
+		case ' ', '-':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if !strings.ContainsRune(shortCodeAlphabet, r) {
+			return "", false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() != shortCodeLength {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func shortCodeKey(code string) string {
+	return "code:" + code
+}
+
+// issueShortCode maps a new short code to secretID, shared in teamID's
+// workspace, for as long as the secret is valid.
+func (b *bot) issueShortCode(teamID, secretID string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	// Codes are short enough that one may already be in use.
+	for range 3 {
+		code, err := newShortCode()
+		if err != nil {
+			return "", err
+		}
+		ok, err := b.state.SetNX(ctx, shortCodeKey(code), []byte(secretURLPath(b.cfg, teamID, secretID)), ttl)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return code, nil
+		}
+	}
+	return "", errors.New("no unused short code found")
+}
+
+const codesUnavailableMessage = "Codes cannot be checked right now. Please try again shortly."
+
+// shortCodeNote issues a short code for secretID and returns the line of the
+// sharer's reply that gives it, or explains that none could be issued.
+func (b *bot) shortCodeNote(req shareRequest, secretID string) string {
+	code, err := b.issueShortCode(req.teamID, secretID, req.ttl)
+	if err != nil {
+		slog.Error("Failed to issue short code", "event", "share", "secret_id", secretID, "user_id", req.userID, "error", err)
+		return "A short code could not be issued for it, so please send the link instead."
+	}
+	return fmt.Sprintf("To read it out instead, give the recipient the code `%s` to enter at %s/code.", formatShortCode(code), retrievalBaseURL(b.cfg))
+}
+
+var codePage = template.Must(template.New("code").Parse(pageHeader + `
+<h1>Enter your code</h1>
+{{if .}}<p>{{.}}</p>
+{{end}}<form method="post">
+<input name="code" autocomplete="off" autofocus required placeholder="ABCD-EFGH">
+<button type="submit">Open the secret</button>
+</form>
+` + pageFooter))
+
+// handleCodePage shows the form for a short code and, when it is submitted,
+// sends the recipient on to the secret's page, which enforces its limits as
+// for a link. Each address may only try a few codes a minute, and is locked
+// out for a while after several wrong ones, so that codes cannot be guessed.
+func (rs *retrievalServer) handleCodePage(w http.ResponseWriter, r *http.Request) {
+	setPageHeaders(w)
+	if r.Method == http.MethodGet {
+		codePage.Execute(w, "")
+		return
+	}
+
+	addr := clientAddr(r)
+	ctx, cancel := context.WithTimeout(r.Context(), stateTimeout)
+	defer cancel()
+	if !rs.codeLimiter.Allow(addr) {
+		retrievalsTotal.WithLabelValues("code", outcomeDenied).Inc()
+		w.WriteHeader(http.StatusTooManyRequests)
+		codePage.Execute(w, "Too many attempts. Please wait a minute and try again.")
+		return
+	}
+	_, locked, err := rs.state.Get(ctx, "codelock:"+addr)
+	if err != nil {
+		slog.Error("Failed to check short code lockout", "event", "retrieve", "remote_addr", addr, "error", err)
+		retrievalsTotal.WithLabelValues("code", outcomeError).Inc()
+		w.WriteHeader(http.StatusServiceUnavailable)
+		codePage.Execute(w, codesUnavailableMessage)
+		return
+	}
+	if locked {
+		retrievalsTotal.WithLabelValues("code", outcomeDenied).Inc()
+		w.WriteHeader(http.StatusTooManyRequests)
+		codePage.Execute(w, "Too many wrong codes. Please try again later, or ask the sender for the link instead.")
+		return
+	}
+
+	var path []byte
+	code, ok := normalizeShortCode(r.PostFormValue("code"))
+	if ok {
+		if path, ok, err = rs.state.Get(ctx, shortCodeKey(code)); err != nil {
+			slog.Error("Failed to look up short code", "event", "retrieve", "remote_addr", addr, "error", err)
+			retrievalsTotal.WithLabelValues("code", outcomeError).Inc()
+			w.WriteHeader(http.StatusServiceUnavailable)
+			codePage.Execute(w, codesUnavailableMessage)
+			return
+		}
+	}
+	if !ok {
+		rs.recordCodeFailure(ctx, addr)
+		retrievalsTotal.WithLabelValues("code", outcomeNotFound).Inc()
+		w.WriteHeader(http.StatusNotFound)
+		codePage.Execute(w, "That code is not valid. It may have expired, or been mistyped.")
+		return
+	}
+	retrievalsTotal.WithLabelValues("code", outcomeSuccess).Inc()
+	// The same base URL as the links the bot sends, so that the redirect
+	// keeps any path prefix in PUBLIC_BASE_URL.
+	http.Redirect(w, r, retrievalBaseURL(rs.cfg)+"/s/"+string(path), http.StatusSeeOther)
+}
+
+// recordCodeFailure counts a wrong code from addr, locking it out once it
+// has made codeMaxFailures within codeLockout.
+func (rs *retrievalServer) recordCodeFailure(ctx context.Context, addr string) {
+	slog.Warn("Wrong short code entered", "event", "retrieve", "remote_addr", addr)
+	n, err := rs.state.Incr(ctx, "codefail:"+addr, codeLockout)
+	if err != nil {
+		slog.Error("Failed to count wrong short code", "event", "retrieve", "remote_addr", addr, "error", err)
+		return
+	}
+	if n >= codeMaxFailures {
+		if _, err := rs.state.SetNX(ctx, "codelock:"+addr, nil, codeLockout); err != nil {
+			slog.Error("Failed to lock out address after wrong short codes", "event", "retrieve", "remote_addr", addr, "error", err)
+		}
+	}
+}
+
+// clientAddr returns the IP address r came from, without its port.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestNormalizeShortCode(t *testing.T) {
+	for typed, want := range map[string]string{
+		"ABCD-EFGH": "ABCDEFGH",
+		"abcd efgh": "ABCDEFGH",
+		"0l1i-o2ab": "011102AB",
+	} {
+		if got, ok := normalizeShortCode(typed); !ok || got != want {
+			t.Errorf("normalizeShortCode(%q) = %q, %v, want %q", typed, got, ok, want)
+		}
+	}
+	for _, typed := range []string{"", "ABCD-EFG", "ABCD-EFGHJ", "ABCD-EFGU", "ABCD/EFGH"} {
+		if got, ok := normalizeShortCode(typed); ok {
+			t.Errorf("normalizeShortCode(%q) = %q, want it rejected", typed, got)
+		}
+	}
+
+	code, err := newShortCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := normalizeShortCode(formatShortCode(code)); !ok || got != code {
+		t.Errorf("normalizeShortCode(formatShortCode(%q)) = %q, %v", code, got, ok)
+	}
+}
+
+func TestShareShortCode(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--code --to @alice hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "cannot be combined with `--code`") {
+		t.Fatalf("replies to --code --to = %q, want it rejected", got)
+	}
+	b.handleShareChannelCommand(context.Background(), slack.SlashCommand{Command: "/share-channel", Text: "--code hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 2 || !strings.Contains(got[1], "/share --code") {
+		t.Fatalf("replies to /share-channel --code = %q, want it rejected", got)
+	}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--code --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	code := regexp.MustCompile("code `([0-9A-Z]{4}-[0-9A-Z]{4})`").FindStringSubmatch(got[len(got)-1])
+	if code == nil {
+		t.Fatalf("reply = %q, want a short code", got[len(got)-1])
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+
+	rs := &retrievalServer{secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state, codeLimiter: newRateLimiter(codeAttemptsPerMinute, time.Minute)}
+	enter := func(code, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/code", strings.NewReader(url.Values{"code": {code}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		rs.handleCodePage(rec, req)
+		return rec
+	}
+
+	rec := enter(strings.ToLower(code[1]), "192.0.2.1:1234")
+	if want := retrievalBaseURL(b.cfg) + "/s/" + id; rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != want {
+		t.Fatalf("entering the code = %d to %q, want a redirect to %s", rec.Code, rec.Header().Get("Location"), want)
+	}
+	b.cfg.PublicBaseURL = "https://example.com/hush"
+	if rec := enter(code[1], "192.0.2.1:1234"); rec.Header().Get("Location") != "https://example.com/hush/s/"+id {
+		t.Errorf("redirect behind a prefixed PUBLIC_BASE_URL = %q, want it kept", rec.Header().Get("Location"))
+	}
+	b.cfg.PublicBaseURL = ""
+
+	for i := 0; i < codeMaxFailures; i++ {
+		if rec := enter("0000-0000", "192.0.2.2:1234"); rec.Code != http.StatusNotFound {
+			t.Fatalf("wrong code %d = %d, want 404", i+1, rec.Code)
+		}
+	}
+	if rec := enter(code[1], "192.0.2.2:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("right code after %d wrong ones = %d, want the address locked out", codeMaxFailures, rec.Code)
+	}
+	if rec := enter(code[1], "192.0.2.3:1234"); rec.Code != http.StatusSeeOther {
+		t.Errorf("right code from another address = %d, want a redirect", rec.Code)
+	}
+}
+
+func TestShortCodeRateLimit(t *testing.T) {
+	rs := &retrievalServer{cfg: &Config{}, state: newMemoryState(), codeLimiter: newRateLimiter(1, time.Minute)}
+	enter := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/code", strings.NewReader("code=ABCD-EFGH"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		rs.handleCodePage(rec, req)
+		return rec.Code
+	}
+	if got := enter(); got != http.StatusNotFound {
+		t.Errorf("first attempt = %d, want 404", got)
+	}
+	if got := enter(); got != http.StatusTooManyRequests {
+		t.Errorf("second attempt = %d, want 429", got)
+	}
+}
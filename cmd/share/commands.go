@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// commandSpec describes a slash command. The registry drives both the
+// CommandRouter and /help, so a command documents itself as soon as it is
+// added.
+type commandSpec struct {
+	name        string
+	args        string
+	description string
+	flags       []flagSpec
+	examples    []string
+	run         func(*bot, context.Context, slack.SlashCommand)
+}
+
+type flagSpec struct {
+	name        string
+	description string
+}
+
+// inChannelCapable are the commands whose confirmations never carry a secret,
+// a token or a link to one, so IN_CHANNEL_RESPONSES may make them visible to
+// the channel.
+var inChannelCapable = []string{"/revoke", "/help"}
+
+// commands returns the registry of slash commands, with defaults and limits
+// taken from cfg.
+func commands(cfg *Config) []commandSpec {
+	ttlFlag := flagSpec{"--ttl <duration>", fmt.Sprintf("How long the link stays valid. Defaults to %s, at most %s.", formatDuration(cfg.defaultTTL()), formatDuration(cfg.MaxTTL))}
+	expiresFlag := flagSpec{"--expires-at <time>", "When the link stops working, as an RFC 3339 timestamp such as `2025-06-01T18:00:00Z`, instead of `--ttl`. The same maximum applies."}
+	usesFlag := flagSpec{"--uses <n>", fmt.Sprintf("How many times it can be retrieved. Defaults to %d, at most %d.", cfg.defaultUses(), cfg.MaxUses)}
+	if cfg.AllowUnlimitedUses {
+		usesFlag.description += " Use 0 for unlimited."
+	}
+	notifyFlag := flagSpec{"--no-notify", "Don't DM me when it is first retrieved."}
+	burnFlag := flagSpec{"--burn", "Destroy it as soon as it is viewed. Implies `--uses 1`."}
+	codeFlag := flagSpec{"--code", "Also give me a short code to read out, which the recipient types on the retrieval page instead of opening the link."}
+	labelFlag := flagSpec{"--label <text>", fmt.Sprintf("A description to recognise it by in `/list` and the Home tab, such as `--label \"prod db password\"`. At most %d characters.", maxLabelLength)}
+	formatFlag := flagSpec{"--format curl|vault|url", "How the reply says to read it from a terminal: a `curl` command, a Vault CLI command, or only the `url` of the retrieval page. Defaults to `curl`."}
+	emailFlag := flagSpec{"--email <address>", "Email the link to someone outside Slack instead. It can be retrieved once."}
+	passphraseFlag := flagSpec{"--passphrase <word>", "The retrieval page asks for this before revealing it. Tell it to the recipient separately. The secret is destroyed after 5 wrong ones."}
+	toFlag := flagSpec{"--to @user[,@user]", "Only these people can open it. Each is sent a personal link by DM."}
+	groupFlag := flagSpec{"--group @group[,@group]", "Like `--to`, for every member of these user groups, such as an on-call rotation."}
+
+	return []commandSpec{
+		{
+			name:        "/share",
+			args:        "[--ttl 30m | --expires-at <time>] [--uses 1] [--to @user] [--group @group | --email <address>] [--label <text>] [--passphrase <word>] [--format curl|vault|url] [--no-notify] [--burn] [--code] <secret>",
+			description: "Share a secret through a self-destructing link. Run it on its own to open a form instead, which can also share a file.",
+			flags:       []flagSpec{ttlFlag, expiresFlag, usesFlag, toFlag, groupFlag, emailFlag, labelFlag, passphraseFlag, formatFlag, notifyFlag, burnFlag, codeFlag},
+			examples:    []string{"/share hunter2", "/share --ttl 2h --uses 3 hunter2", "/share --to @alice hunter2", "/share --group @oncall hunter2", "/share --burn hunter2", "/share --code hunter2", "/share --format vault hunter2", "/share"},
+			run:         (*bot).handleShareCommand,
+		},
+		{
+			name:        "/share-channel",
+			args:        "[--ttl 30m | --expires-at <time>] [--uses 1] [--label <text>] [--passphrase <word>] [--no-notify] [--burn] <secret>",
+			description: "Share a secret like `/share`, but post its link for everyone in the channel to see, along with who shared it. The secret itself is never posted.",
+			flags:       []flagSpec{ttlFlag, expiresFlag, usesFlag, labelFlag, passphraseFlag, notifyFlag, burnFlag},
+			examples:    []string{"/share-channel --uses 5 hunter2"},
+			run:         (*bot).handleShareChannelCommand,
+		},
+		{
+			name:        "/generate",
+			args:        "[--charset alphanumeric|full] [--ttl 30m | --expires-at <time>] [--uses 1] [--to @user] [--no-notify] [length]",
+			description: fmt.Sprintf("Generate a random password and share it. The length is %d to %d characters and defaults to %d.", minPasswordLength, maxPasswordLength, defaultPasswordLength),
+			flags: []flagSpec{
+				{"--charset <name>", "`alphanumeric` for letters and digits, or `full` to add symbols. Defaults to `full`."},
+				ttlFlag, expiresFlag, usesFlag, toFlag, notifyFlag,
+			},
+			examples: []string{"/generate", "/generate --charset alphanumeric 32"},
+			run:      (*bot).handleGenerateCommand,
+		},
+		{
+			name:        "/revoke",
+			args:        "<secretID>",
+			description: "Destroy a secret you shared before it expires.",
+			examples:    []string{"/revoke secret-m5rx3qgkz7a2t4vdl6bhye2nwi"},
+			run:         (*bot).handleRevokeCommand,
+		},
+		{
+			name:        "/extend",
+			args:        "<secretID> <duration>",
+			description: fmt.Sprintf("Give a secret you shared that has not been retrieved yet a new token valid for the given time from now, at most %s. The old token is revoked and the link keeps working.", formatDuration(cfg.MaxTTL)),
+			examples:    []string{"/extend secret-m5rx3qgkz7a2t4vdl6bhye2nwi 2h"},
+			run:         (*bot).handleExtendCommand,
+		},
+		{
+			name:        "/transfer",
+			args:        "<secretID> @newowner",
+			description: "Make someone else the owner of a secret you shared, such as before you go on leave, so that they can revoke, extend and rotate it. Admins can transfer anyone's secrets.",
+			examples:    []string{"/transfer secret-m5rx3qgkz7a2t4vdl6bhye2nwi @alice"},
+			run:         (*bot).handleTransferCommand,
+		},
+		{
+			name:        "/rotate",
+			args:        "<secretID> [new value]",
+			description: "Replace a secret you shared with a new value, or a generated password if none is given, under a new ID with the same limits. The old secret is revoked and anyone it was shared with via `--to` is sent a link to the new value.",
+			examples:    []string{"/rotate secret-m5rx3qgkz7a2t4vdl6bhye2nwi", "/rotate secret-m5rx3qgkz7a2t4vdl6bhye2nwi hunter3"},
+			run:         (*bot).handleRotateCommand,
+		},
+		{
+			name:        "/list",
+			args:        "[page]",
+			description: fmt.Sprintf("List the secrets you have shared that can still be retrieved, %d per page.", listPageSize),
+			examples:    []string{"/list", "/list 2"},
+			run:         (*bot).handleListCommand,
+		},
+		{
+			name:        "/share-admin",
+			args:        "list [page] | revoke <age>",
+			description: fmt.Sprintf("For admins: `list` shows every secret shared in this workspace, oldest first, with who shared it and how old it is, %d per page. `revoke` destroys every secret shared longer ago than the age, such as `12h` or `7d`, once you confirm.", listPageSize),
+			examples:    []string{"/share-admin list", "/share-admin revoke 30d"},
+			run:         (*bot).handleAdminCommand,
+		},
+		{
+			name:        "/whoami",
+			description: "Show how the bot is configured and whether it can reach Vault, to check a new setup. Never shows tokens or secrets.",
+			run:         (*bot).handleWhoamiCommand,
+		},
+		{
+			name:        "/help",
+			description: "Show this message.",
+			run:         (*bot).handleHelpCommand,
+		},
+	}
+}
+
+func (c commandSpec) usage() string {
+	if c.args == "" {
+		return c.name
+	}
+	return c.name + " " + c.args
+}
+
+func (b *bot) handleHelpCommand(ctx context.Context, cmd slack.SlashCommand) {
+	specs := slices.DeleteFunc(commands(b.cfg), func(c commandSpec) bool { return !b.cfg.FeatureFlags.Enabled(c.name) })
+	sendSlackResponseType(b.slack, cmd.ResponseURL, b.replyType(cmd.Command), helpText(specs))
+}
+
+// helpText renders the registry as a Slack message.
+func helpText(specs []commandSpec) string {
+	var sb strings.Builder
+	sb.WriteString("*Available commands*\n")
+	for _, c := range specs {
+		fmt.Fprintf(&sb, "\n`%s`\n%s\n", c.usage(), c.description)
+		for _, f := range c.flags {
+			fmt.Fprintf(&sb, "• `%s` %s\n", f.name, f.description)
+		}
+		for _, e := range c.examples {
+			fmt.Fprintf(&sb, "Example: `%s`\n", e)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestHelpTextListsEveryCommand(t *testing.T) {
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses}}
+	specs := commands(cfg)
+	text := helpText(specs)
+	for _, c := range specs {
+		if !strings.Contains(text, "`"+c.usage()+"`") {
+			t.Errorf("help text does not include %s", c.usage())
+		}
+		for _, f := range c.flags {
+			if !strings.Contains(text, f.name) {
+				t.Errorf("help text does not include flag %s of %s", f.name, c.name)
+			}
+		}
+	}
+}
+
+func TestHelpResponseType(t *testing.T) {
+	var got []string
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ResponseType string `json:"response_type"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		got = append(got, msg.ResponseType)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer slackAPI.Close()
+
+	b, _, _ := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.slack = socketmode.New(slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+	cmd := slack.SlashCommand{Command: "/help", UserID: "U1", ResponseURL: slackAPI.URL + "/response"}
+
+	b.handleHelpCommand(context.Background(), cmd)
+	b.cfg.InChannelResponses = []string{"/help"}
+	b.handleHelpCommand(context.Background(), cmd)
+	if want := []string{slack.ResponseTypeEphemeral, slack.ResponseTypeInChannel}; !slices.Equal(got, want) {
+		t.Errorf("response types = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfigInChannelResponses(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("IN_CHANNEL_RESPONSES", "revoke, /help")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if want := []string{"/revoke", "/help"}; !slices.Equal(cfg.InChannelResponses, want) {
+		t.Errorf("InChannelResponses = %q, want %q", cfg.InChannelResponses, want)
+	}
+
+	// Replies to /share carry the secret's token and link.
+	t.Setenv("IN_CHANNEL_RESPONSES", "/share")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "IN_CHANNEL_RESPONSES") {
+		t.Errorf("LoadConfig() with /share error = %v, want it to name IN_CHANNEL_RESPONSES", err)
+	}
+}
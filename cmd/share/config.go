@@ -0,0 +1,590 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Config holds the bot's runtime configuration, read from the environment.
+type Config struct {
+	// Mode is how the bot receives Slack payloads: "socket" for socket
+	// mode, which needs SlackAppToken, or "http" for Slack to POST them to
+	// SlackHTTPAddr, signed with SlackSigningSecret.
+	Mode               string
+	SlackAppToken      string
+	SlackSigningSecret string
+	SlackHTTPAddr      string
+	SlackBotToken      string
+	// SlackWorkspaces, when set, maps the team ID of each workspace the bot
+	// serves to its bot token, and secrets are kept apart by team in Vault.
+	SlackWorkspaces map[string]string
+	VaultAddr       string
+	VaultToken      string
+	// VaultNamespace is the Vault Enterprise namespace every request is
+	// made in, secret paths and token creation included.
+	VaultNamespace string
+
+	// VaultCACert, VaultClientCert and VaultClientKey are PEM file paths for
+	// verifying Vault's certificate and authenticating to it with mutual
+	// TLS. VaultSkipVerify disables certificate verification.
+	VaultCACert     string
+	VaultClientCert string
+	VaultClientKey  string
+	VaultSkipVerify bool
+
+	// VaultMaxAttempts is how many times a Vault request is tried before a
+	// transient failure is reported.
+	VaultMaxAttempts int
+	// VaultTimeout bounds the Vault requests made for a single operation,
+	// retries included.
+	VaultTimeout time.Duration
+	// VaultBreakerThreshold is how many Vault operations in a row may fail
+	// before the circuit breaker opens, and VaultBreakerCooldown how long it
+	// stays open before probing Vault again.
+	VaultBreakerThreshold int
+	VaultBreakerCooldown  time.Duration
+	// vaultBreaker is shared by every Vault client made for this
+	// configuration, so that they agree on whether Vault is up. It is nil,
+	// and never opens, in configurations not made by LoadConfig.
+	vaultBreaker *circuitBreaker
+	// VaultStartup is what the bot does when Vault cannot be reached at
+	// startup, one of vaultStartups, and VaultStartupRetry how often it
+	// checks again when it starts degraded.
+	VaultStartup      string
+	VaultStartupRetry time.Duration
+
+	// VaultSecretsMount is the mount path of the KV secrets engine and
+	// VaultKVVersion its version, 1 or 2.
+	VaultSecretsMount string
+	VaultKVVersion    int
+	// SecretLayout is how shared secrets are arranged under the mount, one
+	// of secretLayouts.
+	SecretLayout string
+
+	// TokenPolicies are Vault policies attached to the tokens issued to
+	// recipients in addition to the policy written for each secret.
+	TokenPolicies []string
+
+	// VaultRoleID and VaultSecretID, when both set, authenticate the bot
+	// with AppRole instead of VaultToken.
+	VaultRoleID   string
+	VaultSecretID string
+
+	SharePolicy
+	// ShareRateLimit is how many secrets each user may share per minute.
+	ShareRateLimit int
+
+	// SMTPAddr, when set, is the host:port of the SMTP relay that --email
+	// links are sent through, from SMTPFrom. SMTPUsername and SMTPPassword
+	// authenticate to it.
+	SMTPAddr     string
+	SMTPFrom     string
+	SMTPUsername string
+	SMTPPassword string
+	// EmailRateLimit is how many emails each user may send per hour.
+	EmailRateLimit int
+
+	// MaxInflight is how many command handlers may run at once.
+	MaxInflight int
+	// CommandTimeout bounds a command's handler as a whole, Vault requests
+	// and replies included.
+	CommandTimeout time.Duration
+	// FeatureFlags turns commands off.
+	FeatureFlags FeatureFlags
+	// InChannelResponses are the commands whose confirmations are posted
+	// for the whole channel to see rather than only to the user who ran
+	// them. Only commands listed in inChannelCapable qualify.
+	InChannelResponses []string
+	// ShareAllowedChannels are the channel IDs and names, without the #,
+	// that secrets may be shared from. Any channel may be used when it is
+	// empty.
+	ShareAllowedChannels []string
+	// MaxActiveSecrets is the most secrets each workspace may have stored
+	// at once, and MaxActiveSecretsPerUser the most each user may have
+	// that can still be retrieved.
+	MaxActiveSecrets        int
+	MaxActiveSecretsPerUser int
+	// MaxGroupMembers is the largest user group a secret may be shared
+	// with using --group.
+	MaxGroupMembers int
+	// AdminUsers are the Slack user IDs allowed to run /share-admin.
+	AdminUsers []string
+	// ConfirmLength is the length above which a secret pasted into /share
+	// must be confirmed before it is shared. Multi-line secrets always are.
+	ConfirmLength int
+	// TokenDisplayName, when set, renders the display name of the tokens
+	// issued to recipients in place of defaultTokenDisplayName, and
+	// TokenMetadata is added to their metadata.
+	TokenDisplayName *template.Template
+	TokenMetadata    map[string]string
+	// ShareTemplate, when set, renders the reply to a share in place of
+	// defaultShareMessage.
+	ShareTemplate *template.Template
+	// CredentialDetectors recognise high-risk credentials, which are only
+	// shared with --burn and for at most DetectedMaxTTL.
+	CredentialDetectors []credentialDetector
+	DetectedMaxTTL      time.Duration
+
+	// SweepInterval is how often secrets are checked against their expiry
+	// and SecretMaxAge, the longest any secret is kept in Vault. It
+	// defaults to MaxTTL.
+	SweepInterval time.Duration
+	SecretMaxAge  time.Duration
+	// ReconcileInterval is how often issued tokens and stored secrets are
+	// checked against each other for orphans.
+	ReconcileInterval time.Duration
+
+	// EncryptionKey, when set, is used to AES-GCM encrypt secrets before
+	// they are written to Vault.
+	EncryptionKey []byte
+	// TransitKey, when set, is the name of a key in the transit engine
+	// mounted at TransitMount that encrypts secrets inside Vault instead.
+	TransitKey   string
+	TransitMount string
+	// LinkSigningKey signs the personal links sent to the recipients of a
+	// --to share. A random key is used when it is not configured.
+	LinkSigningKey []byte
+	// RedisOptions, when set, keeps the bot's short-lived state in Redis
+	// instead of memory, so that replicas share it and it survives restarts.
+	RedisOptions *redis.Options
+	// RetrievalAddr is the listen address of the retrieval HTTP server.
+	RetrievalAddr string
+	// PrettyPrintJSON indents secrets that are JSON objects or arrays on
+	// the retrieval page.
+	PrettyPrintJSON bool
+	// PublicBaseURL, when set, is the URL recipients reach the retrieval
+	// server at, such as through a reverse proxy, and links are built from
+	// it instead of RetrievalAddr.
+	PublicBaseURL string
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For header
+	// is believed.
+	TrustedProxies []netip.Prefix
+
+	// RevokeOnShutdown revokes every recipient token the bot issued when it
+	// shuts down gracefully.
+	RevokeOnShutdown bool
+
+	// SelfTest runs runSelfTest at startup, and refuses to start if it
+	// fails.
+	SelfTest bool
+	// DryRun skips writing secrets and tokens to Vault and replies with
+	// fake ones instead.
+	DryRun bool
+
+	// AuditLogFile, when set, is a file that audit events are appended to
+	// as JSON lines.
+	AuditLogFile string
+	// AuditVaultPath, when set, is a KV path under which each audit event is
+	// written, such as secrets/data/audit.
+	AuditVaultPath string
+
+	// MetricsAddr, when set, is the listen address of the Prometheus
+	// metrics server.
+	MetricsAddr string
+	// HealthAddr, when set, is the listen address of the /healthz and
+	// /readyz probe server.
+	HealthAddr string
+
+	// LogLevel is the minimum level of log records to emit.
+	LogLevel slog.Level
+	// SlackDebug enables the Slack client's debug logging, whatever
+	// LogLevel is.
+	SlackDebug bool
+	// SlackPingTimeout is how long the Socket Mode connection may go
+	// without a ping from Slack before it is reopened, and for how long it
+	// may be down before /readyz fails. SlackConnectTimeout bounds each
+	// attempt to open it.
+	SlackPingTimeout    time.Duration
+	SlackConnectTimeout time.Duration
+}
+
+// LoadConfig reads the configuration from the environment and validates it.
+// The returned error names every variable that is missing or malformed.
+func LoadConfig() (*Config, error) {
+	return loadConfig(true)
+}
+
+// loadCLIConfig reads the configuration like LoadConfig for the command-line
+// subcommands, which talk to Vault but not to Slack, so that the Slack
+// tokens are not required.
+func loadCLIConfig() (*Config, error) {
+	return loadConfig(false)
+}
+
+func loadConfig(needSlack bool) (*Config, error) {
+	var errs []error
+	cfg := &Config{
+		Mode:               stringEnv("MODE", modeSocket),
+		SlackAppToken:      os.Getenv("SLACK_APP_TOKEN"),
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		SlackHTTPAddr:      stringEnv("SLACK_HTTP_ADDR", defaultSlackHTTPAddr),
+		SlackBotToken:      os.Getenv("SLACK_BOT_TOKEN"),
+		VaultAddr:          requireEnv("VAULT_ADDR", &errs),
+		VaultToken:         os.Getenv("VAULT_TOKEN"),
+		VaultNamespace:     os.Getenv("VAULT_NAMESPACE"),
+		VaultRoleID:        os.Getenv("VAULT_ROLE_ID"),
+		VaultSecretID:      os.Getenv("VAULT_SECRET_ID"),
+
+		VaultCACert:     os.Getenv("VAULT_CACERT"),
+		VaultClientCert: os.Getenv("VAULT_CLIENT_CERT"),
+		VaultClientKey:  os.Getenv("VAULT_CLIENT_KEY"),
+		VaultSkipVerify: boolEnv("VAULT_SKIP_VERIFY", false, &errs),
+
+		VaultMaxAttempts: intEnv("VAULT_MAX_ATTEMPTS", defaultVaultMaxAttempts, &errs),
+		VaultTimeout:     durationEnv("VAULT_TIMEOUT", defaultVaultTimeout, &errs),
+
+		VaultBreakerThreshold: intEnv("VAULT_BREAKER_THRESHOLD", defaultBreakerThreshold, &errs),
+		VaultBreakerCooldown:  durationEnv("VAULT_BREAKER_COOLDOWN", defaultBreakerCooldown, &errs),
+
+		VaultStartup:      stringEnv("VAULT_STARTUP", startupFail),
+		VaultStartupRetry: durationEnv("VAULT_STARTUP_RETRY_INTERVAL", defaultStartupRetry, &errs),
+
+		VaultSecretsMount: strings.Trim(stringEnv("VAULT_SECRETS_MOUNT", defaultSecretsMount), "/"),
+		VaultKVVersion:    intEnv("VAULT_KV_VERSION", defaultKVVersion, &errs),
+		SecretLayout:      stringEnv("SECRET_PATH_LAYOUT", layoutFlat),
+
+		SharePolicy: SharePolicy{
+			MaxTTL:             durationEnv("MAX_TOKEN_TTL", defaultMaxTTL, &errs),
+			DefaultTTL:         durationEnv("DEFAULT_TOKEN_TTL", defaultTokenTTL, &errs),
+			MaxUses:            intEnv("MAX_TOKEN_USES", defaultMaxUses, &errs),
+			DefaultUses:        intEnv("DEFAULT_TOKEN_USES", defaultTokenUses, &errs),
+			AllowUnlimitedUses: boolEnv("ALLOW_UNLIMITED_USES", false, &errs),
+			MaxFileBytes:       intEnv("MAX_FILE_BYTES", defaultMaxFileBytes, &errs),
+			MaxSecretBytes:     intEnv("MAX_SECRET_BYTES", defaultMaxSecretBytes, &errs),
+		},
+		ShareRateLimit:  intEnv("SHARE_RATE_LIMIT", defaultShareRateLimit, &errs),
+		MaxGroupMembers: intEnv("MAX_GROUP_MEMBERS", defaultMaxGroupMembers, &errs),
+
+		MaxActiveSecrets:        intEnv("MAX_ACTIVE_SECRETS", defaultMaxActiveSecrets, &errs),
+		MaxActiveSecretsPerUser: intEnv("MAX_ACTIVE_SECRETS_PER_USER", defaultMaxActiveSecretsPerUser, &errs),
+
+		SMTPAddr:       os.Getenv("SMTP_ADDR"),
+		SMTPFrom:       os.Getenv("SMTP_FROM"),
+		SMTPUsername:   os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:   os.Getenv("SMTP_PASSWORD"),
+		EmailRateLimit: intEnv("EMAIL_RATE_LIMIT", defaultEmailRateLimit, &errs),
+
+		MaxInflight: intEnv("MAX_INFLIGHT_COMMANDS", defaultMaxInflight, &errs),
+		SlackDebug:  boolEnv("SLACK_DEBUG", false, &errs),
+
+		SlackPingTimeout:    durationEnv("SLACK_PING_TIMEOUT", defaultSlackPingTimeout, &errs),
+		SlackConnectTimeout: durationEnv("SLACK_CONNECT_TIMEOUT", defaultSlackConnectTimeout, &errs),
+
+		CommandTimeout: durationEnv("COMMAND_TIMEOUT", defaultCommandTimeout, &errs),
+		ConfirmLength:  intEnv("CONFIRM_SECRET_LENGTH", defaultConfirmLength, &errs),
+		DetectedMaxTTL: durationEnv("DETECTED_CREDENTIAL_MAX_TTL", defaultDetectedMaxTTL, &errs),
+
+		SweepInterval:     durationEnv("SWEEP_INTERVAL", defaultSweepInterval, &errs),
+		ReconcileInterval: durationEnv("RECONCILE_INTERVAL", defaultReconcileInterval, &errs),
+
+		TransitKey:      os.Getenv("VAULT_TRANSIT_KEY"),
+		TransitMount:    strings.Trim(stringEnv("VAULT_TRANSIT_MOUNT", defaultTransitMount), "/"),
+		RetrievalAddr:   stringEnv("RETRIEVAL_ADDR", defaultRetrievalAddr),
+		PrettyPrintJSON: boolEnv("PRETTY_PRINT_JSON", true, &errs),
+
+		RevokeOnShutdown: boolEnv("REVOKE_ON_SHUTDOWN", false, &errs),
+
+		DryRun:   boolEnv("DRY_RUN", false, &errs),
+		SelfTest: boolEnv("SELF_TEST", false, &errs),
+
+		MetricsAddr: os.Getenv("METRICS_ADDR"),
+		HealthAddr:  os.Getenv("HEALTH_ADDR"),
+
+		AuditLogFile:   os.Getenv("AUDIT_LOG_FILE"),
+		AuditVaultPath: strings.Trim(os.Getenv("AUDIT_VAULT_PATH"), "/"),
+	}
+
+	switch {
+	case !needSlack:
+	case cfg.Mode == modeSocket:
+		if cfg.SlackAppToken == "" {
+			errs = append(errs, errors.New("missing required environment variable SLACK_APP_TOKEN"))
+		}
+	case cfg.Mode == modeHTTP:
+		if cfg.SlackSigningSecret == "" {
+			errs = append(errs, errors.New("missing required environment variable SLACK_SIGNING_SECRET, which MODE=http needs"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MODE %q must be socket or http", cfg.Mode))
+	}
+
+	if v := os.Getenv("SLACK_WORKSPACES_FILE"); v != "" {
+		tokens, err := loadWorkspaceTokens(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SLACK_WORKSPACES_FILE %w", err))
+		}
+		cfg.SlackWorkspaces = tokens
+	} else if cfg.SlackBotToken == "" && needSlack {
+		errs = append(errs, errors.New("missing required environment variable SLACK_BOT_TOKEN (or SLACK_WORKSPACES_FILE)"))
+	}
+
+	if cfg.VaultAddr != "" {
+		if u, err := url.Parse(cfg.VaultAddr); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("VAULT_ADDR %q is not a valid http(s) URL", cfg.VaultAddr))
+		}
+	}
+
+	if v := os.Getenv("PUBLIC_BASE_URL"); v != "" {
+		base, err := parsePublicBaseURL(v)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		cfg.PublicBaseURL = base
+	}
+	proxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	cfg.TrustedProxies = proxies
+
+	if !slices.Contains(vaultStartups, cfg.VaultStartup) {
+		errs = append(errs, fmt.Errorf("VAULT_STARTUP %q must be one of %s", cfg.VaultStartup, strings.Join(vaultStartups, ", ")))
+	}
+
+	if cfg.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.SMTPAddr); err != nil {
+			errs = append(errs, fmt.Errorf("SMTP_ADDR %q must be a host:port", cfg.SMTPAddr))
+		}
+		if _, err := parseEmail(cfg.SMTPFrom); err != nil {
+			errs = append(errs, fmt.Errorf("SMTP_FROM %q must be set to a plain email address when SMTP_ADDR is", cfg.SMTPFrom))
+		}
+	}
+
+	for name, path := range map[string]string{
+		"VAULT_CACERT":      cfg.VaultCACert,
+		"VAULT_CLIENT_CERT": cfg.VaultClientCert,
+		"VAULT_CLIENT_KEY":  cfg.VaultClientKey,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.ReadFile(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s cannot be read: %w", name, err))
+		}
+	}
+	if (cfg.VaultClientCert == "") != (cfg.VaultClientKey == "") {
+		errs = append(errs, errors.New("VAULT_CLIENT_CERT and VAULT_CLIENT_KEY must be set together"))
+	}
+
+	if cfg.VaultSecretsMount == "" {
+		errs = append(errs, errors.New("VAULT_SECRETS_MOUNT must not be empty"))
+	}
+	if cfg.VaultKVVersion != 1 && cfg.VaultKVVersion != 2 {
+		errs = append(errs, fmt.Errorf("VAULT_KV_VERSION %d must be 1 or 2", cfg.VaultKVVersion))
+	}
+	if !slices.Contains(secretLayouts, cfg.SecretLayout) {
+		errs = append(errs, fmt.Errorf("SECRET_PATH_LAYOUT %q must be one of %s", cfg.SecretLayout, strings.Join(secretLayouts, ", ")))
+	}
+
+	for _, p := range strings.Split(os.Getenv("VAULT_TOKEN_POLICY"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.TokenPolicies = append(cfg.TokenPolicies, p)
+		}
+	}
+
+	for _, c := range strings.Split(os.Getenv("SHARE_ALLOWED_CHANNELS"), ",") {
+		if c = strings.TrimPrefix(strings.TrimSpace(c), "#"); c != "" {
+			cfg.ShareAllowedChannels = append(cfg.ShareAllowedChannels, c)
+		}
+	}
+
+	for _, u := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		if u = strings.TrimSpace(u); u == "" {
+			continue
+		}
+		if !userIDPattern.MatchString(u) {
+			errs = append(errs, fmt.Errorf("ADMIN_USERS entry %q is not a Slack user ID", u))
+			continue
+		}
+		cfg.AdminUsers = append(cfg.AdminUsers, u)
+	}
+
+	for _, c := range strings.Split(os.Getenv("IN_CHANNEL_RESPONSES"), ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		if !strings.HasPrefix(c, "/") {
+			c = "/" + c
+		}
+		if !slices.Contains(inChannelCapable, c) {
+			errs = append(errs, fmt.Errorf("IN_CHANNEL_RESPONSES %q must be one of %s; commands whose replies can carry a secret always reply privately", c, strings.Join(inChannelCapable, ", ")))
+			continue
+		}
+		cfg.InChannelResponses = append(cfg.InChannelResponses, c)
+	}
+
+	var known []string
+	for _, c := range commands(cfg) {
+		known = append(known, c.name)
+	}
+	if cfg.FeatureFlags.Disabled, err = parseDisabledCommands(os.Getenv("DISABLED_COMMANDS"), known); err != nil {
+		errs = append(errs, err)
+	}
+
+	switch {
+	case (cfg.VaultRoleID == "") != (cfg.VaultSecretID == ""):
+		errs = append(errs, errors.New("VAULT_ROLE_ID and VAULT_SECRET_ID must be set together"))
+	case !cfg.useAppRole() && cfg.VaultToken == "":
+		errs = append(errs, errors.New("missing required environment variable VAULT_TOKEN (or VAULT_ROLE_ID and VAULT_SECRET_ID)"))
+	}
+
+	if cfg.DefaultTTL > cfg.MaxTTL {
+		errs = append(errs, fmt.Errorf("DEFAULT_TOKEN_TTL %s must be at most MAX_TOKEN_TTL %s", cfg.DefaultTTL, cfg.MaxTTL))
+	}
+	if cfg.MaxGroupMembers > maxGroupMembers {
+		errs = append(errs, fmt.Errorf("MAX_GROUP_MEMBERS %d must be at most %d", cfg.MaxGroupMembers, maxGroupMembers))
+	}
+	if cfg.DefaultUses > cfg.MaxUses {
+		errs = append(errs, fmt.Errorf("DEFAULT_TOKEN_USES %d must be at most MAX_TOKEN_USES %d", cfg.DefaultUses, cfg.MaxUses))
+	}
+	cfg.SecretMaxAge = durationEnv("SECRET_MAX_AGE", cfg.MaxTTL, &errs)
+	if cfg.SecretMaxAge < cfg.MaxTTL {
+		errs = append(errs, fmt.Errorf("SECRET_MAX_AGE %s must be at least MAX_TOKEN_TTL %s", cfg.SecretMaxAge, cfg.MaxTTL))
+	}
+	if cfg.CommandTimeout < cfg.VaultTimeout {
+		errs = append(errs, fmt.Errorf("COMMAND_TIMEOUT %s must be at least VAULT_TIMEOUT %s", cfg.CommandTimeout, cfg.VaultTimeout))
+	}
+
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := cfg.LogLevel.UnmarshalText([]byte(v)); err != nil {
+			errs = append(errs, fmt.Errorf("LOG_LEVEL %q must be one of debug, info, warn or error", v))
+		}
+	}
+
+	if v := os.Getenv("ENCRYPTION_KEY"); v != "" {
+		key, err := decodeEncryptionKey(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ENCRYPTION_KEY %w", err))
+		}
+		cfg.EncryptionKey = key
+	}
+	if cfg.EncryptionKey != nil && cfg.TransitKey != "" {
+		errs = append(errs, errors.New("ENCRYPTION_KEY and VAULT_TRANSIT_KEY cannot both be set"))
+	}
+
+	if v := os.Getenv("LINK_SIGNING_KEY"); v != "" {
+		key, err := decodeEncryptionKey(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("LINK_SIGNING_KEY %w", err))
+		}
+		cfg.LinkSigningKey = key
+	}
+
+	cfg.RedisOptions = stateBackendEnv(&errs)
+	if cfg.RedisOptions != nil && cfg.LinkSigningKey == nil {
+		// Pending shares are encrypted with a key derived from it, which
+		// every replica must agree on.
+		errs = append(errs, errors.New("LINK_SIGNING_KEY is required when STATE_BACKEND is redis"))
+	}
+
+	cfg.ShareTemplate = shareTemplateEnv(&errs)
+	cfg.TokenDisplayName = tokenDisplayNameEnv(&errs)
+	cfg.TokenMetadata = tokenMetadataEnv(&errs)
+	cfg.CredentialDetectors = credentialDetectorsEnv(&errs)
+	cfg.vaultBreaker = newCircuitBreaker(cfg.VaultBreakerThreshold, cfg.VaultBreakerCooldown)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// kvPaths returns the paths of the secrets shared in teamID's workspace in
+// the configured KV engine. Each team has its own directories when the bot
+// serves several workspaces.
+func (c *Config) kvPaths(teamID string) kvPaths {
+	p := kvPaths{mount: c.VaultSecretsMount, version: c.VaultKVVersion, channels: c.SecretLayout == layoutChannel}
+	if c.teamScoped() {
+		p.team = teamID
+	}
+	return p
+}
+
+// teamScoped reports whether secrets, and the links to them, are kept apart
+// by workspace: always with several workspaces, and with one when
+// SECRET_PATH_LAYOUT asks for it.
+func (c *Config) teamScoped() bool {
+	return c.multiWorkspace() || c.SecretLayout == layoutTeam || c.SecretLayout == layoutChannel
+}
+
+// multiWorkspace reports whether the bot serves several Slack workspaces.
+func (c *Config) multiWorkspace() bool {
+	return len(c.SlackWorkspaces) > 0
+}
+
+// encrypted reports whether secrets are encrypted before they are stored, so
+// that recipients must retrieve them through the retrieval server.
+func (c *Config) encrypted() bool {
+	return c.EncryptionKey != nil || c.TransitKey != ""
+}
+
+// useAppRole reports whether the bot authenticates to Vault with AppRole.
+func (c *Config) useAppRole() bool {
+	return c.VaultRoleID != "" && c.VaultSecretID != ""
+}
+
+func requireEnv(name string, errs *[]error) string {
+	v := os.Getenv(name)
+	if v == "" {
+		*errs = append(*errs, fmt.Errorf("missing required environment variable %s", name))
+	}
+	return v
+}
+
+func stringEnv(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func durationEnv(name string, def time.Duration, errs *[]error) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s %q must be a positive duration such as 24h", name, v))
+		return def
+	}
+	return d
+}
+
+func intEnv(name string, def int, errs *[]error) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s %q must be a positive integer", name, v))
+		return def
+	}
+	return n
+}
+
+func boolEnv(name string, def bool, errs *[]error) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s %q must be true or false", name, v))
+		return def
+	}
+	return b
+}
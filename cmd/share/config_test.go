@@ -0,0 +1,235 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("SLACK_APP_TOKEN", "xapp-test")
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb-test")
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:8200")
+	t.Setenv("VAULT_TOKEN", "hvs.test")
+}
+
+func TestLoadConfig(t *testing.T) {
+	setRequiredEnv(t)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.VaultAddr != "http://127.0.0.1:8200" {
+		t.Errorf("VaultAddr = %q", cfg.VaultAddr)
+	}
+	if cfg.MaxTTL != defaultMaxTTL || cfg.MaxUses != defaultMaxUses || cfg.AllowUnlimitedUses || cfg.SecretMaxAge != defaultMaxTTL || cfg.MaxSecretBytes != defaultMaxSecretBytes || cfg.DefaultTTL != defaultTokenTTL || cfg.DefaultUses != defaultTokenUses {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	for _, name := range []string{"SLACK_APP_TOKEN", "SLACK_BOT_TOKEN", "VAULT_ADDR", "VAULT_TOKEN"} {
+		t.Run(name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(name, "")
+
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatal("LoadConfig() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), name) {
+				t.Errorf("error %q does not name %s", err, name)
+			}
+		})
+	}
+}
+
+func TestLoadConfigHTTPMode(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MODE", "http")
+	t.Setenv("SLACK_APP_TOKEN", "")
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "SLACK_SIGNING_SECRET") {
+		t.Errorf("LoadConfig() without a signing secret error = %v, want it to name SLACK_SIGNING_SECRET", err)
+	}
+
+	t.Setenv("SLACK_SIGNING_SECRET", "signing-secret")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.SlackHTTPAddr != defaultSlackHTTPAddr {
+		t.Errorf("SlackHTTPAddr = %q, want %q", cfg.SlackHTTPAddr, defaultSlackHTTPAddr)
+	}
+}
+
+func TestLoadConfigAppRole(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("VAULT_TOKEN", "")
+	t.Setenv("VAULT_ROLE_ID", "role")
+	t.Setenv("VAULT_SECRET_ID", "secret")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if !cfg.useAppRole() {
+		t.Error("useAppRole() = false, want true")
+	}
+
+	t.Setenv("VAULT_SECRET_ID", "")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "VAULT_SECRET_ID") {
+		t.Errorf("LoadConfig() with only VAULT_ROLE_ID error = %v, want it to name VAULT_SECRET_ID", err)
+	}
+}
+
+func TestLoadConfigMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"MODE", "webhook"},
+		{"VAULT_ADDR", "127.0.0.1:8200"},
+		{"VAULT_ADDR", "ftp://vault"},
+		{"VAULT_CACERT", "/nonexistent/ca.pem"},
+		{"VAULT_CLIENT_CERT", "/nonexistent/client.pem"},
+		{"VAULT_SKIP_VERIFY", "perhaps"},
+		{"VAULT_MAX_ATTEMPTS", "0"},
+		{"VAULT_TIMEOUT", "0s"},
+		{"VAULT_BREAKER_THRESHOLD", "0"},
+		{"VAULT_BREAKER_COOLDOWN", "0s"},
+		{"VAULT_STARTUP", "retry"},
+		{"VAULT_STARTUP_RETRY_INTERVAL", "soon"},
+		{"VAULT_KV_VERSION", "3"},
+		{"VAULT_KV_VERSION", "v2"},
+		{"SECRET_PATH_LAYOUT", "workspace"},
+		{"MAX_TOKEN_TTL", "forever"},
+		{"MAX_TOKEN_TTL", "-1h"},
+		{"MAX_TOKEN_USES", "0"},
+		{"MAX_TOKEN_USES", "many"},
+		{"DEFAULT_TOKEN_TTL", "48h"},
+		{"PUBLIC_BASE_URL", "hush.example.com"},
+		{"DISABLED_COMMANDS", "/generate,/nope"},
+		{"TRUSTED_PROXIES", "10.0.0.0/33"},
+		{"DEFAULT_TOKEN_USES", "0"},
+		{"DEFAULT_TOKEN_USES", "11"},
+		{"MAX_GROUP_MEMBERS", "1001"},
+		{"ALLOW_UNLIMITED_USES", "sometimes"},
+		{"MAX_FILE_BYTES", "1MB"},
+		{"SHARE_RATE_LIMIT", "-5"},
+		{"CONFIRM_SECRET_LENGTH", "long"},
+		{"SWEEP_INTERVAL", "often"},
+		{"SECRET_MAX_AGE", "1h"},
+		{"COMMAND_TIMEOUT", "5s"},
+		{"DRY_RUN", "maybe"},
+		{"LOG_LEVEL", "loud"},
+		{"SLACK_DEBUG", "maybe"},
+		{"SMTP_ADDR", "smtp.example.com"},
+		{"EMAIL_RATE_LIMIT", "0"},
+		{"ENCRYPTION_KEY", "not base64!"},
+		{"ENCRYPTION_KEY", "c2hvcnQ="},
+		{"LINK_SIGNING_KEY", "c2hvcnQ="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(tt.name, tt.value)
+
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatal("LoadConfig() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("error %q does not name %s", err, tt.name)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTokenPolicy(t *testing.T) {
+	setRequiredEnv(t)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if len(cfg.TokenPolicies) != 0 {
+		t.Errorf("default TokenPolicies = %q, want none", cfg.TokenPolicies)
+	}
+
+	t.Setenv("VAULT_TOKEN_POLICY", "hush-recipient, kv-read")
+	if cfg, err = LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if want := []string{"hush-recipient", "kv-read"}; !slices.Equal(cfg.TokenPolicies, want) {
+		t.Errorf("TokenPolicies = %q, want %q", cfg.TokenPolicies, want)
+	}
+}
+
+func TestLoadConfigOverrides(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MAX_TOKEN_TTL", "2h")
+	t.Setenv("MAX_TOKEN_USES", "3")
+	t.Setenv("ALLOW_UNLIMITED_USES", "true")
+	t.Setenv("DEFAULT_TOKEN_TTL", "30m")
+	t.Setenv("DEFAULT_TOKEN_USES", "2")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.MaxTTL != 2*time.Hour || cfg.MaxUses != 3 || !cfg.AllowUnlimitedUses || cfg.DefaultTTL != 30*time.Minute || cfg.DefaultUses != 2 {
+		t.Errorf("overrides not applied: %+v", cfg)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MAX_TOKEN_TTL", "2h")
+	for _, name := range []string{"MAX_TOKEN_USES", "VAULT_TOKEN_POLICY", "ALLOW_UNLIMITED_USES"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	path := filepath.Join(t.TempDir(), "hush.yaml")
+	file := "max_token_ttl: 12h\nMAX_TOKEN_USES: 7\nallow_unlimited_uses: true\nvault_token_policy:\n  - audit\n  - readers\n"
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfigFile(path); err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.MaxTTL != 2*time.Hour {
+		t.Errorf("MaxTTL = %s, want the environment's 2h over the file", cfg.MaxTTL)
+	}
+	if cfg.MaxUses != 7 || !cfg.AllowUnlimitedUses {
+		t.Errorf("MaxUses = %d, AllowUnlimitedUses = %v, want the file's 7 and true", cfg.MaxUses, cfg.AllowUnlimitedUses)
+	}
+	if want := []string{"audit", "readers"}; !slices.Equal(cfg.TokenPolicies, want) {
+		t.Errorf("TokenPolicies = %q, want %q", cfg.TokenPolicies, want)
+	}
+
+	for content, want := range map[string]string{
+		"max-token-ttl: 1h\n":           "not an environment variable name",
+		"max_token_ttl:\n":              "has no value",
+		"vault:\n  addr: x\n":           "not a map",
+		"MAX_TTL: 1h\nmax_ttl: 2h\n":    "more than once",
+		"max_token_uses: [1, [2]]\n":    "list of single values",
+		"max_token_ttl: 1h\n  bad: 2\n": "parsing config file",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadConfigFile(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loadConfigFile(%q) error = %v, want it to mention %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configKeyPattern matches the environment variable names a config file may
+// set, once upper-cased.
+var configKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// loadConfigFile reads the YAML file at path, a map of the environment
+// variables described in the README to their values, and sets those that
+// are not already in the environment. The environment therefore overrides
+// the file, which overrides the defaults, and LoadConfig validates the
+// result as usual. Keys may be written in lower case, and a list is joined
+// with commas for the variables that take several values, such as
+// VAULT_TOKEN_POLICY. Setting the variables, rather than keeping the file's
+// values aside, also passes settings such as OTEL_EXPORTER_OTLP_ENDPOINT on
+// to the libraries that read them from the environment.
+func loadConfigFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	var file map[string]interface{}
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(file))
+	var errs []error
+	for key, v := range file {
+		name := strings.ToUpper(key)
+		if !configKeyPattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("config file key %q is not an environment variable name", key))
+			continue
+		}
+		if _, dup := values[name]; dup {
+			errs = append(errs, fmt.Errorf("config file sets %s more than once", name))
+			continue
+		}
+		value, err := configFileValue(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file key %s %w", key, err))
+			continue
+		}
+		values[name] = value
+	}
+	if len(errs) > 0 {
+		// Map order is random; report in a stable one.
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return errors.Join(errs...)
+	}
+
+	for name, value := range values {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("setting %s from config file: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// configFileValue renders a config file value as its environment variable
+// would be written.
+func configFileValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", errors.New("has no value")
+	case map[string]interface{}:
+		return "", errors.New("must be a single value or a list, not a map")
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := configFileValue(item)
+			if err != nil {
+				return "", err
+			}
+			if _, nested := item.([]interface{}); nested {
+				return "", errors.New("must be a list of single values")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
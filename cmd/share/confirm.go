@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	defaultConfirmLength = 500
+	// pendingShareTTL is how long a share waits for its confirmation.
+	pendingShareTTL = 5 * time.Minute
+
+	confirmShareActionID = "confirm_share"
+	cancelShareActionID  = "cancel_share"
+)
+
+// needsConfirmation reports whether a secret pasted into /share is long or
+// spans several lines, which is often a sign the wrong thing was pasted.
+func needsConfirmation(secret string, cfg *Config) bool {
+	return len(secret) > cfg.ConfirmLength || strings.Contains(secret, "\n")
+}
+
+// pendingShares holds shares awaiting confirmation in the state store, keyed
+// by the sharer and a nonce carried in the value of the confirmation
+// buttons. They are encrypted there, since they include the secret.
+type pendingShares struct {
+	state StateStore
+	key   []byte
+}
+
+// newPendingShares keeps pending shares in state, encrypted with a key
+// derived from signingKey so that every replica given the same
+// LINK_SIGNING_KEY can read them.
+func newPendingShares(state StateStore, signingKey []byte) *pendingShares {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte("hush pending shares"))
+	return &pendingShares{state: state, key: mac.Sum(nil)}
+}
+
+// pendingRecord is a shareRequest as it is kept in the state store.
+type pendingRecord struct {
+	TTL       time.Duration `json:"ttl"`
+	ExpiresAt time.Time     `json:"expires_at,omitempty"`
+	Uses      int           `json:"uses"`
+	Notify    bool          `json:"notify"`
+	Burn      bool          `json:"burn"`
+	Code      bool          `json:"code,omitempty"`
+	To        []string      `json:"to,omitempty"`
+	Groups    []string      `json:"groups,omitempty"`
+	Label     string        `json:"label,omitempty"`
+	Format    string        `json:"format,omitempty"`
+	Email     string        `json:"email,omitempty"`
+	// PassphraseHash is only ever the hash, never the passphrase.
+	PassphraseHash string        `json:"passphrase_hash,omitempty"`
+	Secret         string        `json:"secret"`
+	Fields         []secretField `json:"fields,omitempty"`
+	File           *secretFile   `json:"file,omitempty"`
+	Description    string        `json:"description,omitempty"`
+	InChannel      bool          `json:"in_channel,omitempty"`
+	TeamID         string        `json:"team_id,omitempty"`
+	ChannelID      string        `json:"channel_id,omitempty"`
+	UserID         string        `json:"user_id"`
+	UserName       string        `json:"user_name"`
+	ResponseURL    string        `json:"response_url"`
+	Expires        time.Time     `json:"expires"`
+}
+
+func pendingKey(userID, nonce string) string {
+	return "pending:" + userID + ":" + nonce
+}
+
+// add stores req and returns its nonce.
+func (p *pendingShares) add(req shareRequest, now time.Time) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(b)
+
+	raw, err := json.Marshal(pendingRecord{
+		TTL:            req.ttl,
+		ExpiresAt:      req.expiresAt,
+		Uses:           req.uses,
+		Notify:         req.notify,
+		Burn:           req.burn,
+		Code:           req.code,
+		To:             req.to,
+		Groups:         req.groups,
+		Label:          req.label,
+		Format:         req.format,
+		Email:          req.email,
+		PassphraseHash: req.passphraseHash,
+		Secret:         req.secret,
+		Fields:         req.fields,
+		File:           req.file,
+		Description:    req.description,
+		InChannel:      req.inChannel,
+		TeamID:         req.teamID,
+		ChannelID:      req.channelID,
+		UserID:         req.userID,
+		UserName:       req.userName,
+		ResponseURL:    req.responseURL,
+		Expires:        now.Add(pendingShareTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	sealed, err := encryptSecret(p.key, string(raw))
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	if _, err := p.state.SetNX(ctx, pendingKey(req.userID, nonce), []byte(sealed), pendingShareTTL); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// take removes and returns the share for nonce. It reports false if there
+// is none, it has expired, or it belongs to someone other than userID.
+func (p *pendingShares) take(nonce, userID string, now time.Time) (shareRequest, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	sealed, ok, err := p.state.Take(ctx, pendingKey(userID, nonce))
+	if err != nil {
+		slog.Error("Failed to read pending share", "event", "state", "user_id", userID, "error", err)
+		return shareRequest{}, false
+	}
+	if !ok {
+		return shareRequest{}, false
+	}
+	raw, err := decryptSecret(p.key, string(sealed))
+	if err != nil {
+		slog.Error("Failed to decrypt pending share", "event", "state", "user_id", userID, "error", err)
+		return shareRequest{}, false
+	}
+	var r pendingRecord
+	if err := json.Unmarshal([]byte(raw), &r); err != nil || r.UserID != userID || now.After(r.Expires) {
+		return shareRequest{}, false
+	}
+	return shareRequest{
+		shareArgs: shareArgs{
+			ttl:            r.TTL,
+			expiresAt:      r.ExpiresAt,
+			uses:           r.Uses,
+			notify:         r.Notify,
+			burn:           r.Burn,
+			code:           r.Code,
+			to:             r.To,
+			groups:         r.Groups,
+			label:          r.Label,
+			format:         r.Format,
+			email:          r.Email,
+			passphraseHash: r.PassphraseHash,
+			secret:         r.Secret,
+			fields:         r.Fields,
+		},
+		file:        r.File,
+		description: r.Description,
+		inChannel:   r.InChannel,
+		teamID:      r.TeamID,
+		channelID:   r.ChannelID,
+		userID:      r.UserID,
+		userName:    r.UserName,
+		responseURL: r.ResponseURL,
+	}, true
+}
+
+// confirmShare holds req until the sharer confirms it with a button.
+func (b *bot) confirmShare(req shareRequest) {
+	nonce, err := b.pending.add(req, time.Now())
+	if err != nil {
+		slog.Error("Failed to generate confirmation nonce", "event", "share", "user_id", req.userID, "error", err)
+		sendSlackResponse(b.slack, req.responseURL, "Failed to share the secret. Please try again.")
+		return
+	}
+
+	var what string
+	if lines := strings.Count(req.secret, "\n") + 1; lines > 1 {
+		what = fmt.Sprintf("spans %d lines", lines)
+	} else {
+		what = fmt.Sprintf("is %d characters long", len(req.secret))
+	}
+	text := fmt.Sprintf("The secret you pasted %s. Are you sure you want to share it? This expires in %s.", what, formatDuration(pendingShareTTL))
+
+	share := slack.NewButtonBlockElement(confirmShareActionID, nonce, slack.NewTextBlockObject(slack.PlainTextType, "Share", false, false)).WithStyle(slack.StylePrimary)
+	cancel := slack.NewButtonBlockElement(cancelShareActionID, nonce, slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false))
+	err = postSlack(
+		&b.slack.Client, "confirm", "",
+		slack.MsgOptionResponseURL(req.responseURL, slack.ResponseTypeEphemeral),
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("", share, cancel),
+		),
+	)
+	if err != nil {
+		slog.Error("Failed to send response to Slack", "error", err)
+	}
+}
+
+// handleShareConfirmation shares or discards a pending share when one of
+// its confirmation buttons is clicked.
+func (b *bot) handleShareConfirmation(callback slack.InteractionCallback, action *slack.BlockAction) {
+	req, ok := b.pending.take(action.Value, callback.User.ID, time.Now())
+	if !ok {
+		replaceSlackResponse(b.slack, callback.ResponseURL, "This confirmation has expired. Please run `/share` again.")
+		return
+	}
+	if action.ActionID == cancelShareActionID {
+		replaceSlackResponse(b.slack, callback.ResponseURL, "Cancelled. Your secret was not shared.")
+		return
+	}
+
+	command := "/share"
+	if req.inChannel {
+		command = "/share-channel"
+	}
+	// The command may have been turned off since the share was held.
+	if b.shareDisabled(command, "share", req.userID) {
+		replaceSlackResponse(b.slack, callback.ResponseURL, disabledMessage(command))
+		return
+	}
+
+	replaceSlackResponse(b.slack, callback.ResponseURL, "Sharing your secret...")
+	b.runHandler("share", req.userID, req.responseURL, func(ctx context.Context) { b.shareSecret(ctx, req) })
+}
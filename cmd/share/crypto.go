@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// encryptionAlgorithm is recorded alongside secrets encrypted with
+// ENCRYPTION_KEY so the retrieval handler knows to decrypt them.
+const encryptionAlgorithm = "aes-256-gcm"
+
+// secretCipher encrypts secret values before they are written to Vault.
+type secretCipher interface {
+	// algorithm is recorded alongside the values it encrypts.
+	algorithm() string
+	encrypt(ctx context.Context, plaintext string) (string, error)
+	decrypt(ctx context.Context, ciphertext string) (string, error)
+}
+
+// newSecretCipher returns the cipher cfg configures, using store for transit
+// requests, or nil if secrets are stored as they are.
+func newSecretCipher(cfg *Config, store SecretStore) secretCipher {
+	switch {
+	case cfg.EncryptionKey != nil:
+		return aesKey(cfg.EncryptionKey)
+	case cfg.TransitKey != "":
+		return transitCipher{store: store, mount: cfg.TransitMount, key: cfg.TransitKey}
+	default:
+		return nil
+	}
+}
+
+// aesKey encrypts secrets in the bot with AES-256-GCM.
+type aesKey []byte
+
+func (k aesKey) algorithm() string { return encryptionAlgorithm }
+
+func (k aesKey) encrypt(_ context.Context, plaintext string) (string, error) {
+	return encryptSecret(k, plaintext)
+}
+
+func (k aesKey) decrypt(_ context.Context, ciphertext string) (string, error) {
+	return decryptSecret(k, ciphertext)
+}
+
+// decodeEncryptionKey parses a base64-encoded 32 byte AES-256 key.
+func decodeEncryptionKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("decodes to %d bytes, want 32", len(key))
+	}
+	return key, nil
+}
+
+// encryptSecret seals plaintext with AES-GCM and returns the nonce and
+// ciphertext as a single base64 string.
+func encryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret reverses encryptSecret.
+func decryptSecret(key []byte, encoded string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
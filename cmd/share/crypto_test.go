@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestEncryptDecryptSecret(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	ciphertext, err := encryptSecret(key, "password123")
+	if err != nil {
+		t.Fatalf("encryptSecret() error = %v", err)
+	}
+	if ciphertext == "password123" {
+		t.Fatal("encryptSecret() returned plaintext")
+	}
+
+	plaintext, err := decryptSecret(key, ciphertext)
+	if err != nil {
+		t.Fatalf("decryptSecret() error = %v", err)
+	}
+	if plaintext != "password123" {
+		t.Errorf("decryptSecret() = %q, want %q", plaintext, "password123")
+	}
+
+	if _, err := decryptSecret(bytes.Repeat([]byte{8}, 32), ciphertext); err == nil {
+		t.Error("decryptSecret() with the wrong key succeeded")
+	}
+}
+
+func TestDecodeEncryptionKey(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	if _, err := decodeEncryptionKey(valid); err != nil {
+		t.Errorf("decodeEncryptionKey(valid) error = %v", err)
+	}
+	for _, encoded := range []string{"%%%", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		if _, err := decodeEncryptionKey(encoded); err == nil {
+			t.Errorf("decodeEncryptionKey(%q) succeeded, want error", encoded)
+		}
+	}
+}
+
+func TestEncryptedShareNeverPointsAtVault(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.cfg.EncryptionKey = bytes.Repeat([]byte{7}, 32)
+	share := func(text string) string {
+		t.Helper()
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	if got := share("hunter2"); strings.Contains(got, "127.0.0.1:8200") || !strings.Contains(got, retrievalBaseURL(b.cfg)+"/v1/secrets/secret-") {
+		t.Errorf("reply = %q, want the curl command to go through the retrieval server", got)
+	}
+	if got := share("--format vault hunter2"); strings.Contains(got, "127.0.0.1:8200") || !strings.Contains(got, "VAULT_ADDR="+retrievalBaseURL(b.cfg)+" ") {
+		t.Errorf("reply to --format vault = %q, want the Vault CLI pointed at the retrieval server", got)
+	}
+
+	tmpl, err := loadShareTemplate("Read {{.SecretID}} from http://127.0.0.1:8200/v1/secrets/data/shared/{{.SecretID}} with {{.Token}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.cfg.ShareTemplate = tmpl
+	if got := share("hunter2"); strings.Contains(got, "127.0.0.1:8200") || strings.Contains(got, "hvs.") || !strings.Contains(got, "/s/secret-") {
+		t.Errorf("reply from a template pointing at Vault = %q, want only the link", got)
+	}
+}
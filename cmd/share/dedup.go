@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// commandDedupWindow is how long a slash command is remembered so that a
+// redelivery of it is recognised. Slack redelivers within seconds.
+const commandDedupWindow = 5 * time.Minute
+
+// dedupCache remembers keys for a while so that repeated deliveries of the
+// same request can be told apart from new ones.
+type dedupCache struct {
+	state StateStore
+	ttl   time.Duration
+}
+
+func newDedupCache(state StateStore, ttl time.Duration) *dedupCache {
+	return &dedupCache{state: state, ttl: ttl}
+}
+
+// First records key and reports whether it had not been seen within the
+// cache's TTL. If the state store cannot be reached the key is treated as
+// new, since handling a redelivery twice is better than dropping a command.
+func (c *dedupCache) First(key string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	first, err := c.state.SetNX(ctx, "dedup:"+key, nil, c.ttl)
+	if err != nil {
+		slog.Error("Failed to record command delivery", "event", "state", "error", err)
+		return true
+	}
+	return first
+}
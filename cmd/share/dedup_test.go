@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestDedupCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	state := newMemoryState()
+	state.now = func() time.Time { return now }
+	c := newDedupCache(state, time.Minute)
+
+	if !c.First("trigger-1") {
+		t.Error("First() on a new key = false")
+	}
+	if c.First("trigger-1") {
+		t.Error("First() on a repeated key = true")
+	}
+	if !c.First("trigger-2") {
+		t.Error("First() on another key = false")
+	}
+
+	now = now.Add(time.Minute)
+	if !c.First("trigger-1") {
+		t.Error("First() after the TTL = false")
+	}
+}
+
+func TestDispatchCommandRedelivery(t *testing.T) {
+	store := newFakeSecretStore()
+	b, responseURL, replies := newTestBot(t, store, &fakeTokenCreator{})
+
+	cmd := slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL, TriggerID: "trigger-1"}
+	b.dispatchCommand(cmd)
+	b.inflight.Wait()
+	b.dispatchCommand(cmd)
+	b.inflight.Wait()
+
+	if ids, _ := listSecretIDs(context.Background(), store, b.cfg.kvPaths("")); len(ids) != 1 {
+		t.Errorf("secrets stored = %q, want one", ids)
+	}
+	if got := replies(); len(got) != 1 {
+		t.Errorf("replies = %q, want one", got)
+	}
+}
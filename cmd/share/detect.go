@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultDetectedMaxTTL is the longest a detected credential may be shared
+// for unless DETECTED_CREDENTIAL_MAX_TTL says otherwise.
+const defaultDetectedMaxTTL = 15 * time.Minute
+
+// credentialDetector recognises a kind of credential risky enough that it is
+// only shared with --burn and a short TTL.
+type credentialDetector struct {
+	// name is how CREDENTIAL_DETECTORS enables it.
+	name string
+	// kind describes the credential in the sharer's warning.
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// credentialDetectors are the detectors that CREDENTIAL_DETECTORS can enable.
+// New ones only need adding here.
+var credentialDetectors = []credentialDetector{
+	{"aws-access-key", "an AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private-key", "a private key", regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)},
+	{"slack-token", "a Slack token", regexp.MustCompile(`\bxox[abposre]-[0-9A-Za-z-]{10,}`)},
+}
+
+// credentialDetectorsEnv returns the detectors named, comma-separated, by
+// CREDENTIAL_DETECTORS, or all of them for "all". None are enabled by
+// default.
+func credentialDetectorsEnv(errs *[]error) []credentialDetector {
+	v := strings.TrimSpace(os.Getenv("CREDENTIAL_DETECTORS"))
+	if v == "" {
+		return nil
+	}
+	if v == "all" {
+		return credentialDetectors
+	}
+	var enabled []credentialDetector
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(credentialDetectors, func(d credentialDetector) bool { return d.name == name })
+		if i < 0 {
+			*errs = append(*errs, fmt.Errorf("CREDENTIAL_DETECTORS %q is not one of %s or all", name, detectorNames()))
+			continue
+		}
+		enabled = append(enabled, credentialDetectors[i])
+	}
+	return enabled
+}
+
+func detectorNames() string {
+	names := make([]string, len(credentialDetectors))
+	for i, d := range credentialDetectors {
+		names[i] = d.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// detectCredential returns the first of detectors that matches the secret,
+// fields or file in req.
+func detectCredential(detectors []credentialDetector, req shareRequest) (credentialDetector, bool) {
+	for _, d := range detectors {
+		if d.pattern.MatchString(req.secret) || (req.file != nil && d.pattern.Match(req.file.Content)) {
+			return d, true
+		}
+		for _, f := range req.fields {
+			if d.pattern.MatchString(f.Value) {
+				return d, true
+			}
+		}
+	}
+	return credentialDetector{}, false
+}
+
+// restrictDetectedCredential shares a secret that one of the configured
+// detectors recognises with --burn and at most DetectedMaxTTL, and sets the
+// warning the sharer is shown.
+func (b *bot) restrictDetectedCredential(req *shareRequest) {
+	d, ok := detectCredential(b.cfg.CredentialDetectors, *req)
+	if !ok {
+		return
+	}
+	req.burn = true
+	req.uses = 1
+	req.ttl = min(req.ttl, b.cfg.DetectedMaxTTL)
+	req.warning = fmt.Sprintf("This looks like %s, so it is valid for at most %s and can only be viewed once. Consider rotating it once it has been retrieved.", d.kind, formatDuration(b.cfg.DetectedMaxTTL))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// wantsDownload reports whether r asked, with ?download=1, for a text
+// secret to be served as a file, such as a kubeconfig the recipient wants
+// to save rather than copy.
+func wantsDownload(r *http.Request) bool {
+	download, _ := strconv.ParseBool(r.URL.Query().Get("download"))
+	return download
+}
+
+// downloadFile returns secret as a file to download, named after label. A
+// file secret is returned as it was shared, and fields as name=value lines.
+func downloadFile(secret secretPayload, label string) *secretFile {
+	if secret.File != nil {
+		return secret.File
+	}
+	if len(secret.Fields) > 0 {
+		var sb strings.Builder
+		for _, f := range secret.Fields {
+			sb.WriteString(f.Name + "=" + f.Value + "\n")
+		}
+		return &secretFile{Name: downloadName(label, ".env"), ContentType: "text/plain; charset=utf-8", Content: []byte(sb.String())}
+	}
+	return &secretFile{Name: downloadName(label, ".txt"), ContentType: "text/plain; charset=utf-8", Content: []byte(secret.Text)}
+}
+
+// downloadName turns label into a file name, keeping letters, digits, dots,
+// dashes and underscores and joining the rest with dashes. Without a label
+// it is "secret" with ext.
+func downloadName(label, ext string) string {
+	words := strings.Fields(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return ' '
+	}, label))
+	if name := strings.Trim(strings.Join(words, "-"), ".-"); name != "" {
+		return name
+	}
+	return "secret" + ext
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestDownloadTextSecret(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, store, tokens)
+	paths := b.cfg.kvPaths("")
+	rs := &retrievalServer{secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	download := func(text string) (string, *httptest.ResponseRecorder) {
+		t.Helper()
+		tokens.created = nil
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", ResponseURL: responseURL})
+		id := tokens.created[0].Metadata["secret_id"]
+		req := httptest.NewRequest(http.MethodPost, "/s/"+id+"?download=1", nil)
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return id, rec
+	}
+
+	id, rec := download(`--uses 2 --no-notify --label "prod kubeconfig" apiVersion: v1`)
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=prod-kubeconfig` {
+		t.Errorf("Content-Disposition = %q, want a file named after the label", got)
+	}
+	if rec.Body.String() != "apiVersion: v1" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("download = %q, headers %v, want the bare secret", rec.Body.String(), rec.Header())
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, id); err != nil || meta.UsesRemaining != 1 {
+		t.Errorf("metadata = %+v, %v, want the download to spend a use", meta, err)
+	}
+
+	id, rec = download("--burn --no-notify hunter2")
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=secret.txt` || rec.Body.String() != "hunter2" {
+		t.Errorf("download = %q as %q, want secret.txt", rec.Body.String(), got)
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+		t.Errorf("burned secret metadata error = %v, want it deleted", err)
+	}
+}
+
+func TestDownloadName(t *testing.T) {
+	tests := []struct{ label, want string }{
+		{"", "secret.txt"},
+		{"kubeconfig", "kubeconfig"},
+		{"staging db / admin", "staging-db-admin"},
+		{"prod.env", "prod.env"},
+		{"../../etc/passwd", "etc-passwd"},
+		{"🔑", "secret.txt"},
+	}
+	for _, tt := range tests {
+		if got := downloadName(tt.label, ".txt"); got != tt.want {
+			t.Errorf("downloadName(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
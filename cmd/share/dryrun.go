@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// dryRunShares numbers the secrets shared in dry-run mode.
+var dryRunShares atomic.Int64
+
+// dryRunCredentials returns a fake secret ID and token for DRY_RUN. They are
+// numbered from one so that test runs produce the same values every time.
+func dryRunCredentials() (secretID, token string) {
+	n := dryRunShares.Add(1)
+	return fmt.Sprintf("secret-dry-run-%d", n), fmt.Sprintf("hvs.dry-run-%d", n)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const defaultEmailRateLimit = 10
+
+// Mailer sends a plain text email.
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// newMailer returns a Mailer for the SMTP relay in cfg, or nil if none is
+// configured.
+func newMailer(cfg *Config) Mailer {
+	if cfg.SMTPAddr == "" {
+		return nil
+	}
+	return &smtpMailer{addr: cfg.SMTPAddr, from: cfg.SMTPFrom, username: cfg.SMTPUsername, password: cfg.SMTPPassword}
+}
+
+// smtpMailer sends mail through an SMTP relay. The connection is upgraded
+// with STARTTLS whenever the relay offers it, and a configured username is
+// only ever sent over TLS or to localhost.
+type smtpMailer struct {
+	addr     string
+	from     string
+	username string
+	password string
+}
+
+func (m *smtpMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	host, _, err := net.SplitHostPort(m.addr)
+	if err != nil {
+		return err
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+	if m.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.username, m.password, host)); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(emailMessage(m.from, to, subject, body, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// emailMessage formats a plain text message with CRLF line endings. The
+// addresses have been checked by parseEmail, so they cannot carry headers.
+func emailMessage(from, to, subject, body string, now time.Time) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", from)
+	fmt.Fprintf(&sb, "To: %s\r\n", to)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&sb, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(sb.String())
+}
+
+// parseEmail checks a user-supplied --email address, which must be a bare
+// address such as someone@example.com. Slack's mailto: formatting is
+// removed first.
+func parseEmail(value string) (string, error) {
+	if inner, ok := strings.CutPrefix(value, "<mailto:"); ok {
+		inner, _ = strings.CutSuffix(inner, ">")
+		value, _, _ = strings.Cut(inner, "|")
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@")+1:], ".") {
+		return "", fmt.Errorf("Invalid email address %q. Use a plain address such as `someone@example.com`.", value)
+	}
+	return value, nil
+}
+
+// sendEmailLink emails the retrieval link of a secret shared with --email to
+// its recipient and tells the sharer. The Vault token is left out, so the
+// link, with its TTL and single use, is the only way in. If the email cannot
+// be sent the secret is destroyed rather than left unreachable, and false is
+// returned.
+func (b *bot) sendEmailLink(ctx context.Context, req shareRequest, secretID string, meta secretMetadata, pageURL, what string) bool {
+	sharer := req.userName
+	if sharer == "" {
+		sharer = "Someone"
+	}
+	body := fmt.Sprintf("%s shared a secret with you through Slack. It is valid for %s and can be retrieved %s. Open this link to view it:\n\n%s\n\nThe link stops working once it has been used or has expired. If you were not expecting this email, you can ignore it.\n", sharer, formatDuration(req.ttl), formatUses(req.uses), pageURL)
+
+	var err error
+	if b.cfg.DryRun {
+		slog.Info("Dry run: skipped emailing retrieval link", "event", "share", "secret_id", secretID, "user_id", req.userID)
+	} else if b.mailer == nil {
+		err = errors.New("no SMTP relay is configured")
+	} else {
+		err = b.mailer.SendMail(ctx, req.email, "A secret has been shared with you", body)
+	}
+	if err != nil {
+		slog.Error("Failed to email retrieval link", "event", "share", "secret_id", secretID, "user_id", req.userID, "error", err)
+		emailsTotal.WithLabelValues(outcomeError).Inc()
+		vaultCtx, cancel := vaultContext(context.WithoutCancel(ctx), b.cfg)
+		defer cancel()
+		if err := b.destroySecret(vaultCtx, req.teamID, secretID, meta); err != nil {
+			slog.Error("Failed to revoke secret after its email failed", "event", "share", "secret_id", secretID, "user_id", req.userID, "error", err)
+			sendSlackResponse(b.slack, req.responseURL, fmt.Sprintf("Your secret was stored but the email to %s could not be sent. Run `/revoke %s` and try again.", req.email, secretID))
+			return false
+		}
+		sendSlackResponse(b.slack, req.responseURL, fmt.Sprintf("The email to %s could not be sent, so the secret was destroyed. Please try again.", req.email))
+		return false
+	}
+	emailsTotal.WithLabelValues(outcomeSuccess).Inc()
+
+	response := fmt.Sprintf("%s been securely shared with %s by email, is valid for %s and can be retrieved %s. The email only carries the retrieval link.\nTo destroy it early, run `/revoke %s`.", what, req.email, formatDuration(req.ttl), formatUses(req.uses), secretID)
+	if !req.expiresAt.IsZero() {
+		response += "\n" + expiryNote(req.expiresAt)
+	}
+	if req.burn {
+		response += "\n" + burnNote
+	}
+	if req.passphraseHash != "" {
+		response += "\n" + passphraseNote
+	}
+	if req.warning != "" {
+		response += "\n" + req.warning
+	}
+	sendSlackResponse(b.slack, req.responseURL, response)
+	return true
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeMailer records the emails it is asked to send and fails with err.
+type fakeMailer struct {
+	mu   sync.Mutex
+	err  error
+	sent []string
+}
+
+func (m *fakeMailer) SendMail(_ context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, to+"\n"+subject+"\n"+body)
+	return nil
+}
+
+func TestParseEmail(t *testing.T) {
+	for _, value := range []string{"someone@example.com", "<mailto:someone@example.com|someone@example.com>"} {
+		if got, err := parseEmail(value); err != nil || got != "someone@example.com" {
+			t.Errorf("parseEmail(%q) = %q, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"someone", "someone@localhost", "Someone <someone@example.com>", "someone@example.com\r\nBcc: other@example.com", "a@example.com,b@example.com"} {
+		if _, err := parseEmail(value); err == nil {
+			t.Errorf("parseEmail(%q) succeeded, want error", value)
+		}
+	}
+}
+
+func TestShareByEmail(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	mailer := &fakeMailer{}
+	b.mailer = mailer
+	share := func(text string) string {
+		t.Helper()
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", UserName: "alice", ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	if got := share("--email someone@example.com hunter2"); !strings.Contains(got, "not set up") {
+		t.Errorf("reply without SMTP = %q, want email refused", got)
+	}
+	b.cfg.SMTPAddr = "smtp.example.com:587"
+	b.cfg.EmailRateLimit = 1
+	b.emailLimiter = newRateLimiter(1, time.Hour)
+
+	got := share("--email someone@example.com hunter2")
+	if !strings.Contains(got, "securely shared with someone@example.com by email") || strings.Contains(got, "hvs.") {
+		t.Errorf("reply = %q, want the email confirmed without the token", got)
+	}
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent = %q, want one email", mailer.sent)
+	}
+	if mail := mailer.sent[0]; !strings.HasPrefix(mail, "someone@example.com\n") || !strings.Contains(mail, "alice shared a secret") || !strings.Contains(mail, "/s/secret-") || strings.Contains(mail, "hunter2") || strings.Contains(mail, "hvs.") {
+		t.Errorf("email = %q, want only the link", mail)
+	}
+
+	if got := share("--email someone@example.com hunter2"); !strings.Contains(got, "at most 1 secret by email an hour") {
+		t.Errorf("reply over the rate limit = %q, want it refused", got)
+	}
+	if got := share("--email someone@example.com --uses 2 hunter2"); !strings.Contains(got, "only be retrieved once") {
+		t.Errorf("reply to --uses 2 = %q, want it refused", got)
+	}
+
+	b.emailLimiter = newRateLimiter(1, time.Hour)
+	mailer.err = errors.New("relay unavailable")
+	if got := share("--email someone@example.com hunter2"); !strings.Contains(got, "could not be sent, so the secret was destroyed") {
+		t.Errorf("reply when the email fails = %q", got)
+	}
+	id := tokens.created[len(tokens.created)-1].Metadata["secret_id"]
+	if _, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), id); !errors.Is(err, errSecretNotFound) {
+		t.Errorf("secret after a failed email = %v, want it destroyed", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	msg := string(emailMessage("hush@example.com", "someone@example.com", "A secret has been shared with you", "Open this link:\nhttps://hush/s/secret-1\n", time.Unix(0, 0).UTC()))
+	want := "From: hush@example.com\r\nTo: someone@example.com\r\nSubject: A secret has been shared with you\r\nDate: Thu, 01 Jan 1970 00:00:00 +0000\r\n"
+	if !strings.HasPrefix(msg, want) || !strings.HasSuffix(msg, "\r\n\r\nOpen this link:\r\nhttps://hush/s/secret-1\r\n") {
+		t.Errorf("emailMessage() = %q", msg)
+	}
+}
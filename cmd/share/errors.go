@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Categories of command failure. A handler wraps the error behind a failure
+// with storeFailure, tokenFailure or invalid, and reportFailure turns it into
+// the reply and the log line, so that every handler words a failure of the
+// same kind the same way.
+var (
+	// errVaultStore is reading, writing or deleting a secret, its metadata
+	// or the sharer's index in Vault failing.
+	errVaultStore = errors.New("vault store request failed")
+	// errTokenCreate is issuing the short-lived token for a secret, or the
+	// policy it is bound to, failing.
+	errTokenCreate = errors.New("token creation failed")
+	// errValidation is the command being given something it cannot act on.
+	// The reply is the underlying error's text.
+	errValidation = errors.New("invalid command")
+)
+
+// commandError is a failed command: the category it falls in, what it was
+// doing when it failed, for the reply, and the underlying error.
+type commandError struct {
+	kind   error
+	action string
+	err    error
+}
+
+func (e *commandError) Error() string { return e.kind.Error() + ": " + e.err.Error() }
+
+func (e *commandError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// storeFailure records that a Vault request for the secret failed while the
+// command was trying to do action, which completes "Failed to ...", e.g.
+// "store the secret".
+func storeFailure(action string, err error) error {
+	return &commandError{kind: errVaultStore, action: action, err: err}
+}
+
+// tokenFailure records that the secret's token could not be issued.
+func tokenFailure(err error) error {
+	return &commandError{kind: errTokenCreate, err: err}
+}
+
+// invalid records that the command's input was rejected with err, whose
+// text tells the user what to fix.
+func invalid(err error) error {
+	return &commandError{kind: errValidation, err: err}
+}
+
+// failureKind names the category of err for logs and tests.
+func failureKind(err error) string {
+	switch {
+	case errors.Is(err, errValidation):
+		return "validation"
+	case errors.Is(err, errTokenCreate):
+		return "token_create"
+	case errors.Is(err, errVaultStore):
+		return "vault_store"
+	}
+	return "internal"
+}
+
+// failureMessage returns the reply to a command that failed with err. A
+// sealed, refusing, slow or unavailable Vault is explained as such whatever
+// the command was doing, since that is what the user can act on.
+func failureMessage(err error) string {
+	var cmdErr *commandError
+	if !errors.As(err, &cmdErr) {
+		return "Something went wrong. Please try again."
+	}
+	switch cmdErr.kind {
+	case errValidation:
+		return cmdErr.err.Error()
+	case errTokenCreate:
+		return vaultFailure(cmdErr.err, "Failed to create a secure access token. Please try again.")
+	}
+	return vaultFailure(cmdErr.err, fmt.Sprintf("Failed to %s. Please try again.", cmdErr.action))
+}
+
+// logFailure logs err under msg with args and the category of the failure.
+// Rejected input is the user's to fix, so it is only logged at debug level.
+func logFailure(msg string, err error, args ...any) {
+	args = append(args, "error_kind", failureKind(err), "error", err)
+	if errors.Is(err, errValidation) {
+		slog.Debug(msg, args...)
+		return
+	}
+	slog.Error(msg, args...)
+}
+
+// reportFailure logs err under msg with args and tells the user at
+// responseURL what went wrong.
+func (b *bot) reportFailure(responseURL, msg string, err error, args ...any) {
+	logFailure(msg, err, args...)
+	sendSlackResponse(b.slack, responseURL, failureMessage(err))
+}
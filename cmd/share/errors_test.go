@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestFailureMessage(t *testing.T) {
+	vaultErr := errors.New("connection refused")
+	sealed := &api.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"Vault is sealed"}}
+	tests := []struct {
+		name     string
+		err      error
+		wantKind string
+		want     string
+	}{
+		{"store", storeFailure("list your secrets", vaultErr), "vault_store", "Failed to list your secrets. Please try again."},
+		{"token", tokenFailure(vaultErr), "token_create", "Failed to create a secure access token. Please try again."},
+		{"validation", invalid(errors.New("Invalid TTL.")), "validation", "Invalid TTL."},
+		{"sealed store", storeFailure("store the secret", sealed), "vault_store", vaultSealedMessage},
+		{"token timeout", tokenFailure(fmt.Errorf("create token: %w", context.DeadlineExceeded)), "token_create", vaultTimeoutMessage},
+		{"breaker open", storeFailure("revoke the secret", errVaultUnavailable), "vault_store", vaultUnavailableMessage},
+		{"uncategorized", vaultErr, "internal", "Something went wrong. Please try again."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureKind(tt.err); got != tt.wantKind {
+				t.Errorf("failureKind() = %q, want %q", got, tt.wantKind)
+			}
+			if got := failureMessage(tt.err); got != tt.want {
+				t.Errorf("failureMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// The underlying error stays reachable for callers that branch on it.
+	if err := storeFailure("store the secret", sealed); !vaultSealed(err) || !errors.Is(err, errVaultStore) {
+		t.Errorf("storeFailure() = %v, want both the kind and the Vault error", err)
+	}
+}
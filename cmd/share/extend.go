@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const extendUsage = "Usage: `/extend <secretID> <duration>`"
+
+// handleExtendCommand gives a shared secret a new token valid for the given
+// duration from now, revoking the old one, so that a recipient who missed
+// the original expiry can still retrieve it. Only the user who shared the
+// secret may extend it.
+func (b *bot) handleExtendCommand(ctx context.Context, cmd slack.SlashCommand) {
+	secretID, value, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	value = strings.TrimSpace(value)
+	if secretID == "" || value == "" {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Please provide the ID of the secret to extend and how long it should be valid for. "+extendUsage)
+		return
+	}
+	if !validSecretID(secretID) {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found.", secretID))
+		return
+	}
+	ttl, err := parseTTL(value, b.cfg)
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Rejected extend command", invalid(err), "event", "extend", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	paths := b.cfg.kvPaths(cmd.TeamID)
+	meta, err := readSecretMetadata(ctx, b.secrets, paths, secretID)
+	if errors.Is(err, errSecretNotFound) {
+		extensionsTotal.WithLabelValues(outcomeNotFound).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found. It may have already been retrieved, revoked or swept away. Share it again instead.", secretID))
+		return
+	}
+	if err != nil {
+		extensionsTotal.WithLabelValues(outcomeError).Inc()
+		b.reportFailure(cmd.ResponseURL, "Failed to read secret metadata from Vault", storeFailure("extend the secret", err), "event", "extend", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+	if meta.SharedBy != cmd.UserID {
+		extensionsTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Only the person who shared this secret can extend it.")
+		return
+	}
+
+	// The sweep deletes any secret older than SecretMaxAge, so it cannot
+	// be extended past that.
+	now := time.Now()
+	expires := now.Add(ttl)
+	if b.cfg.SecretMaxAge > 0 && !meta.CreatedAt.IsZero() && expires.After(meta.CreatedAt.Add(b.cfg.SecretMaxAge)) {
+		extensionsTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Secrets are kept for at most %s after they are shared, so this one can be extended until %s at the latest. Share it again instead.", formatDuration(b.cfg.SecretMaxAge), meta.CreatedAt.Add(b.cfg.SecretMaxAge).UTC().Format(time.RFC1123)))
+		return
+	}
+
+	uses := meta.UsesRemaining
+	if meta.Burn {
+		uses = 1
+	}
+	token, accessor, err := createVaultToken(ctx, b.tokens, b.cfg, append([]string{paths.policyName(secretID)}, b.cfg.TokenPolicies...), secretID, meta.SharedBy, meta.SharedByName, ttl, uses)
+	if err != nil {
+		extensionsTotal.WithLabelValues(outcomeError).Inc()
+		b.reportFailure(cmd.ResponseURL, "Failed to create short-lived token", tokenFailure(err), "event", "extend", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+
+	// Tracked before it is recorded, so that the reconciler revokes it if
+	// it cannot be revoked here.
+	b.issued.add(accessor, cmd.TeamID, secretID, ttl)
+	// Only the token and expiry change, so that a retrieval since the read
+	// above is not undone.
+	var oldAccessor string
+	err = updateSecretMetadata(ctx, b.state, b.secrets, paths, secretID, func(m *secretMetadata) error {
+		oldAccessor = m.TokenAccessor
+		m.TokenAccessor = accessor
+		m.ExpiresAt = expires
+		return nil
+	})
+	if err != nil {
+		message := fmt.Sprintf("Secret `%s` was retrieved, revoked or swept away before it could be extended.", secretID)
+		outcome := outcomeNotFound
+		if !errors.Is(err, errSecretNotFound) {
+			err = storeFailure("extend the secret", err)
+			logFailure("Failed to store secret metadata in Vault", err, "event", "extend", "secret_id", secretID, "user_id", cmd.UserID)
+			message, outcome = failureMessage(err), outcomeError
+		}
+		if err := revokeTokenAccessor(ctx, b.tokens, accessor); err != nil {
+			slog.Error("Failed to revoke unused token", "event", "extend", "secret_id", secretID, "error", err)
+		} else {
+			b.issued.remove(accessor)
+		}
+		extensionsTotal.WithLabelValues(outcome).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, message)
+		return
+	}
+
+	var note string
+	if oldAccessor != "" {
+		if err := revokeTokenAccessor(ctx, b.tokens, oldAccessor); err != nil {
+			slog.Error("Failed to revoke previous token", "event", "extend", "secret_id", secretID, "user_id", cmd.UserID, "error", err)
+			note = "\nThe previous token could not be revoked, so it also works until it expires."
+		} else {
+			b.issued.remove(oldAccessor)
+		}
+	}
+
+	extensionsTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret extended", "event", "extend", "secret_id", secretID, "user_id", cmd.UserID, "ttl", ttl)
+	audit(b.audit, AuditEvent{
+		Action:    auditExtend,
+		SecretID:  secretID,
+		SharedBy:  meta.SharedBy,
+		Actor:     cmd.UserID,
+		TTL:       ttl.String(),
+		ExpiresAt: expires.UTC().Format(time.RFC3339),
+	})
+
+	pageURL := secretPageURL(b.cfg, cmd.TeamID, secretID)
+	response := fmt.Sprintf("Secret `%s` is now valid for %s. ", secretID, formatDuration(ttl))
+	switch {
+	case len(meta.AllowedUsers) > 0:
+		// The token would bypass the --to restriction, so it is not shown.
+		response += fmt.Sprintf("The personal links already sent to %s keep working.", formatMentions(meta.AllowedUsers))
+	case meta.PassphraseHash != "":
+		// Nor would it ask for the passphrase.
+		response += fmt.Sprintf("The link still works, and still asks for the passphrase:\n%s", pageURL)
+	default:
+		response += fmt.Sprintf("The link still works:\n%s\n\nThe old token has been replaced. From a terminal: \n```curl --header \"X-Vault-Token: %s\" --request GET %s```", pageURL, token, b.secretAPIURL(cmd.TeamID, secretID))
+	}
+	sendSlackResponse(b.slack, cmd.ResponseURL, response+note)
+}
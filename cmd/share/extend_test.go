@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+)
+
+func TestExtendCommand(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.SecretMaxAge = b.cfg.MaxTTL
+	paths := b.cfg.kvPaths("")
+
+	created := time.Now().Add(-2 * time.Hour)
+	meta := secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-old", CreatedAt: created, ExpiresAt: created.Add(time.Hour), UsesRemaining: 2}
+	if err := writeSecretMetadata(context.Background(), store, paths, "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: "secret-1 3h", UserID: "U2", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "Only the person who shared") {
+		t.Fatalf("replies to another user = %q, want a denial", got)
+	}
+
+	b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: "secret-1 3h", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if len(got) != 2 || !strings.Contains(got[1], "is now valid for 3h") || !strings.Contains(got[1], "hvs.recipient") {
+		t.Fatalf("reply = %q, want the new expiry and token", got[len(got)-1])
+	}
+	if len(tokens.created) != 1 || tokens.created[0].TTL != "3h0m0s" || tokens.created[0].NumUses != 2 {
+		t.Errorf("token created = %+v, want 3h and the remaining 2 uses", tokens.created)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-old"}) {
+		t.Errorf("revoked = %q, want the old token", tokens.revoked)
+	}
+	updated, err := readSecretMetadata(context.Background(), store, paths, "secret-1")
+	if err != nil || updated.TokenAccessor != "accessor-1" || time.Until(updated.ExpiresAt) < 2*time.Hour {
+		t.Errorf("metadata = %+v, %v, want the new accessor and expiry", updated, err)
+	}
+}
+
+func TestExtendPassphraseSecret(t *testing.T) {
+	store := newFakeSecretStore()
+	b, responseURL, replies := newTestBot(t, store, &fakeTokenCreator{})
+	meta := secretMetadata{SharedBy: "U1", ExpiresAt: time.Now().Add(time.Hour), UsesRemaining: 1, PassphraseHash: "$2a$10$hash"}
+	if err := writeSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: "secret-1 3h", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if len(got) != 1 || !strings.Contains(got[0], "is now valid for 3h") || !strings.Contains(got[0], secretPageURL(b.cfg, "", "secret-1")) {
+		t.Fatalf("reply = %q, want the new expiry and the link", got)
+	}
+	if strings.Contains(got[0], "hvs.recipient") || strings.Contains(got[0], "curl") {
+		t.Errorf("reply = %q, want no token that would get around the passphrase", got[0])
+	}
+}
+
+func TestExtendCommandLimits(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.SecretMaxAge = b.cfg.MaxTTL
+	created := time.Now().Add(-20 * time.Hour)
+	meta := secretMetadata{SharedBy: "U1", CreatedAt: created, ExpiresAt: created.Add(time.Hour)}
+	if err := writeSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	for text, want := range map[string]string{
+		"secret-1":     "Usage",
+		"secret-1 48h": "exceeds the maximum",
+		"secret-1 8h":  "extended until",
+		"secret-2 1h":  "No secret with ID `secret-2`",
+	} {
+		b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: text, UserID: "U1", ResponseURL: responseURL})
+		if got := replies(); !strings.Contains(got[len(got)-1], want) {
+			t.Errorf("reply to /extend %s = %q, want it to mention %q", text, got[len(got)-1], want)
+		}
+	}
+	if len(tokens.created) != 0 {
+		t.Errorf("tokens created = %d, want none", len(tokens.created))
+	}
+}
+
+// staleReadStore calls during once, just after the first read of a
+// secret's metadata, as if it happened between that read and whatever the
+// caller does next.
+type staleReadStore struct {
+	*fakeSecretStore
+	during func()
+}
+
+func (s *staleReadStore) ReadWithContext(ctx context.Context, path string) (*api.Secret, error) {
+	resp, err := s.fakeSecretStore.ReadWithContext(ctx, path)
+	if during := s.during; during != nil && strings.Contains(path, "/metadata/") {
+		s.during = nil
+		during()
+	}
+	return resp, err
+}
+
+// newRacedSecret stores secret-1, with two uses, for U1 in a bot whose first
+// read of its metadata is overtaken by a reveal on the retrieval page.
+func newRacedSecret(t *testing.T) (*bot, *fakeSecretStore, string) {
+	t.Helper()
+	fake := newFakeSecretStore()
+	store := &staleReadStore{fakeSecretStore: fake}
+	b, responseURL, _ := newTestBot(t, store, &fakeTokenCreator{})
+	paths := b.cfg.kvPaths("")
+	if err := storeSecret(context.Background(), fake, paths, "secret-1", secretPayload{Text: "hunter2"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	meta := secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-old", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), UsesRemaining: 2}
+	if err := writeSecretMetadata(context.Background(), fake, paths, "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := &retrievalServer{secrets: fake, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	store.during = func() {
+		req := httptest.NewRequest(http.MethodPost, "/s/secret-1", nil)
+		req.SetPathValue("secretID", "secret-1")
+		rs.handlePage(httptest.NewRecorder(), req)
+	}
+	return b, fake, responseURL
+}
+
+func TestExtendKeepsConcurrentRetrieval(t *testing.T) {
+	b, store, responseURL := newRacedSecret(t)
+	b.cfg.SecretMaxAge = b.cfg.MaxTTL
+
+	b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: "secret-1 2h", UserID: "U1", ResponseURL: responseURL})
+	updated, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), "secret-1")
+	if err != nil || updated.UsesRemaining != 1 || updated.TokenAccessor != "accessor-1" {
+		t.Errorf("metadata = %+v, %v, want the new token with the use spent meanwhile kept", updated, err)
+	}
+}
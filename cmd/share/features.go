@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// FeatureFlags switches slash commands on and off for a deployment, such as
+// turning off /generate where passwords must come from a vault of record.
+// Every command is enabled unless it is listed.
+type FeatureFlags struct {
+	// Disabled are the commands, with their leading slash, that reply that
+	// they are disabled instead of running.
+	Disabled []string
+}
+
+// Enabled reports whether command, with its leading slash, may run.
+func (f FeatureFlags) Enabled(command string) bool {
+	return !slices.Contains(f.Disabled, command)
+}
+
+// parseDisabledCommands parses DISABLED_COMMANDS, a comma-separated list of
+// command names with or without their leading slash, each of which must be
+// one of known.
+func parseDisabledCommands(value string, known []string) ([]string, error) {
+	var disabled []string
+	for _, c := range strings.Split(value, ",") {
+		if c = strings.TrimSpace(c); c == "" {
+			continue
+		}
+		if !strings.HasPrefix(c, "/") {
+			c = "/" + c
+		}
+		if !slices.Contains(known, c) {
+			return nil, fmt.Errorf("DISABLED_COMMANDS %q must be one of %s", c, strings.Join(known, ", "))
+		}
+		disabled = append(disabled, c)
+	}
+	return disabled, nil
+}
+
+func (b *bot) handleDisabledCommand(ctx context.Context, cmd slack.SlashCommand) {
+	slog.Warn("Disabled command used", "event", "command", "command", sanitizedCommand(cmd))
+	sendSlackResponse(b.slack, cmd.ResponseURL, disabledMessage(cmd.Command))
+}
+
+func disabledMessage(command string) string {
+	return fmt.Sprintf("This command is disabled. Please ask an admin if you need `%s`.", command)
+}
+
+// shareDisabled reports whether command is turned off for a share made
+// without it, through the form, the message shortcut or a confirmation
+// button, each of which stands in for a slash command.
+func (b *bot) shareDisabled(command, event, userID string) bool {
+	if b.cfg.FeatureFlags.Enabled(command) {
+		return false
+	}
+	slog.Warn("Disabled command used", "event", event, "command", command, "user_id", userID)
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// fieldsField lists, comma-separated and in order, the names of the fields
+// of a secret shared as name=value pairs.
+const fieldsField = "fields"
+
+// secretField is one name=value pair of a structured secret, such as the
+// username of a set of database credentials.
+type secretField struct {
+	Name  string
+	Value string
+}
+
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]{0,63}$`)
+
+// reservedFieldNames are stored alongside a secret's values, so no field may
+// take their names.
+var reservedFieldNames = []string{"secret", fieldsField, "filename", "content_type", "encryption"}
+
+// parseSecretFields splits text into name=value pairs separated by
+// whitespace, such as "username=app password=hunter2 host=db1". It returns
+// nil if any part of text is not such a pair, in which case text is shared
+// as a single secret; passwords often contain = themselves.
+func parseSecretFields(text string) ([]secretField, error) {
+	var fields []secretField
+	for _, part := range strings.Fields(text) {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || !fieldNamePattern.MatchString(name) || value == "" || strings.HasPrefix(value, "=") {
+			return nil, nil
+		}
+		fields = append(fields, secretField{Name: name, Value: value})
+	}
+
+	for i, f := range fields {
+		if slices.Contains(reservedFieldNames, f.Name) {
+			return nil, fmt.Errorf("`%s` cannot be used as a field name. Choose another name, or open the form with `/share` to share the text as one secret.", f.Name)
+		}
+		if slices.ContainsFunc(fields[:i], func(g secretField) bool { return g.Name == f.Name }) {
+			return nil, fmt.Errorf("The field `%s` is given more than once.", f.Name)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseSecretFields(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []secretField
+		wantErr bool
+	}{
+		{text: "hunter2"},
+		{text: "correct horse battery staple"},
+		{text: "username=app password=hunter2", want: []secretField{{"username", "app"}, {"password", "hunter2"}}},
+		{text: "  token=abc==  ", want: []secretField{{"token", "abc=="}}},
+		{text: "username=app hunter2"},
+		{text: "username= hunter2"},
+		{text: "a==b"},
+		{text: "1st=x"},
+		{text: "user=a user=b", wantErr: true},
+		{text: "filename=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseSecretFields(tt.text)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseSecretFields(%q) = %v, %v, want %v (error %v)", tt.text, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSecretFieldsRoundTrip(t *testing.T) {
+	fields := []secretField{{"username", "app"}, {"password", "hunter2"}}
+	for _, key := range []secretCipher{nil, aesKey(bytes.Repeat([]byte{7}, 32))} {
+		store := newFakeSecretStore()
+		paths := kvPaths{mount: "secrets", version: 2}
+		if err := storeSecret(context.Background(), store, paths, "secret-1", secretPayload{Fields: fields}, key); err != nil {
+			t.Fatalf("storeSecret() error = %v", err)
+		}
+		data, _ := store.data[paths.data("secret-1")]["data"].(map[string]interface{})
+		if key == nil && (data["username"] != "app" || data["password"] != "hunter2") {
+			t.Errorf("stored data = %v, want a field per value", data)
+		}
+		if _, ok := data["secret"]; ok {
+			t.Errorf("stored data = %v, want no secret field", data)
+		}
+
+		got, err := readSecret(context.Background(), store, paths, "secret-1", key)
+		if err != nil || !reflect.DeepEqual(got.Fields, fields) {
+			t.Errorf("readSecret() = %+v, %v, want fields %v", got, err, fields)
+		}
+	}
+}
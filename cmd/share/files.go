@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// errFileTooLarge is returned when a file exceeds the configured maximum.
+var errFileTooLarge = errors.New("file too large")
+
+// downloadSlackFile fetches the content of a file uploaded to Slack, refusing
+// files larger than maxBytes.
+func downloadSlackFile(client *slack.Client, f slack.File, maxBytes int) (*secretFile, error) {
+	if f.Size > maxBytes {
+		return nil, errFileTooLarge
+	}
+
+	w := &limitedBuffer{max: maxBytes}
+	if err := client.GetFile(f.URLPrivateDownload, w); err != nil {
+		return nil, err
+	}
+	return &secretFile{Name: f.Name, ContentType: f.Mimetype, Content: w.buf.Bytes()}, nil
+}
+
+// limitedBuffer is a bytes.Buffer that fails writes beyond max bytes.
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.buf.Len()+len(p) > b.max {
+		return 0, errFileTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// formatBytes renders n as a human readable size such as "1 MB".
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10 && n%(1<<10) == 0:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
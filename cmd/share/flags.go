@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// commandFlag is a --name option given to a slash command, with its value
+// if it takes one.
+type commandFlag struct {
+	name  string
+	value string
+}
+
+// flagSeparators end a flag or its value.
+const flagSeparators = " \t\n"
+
+// quotePairs maps each opening quote to its closing quote. Slack clients
+// that substitute smart quotes send the curly pair.
+var quotePairs = map[rune]rune{'"': '"', '\'': '\'', '“': '”'}
+
+// splitFlags consumes the flags at the start of text and returns them in
+// order along with the remaining text. valued names the flags the command
+// accepts, mapped to whether each takes a value; a value may be quoted, and
+// a valued flag at the very end is returned with an empty one for its
+// handler to reject. Parsing stops at the first token that is not one of
+// them, or after a "--" terminator, so the remainder can itself start with
+// "--". A remainder that is wholly enclosed in quotes is unquoted, except
+// after "--", where it is taken literally.
+func splitFlags(text string, valued map[string]bool) ([]commandFlag, string, error) {
+	var flags []commandFlag
+	rest := strings.TrimLeft(text, flagSeparators)
+	for strings.HasPrefix(rest, "--") {
+		token, after := cutToken(rest)
+		if token == "--" {
+			return flags, strings.TrimLeft(after, flagSeparators), nil
+		}
+		name, value, hasValue := strings.Cut(token, "=")
+		takesValue, ok := valued[name]
+		if !ok {
+			break
+		}
+		if takesValue && !hasValue {
+			if value, after, ok = cutValue(strings.TrimLeft(after, flagSeparators)); !ok {
+				return flags, "", fmt.Errorf("The value of `%s` is missing its closing quote.", name)
+			}
+		} else if hasValue && !takesValue {
+			return flags, "", fmt.Errorf("`%s` does not take a value.", name)
+		}
+		flags = append(flags, commandFlag{name: name, value: value})
+		rest = strings.TrimLeft(after, flagSeparators)
+	}
+
+	if unquoted, ok := unquote(rest); ok {
+		rest = unquoted
+	}
+	return flags, rest, nil
+}
+
+// cutToken splits s at its first separator.
+func cutToken(s string) (token, rest string) {
+	if i := strings.IndexAny(s, flagSeparators); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// cutValue splits a flag's value, which may be quoted, from the text after
+// it, reporting false if a quoted value is not closed.
+func cutValue(s string) (value, rest string, ok bool) {
+	r, size := utf8.DecodeRuneInString(s)
+	closing, quoted := quotePairs[r]
+	if !quoted {
+		value, rest = cutToken(s)
+		return value, rest, true
+	}
+	inner := s[size:]
+	end := strings.IndexRune(inner, closing)
+	if end < 0 {
+		return "", "", false
+	}
+	return inner[:end], inner[end+utf8.RuneLen(closing):], true
+}
+
+// unquote returns s without its enclosing quotes, reporting false if it is
+// not wholly enclosed in a matching pair.
+func unquote(s string) (string, bool) {
+	r, size := utf8.DecodeRuneInString(s)
+	closing, ok := quotePairs[r]
+	if !ok {
+		return "", false
+	}
+	inner, ok := strings.CutSuffix(s[size:], string(closing))
+	if !ok || strings.ContainsRune(inner, closing) {
+		return "", false
+	}
+	return inner, true
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitFlags(t *testing.T) {
+	tests := []struct {
+		text  string
+		flags []commandFlag
+		rest  string
+	}{
+		{"hunter2", nil, "hunter2"},
+		{"--ttl 30m hunter2", []commandFlag{{"--ttl", "30m"}}, "hunter2"},
+		{"--ttl=30m --burn hunter2", []commandFlag{{"--ttl", "30m"}, {"--burn", ""}}, "hunter2"},
+		{"--to \"@alice, @bob\" hunter2", []commandFlag{{"--to", "@alice, @bob"}}, "hunter2"},
+		{"--ttl 1h correct horse battery staple", []commandFlag{{"--ttl", "1h"}}, "correct horse battery staple"},
+		{"--ttl 1h \"  padded secret  \"", []commandFlag{{"--ttl", "1h"}}, "  padded secret  "},
+		{"--burn “smart quoted”", []commandFlag{{"--burn", ""}}, "smart quoted"},
+		{"'single quoted'", nil, "single quoted"},
+		{"\"one\" and \"two\"", nil, "\"one\" and \"two\""},
+		{"--uses 2\n-----BEGIN KEY-----\n--burn\n", []commandFlag{{"--uses", "2"}}, "-----BEGIN KEY-----\n--burn\n"},
+		// A secret that starts with -- but is not a flag needs no terminator.
+		{"--not-a-flag", nil, "--not-a-flag"},
+		// After --, the rest is taken literally, even if it looks like a
+		// flag or is quoted.
+		{"--ttl 5m -- --burn", []commandFlag{{"--ttl", "5m"}}, "--burn"},
+		{"-- \"quoted\"", nil, "\"quoted\""},
+		{"-- -- --", nil, "-- --"},
+		{"--ttl", []commandFlag{{"--ttl", ""}}, ""},
+	}
+	valued := map[string]bool{"--ttl": true, "--uses": true, "--to": true, "--burn": false}
+	for _, tt := range tests {
+		flags, rest, err := splitFlags(tt.text, valued)
+		if err != nil {
+			t.Errorf("splitFlags(%q) error = %v", tt.text, err)
+			continue
+		}
+		if !slices.Equal(flags, tt.flags) || rest != tt.rest {
+			t.Errorf("splitFlags(%q) = %q, %q, want %q, %q", tt.text, flags, rest, tt.flags, tt.rest)
+		}
+	}
+}
+
+func TestSplitFlagsErrors(t *testing.T) {
+	valued := map[string]bool{"--to": true, "--burn": false}
+	for text, want := range map[string]string{
+		"--to \"@alice hunter2": "closing quote",
+		"--burn=yes hunter2":    "does not take a value",
+	} {
+		if _, _, err := splitFlags(text, valued); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("splitFlags(%q) error = %v, want it to mention %q", text, err, want)
+		}
+	}
+}
+
+func TestParseShareArgsTerminator(t *testing.T) {
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses}}
+	args, err := parseShareArgs("--ttl 10m -- --uses 5 is my password", cfg)
+	if err != nil {
+		t.Fatalf("parseShareArgs() error = %v", err)
+	}
+	if args.ttl != 10*time.Minute || args.uses != defaultTokenUses || args.secret != "--uses 5 is my password" {
+		t.Errorf("parseShareArgs() = %+v, want the text after -- as the secret", args)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	defaultPasswordLength = 24
+	minPasswordLength     = 8
+	maxPasswordLength     = 256
+)
+
+// Character sets for /generate --charset.
+const (
+	charsetAlphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	charsetFull         = charsetAlphanumeric + "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+var charsets = map[string]string{
+	"alphanumeric": charsetAlphanumeric,
+	"full":         charsetFull,
+}
+
+// generateArgs holds the options parsed from the text of a /generate command.
+type generateArgs struct {
+	shareArgs
+	length  int
+	charset string
+}
+
+// handleGenerateCommand shares a freshly generated password. The password is
+// only ever shown through the retrieval link, never in the conversation.
+func (b *bot) handleGenerateCommand(ctx context.Context, cmd slack.SlashCommand) {
+	if !b.allowShareChannel(cmd) {
+		return
+	}
+	if !b.shareLimiter.Allow(cmd.UserID) {
+		slog.Warn("Share rate limit exceeded", "event", "generate", "user_id", cmd.UserID)
+		sharesTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, rateLimitedMessage)
+		return
+	}
+
+	args, err := parseGenerateArgs(cmd.Text, b.cfg)
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Rejected generate command", invalid(err), "event", "generate", "user_id", cmd.UserID)
+		return
+	}
+
+	args.secret, err = generatePassword(args.length, args.charset)
+	if err != nil {
+		slog.Error("Failed to generate password", "event", "generate", "user_id", cmd.UserID, "error", err)
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Failed to generate a password. Please try again.")
+		return
+	}
+
+	b.shareSecret(ctx, shareRequest{
+		shareArgs:   args.shareArgs,
+		description: fmt.Sprintf("A new %d-character password has", args.length),
+		teamID:      cmd.TeamID,
+		channelID:   cmd.ChannelID,
+		userID:      cmd.UserID,
+		userName:    cmd.UserName,
+		responseURL: cmd.ResponseURL,
+	})
+}
+
+// parseGenerateArgs parses `[--charset name] [--ttl d] [--uses n]
+// [--to @user] [--no-notify] [length]` in any order.
+func parseGenerateArgs(text string, cfg *Config) (generateArgs, error) {
+	args := generateArgs{
+		shareArgs: cfg.defaultArgs(),
+		length:    defaultPasswordLength,
+		charset:   charsetFull,
+	}
+
+	fields := strings.Fields(text)
+	ttlSet := false
+	for i := 0; i < len(fields); i++ {
+		name := fields[i]
+		var value string
+		switch name {
+		case "--charset", "--ttl", "--expires-at", "--uses", "--to":
+			if i+1 < len(fields) {
+				i++
+				value = fields[i]
+			}
+		}
+
+		var err error
+		switch name {
+		case "--charset":
+			var ok bool
+			if args.charset, ok = charsets[value]; !ok {
+				err = fmt.Errorf("Invalid charset %q. Use `alphanumeric` or `full`.", value)
+			}
+		case "--ttl":
+			args.ttl, err = parseTTL(value, cfg)
+			ttlSet = true
+		case "--expires-at":
+			args.expiresAt, args.ttl, err = parseExpiresAt(value, cfg, time.Now())
+		case "--uses":
+			args.uses, err = parseUses(value, cfg)
+		case "--to":
+			args.to, err = appendRecipients(args.to, value)
+		case "--no-notify":
+			args.notify = false
+		default:
+			n, convErr := strconv.Atoi(name)
+			if convErr != nil || n < minPasswordLength || n > maxPasswordLength {
+				err = fmt.Errorf("Invalid length %q. Use a number between %d and %d. Usage: `/generate [--charset alphanumeric|full] [--ttl 30m | --expires-at <time>] [--uses 1] [length]`", name, minPasswordLength, maxPasswordLength)
+			}
+			args.length = n
+		}
+		if err != nil {
+			return args, err
+		}
+	}
+	if ttlSet && !args.expiresAt.IsZero() {
+		return args, errTTLAndExpiresAt
+	}
+	return args, nil
+}
+
+// generatePassword returns length characters drawn uniformly from charset
+// using crypto/rand.
+func generatePassword(length int, charset string) (string, error) {
+	max := big.NewInt(int64(len(charset)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = charset[n.Int64()]
+	}
+	return string(password), nil
+}
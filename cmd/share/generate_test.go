@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	for name, charset := range charsets {
+		t.Run(name, func(t *testing.T) {
+			password, err := generatePassword(64, charset)
+			if err != nil {
+				t.Fatalf("generatePassword() error = %v", err)
+			}
+			if len(password) != 64 {
+				t.Errorf("len = %d, want 64", len(password))
+			}
+			for _, r := range password {
+				if !strings.ContainsRune(charset, r) {
+					t.Errorf("password contains %q, which is not in the charset", r)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGenerateArgs(t *testing.T) {
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses}}
+
+	args, err := parseGenerateArgs("", cfg)
+	if err != nil {
+		t.Fatalf("parseGenerateArgs(\"\") error = %v", err)
+	}
+	if args.length != defaultPasswordLength || args.charset != charsetFull || !args.notify {
+		t.Errorf("unexpected defaults: %+v", args)
+	}
+
+	args, err = parseGenerateArgs("32 --charset alphanumeric --ttl 2h --no-notify", cfg)
+	if err != nil {
+		t.Fatalf("parseGenerateArgs() error = %v", err)
+	}
+	if args.length != 32 || args.charset != charsetAlphanumeric || args.ttl.String() != "2h0m0s" || args.notify {
+		t.Errorf("options not applied: %+v", args)
+	}
+
+	for _, text := range []string{"4", "1000", "many", "--charset emoji", "--charset"} {
+		if _, err := parseGenerateArgs(text, cfg); err == nil {
+			t.Errorf("parseGenerateArgs(%q) succeeded, want error", text)
+		}
+	}
+}
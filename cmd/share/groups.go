@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// maxGroupMembers bounds MAX_GROUP_MEMBERS so that a group's members fit
+// in the secret's custom metadata, which Vault limits to 64 keys of 512
+// bytes each.
+const (
+	defaultMaxGroupMembers = 100
+	maxGroupMembers        = 1000
+)
+
+// Personal links beyond the first groupDMBurst are sent one every
+// defaultGroupDMInterval, so that sharing with a large user group stays
+// within Slack's rate limit on posting messages instead of relying on
+// retries that could drop some of them. They are sent in the background,
+// since a large group takes longer than COMMAND_TIMEOUT allows.
+const (
+	groupDMBurst           = 10
+	defaultGroupDMInterval = time.Second
+)
+
+// appendGroups adds the comma-separated --group references in value to
+// groups.
+func appendGroups(groups []string, value string) ([]string, error) {
+	added := false
+	for _, ref := range strings.Split(value, ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			groups = append(groups, ref)
+			added = true
+		}
+	}
+	if !added {
+		return groups, errors.New("Please name the user group the secret is for, e.g. `--group @oncall`.")
+	}
+	return groups, nil
+}
+
+// resolveGroups turns the --group references in refs into user group IDs
+// and the user IDs of their members. A reference is an escaped mention such
+// as <!subteam^S123|@oncall>, a group ID, or a group handle with or without
+// a leading @, which is looked up in teamID's workspace.
+func (b *bot) resolveGroups(teamID string, refs []string) (groupIDs, members []string, err error) {
+	if len(refs) == 0 {
+		return nil, nil, nil
+	}
+	groups, err := b.api(teamID).GetUserGroups(slack.GetUserGroupsOptionIncludeUsers(true))
+	if err != nil {
+		return nil, nil, errors.New("Failed to look up user groups. Please try again.")
+	}
+	for _, ref := range refs {
+		id, isID := mentionGroupID(ref)
+		var group *slack.UserGroup
+		for i, g := range groups {
+			if g.DateDelete == 0 && (isID && g.ID == id || !isID && strings.EqualFold(g.Handle, strings.TrimPrefix(ref, "@"))) {
+				group = &groups[i]
+				break
+			}
+		}
+		switch {
+		case group == nil:
+			return nil, nil, fmt.Errorf("Could not find a Slack user group called %s.", ref)
+		case len(group.Users) == 0:
+			return nil, nil, fmt.Errorf("The user group %s has no members.", ref)
+		case b.cfg.MaxGroupMembers > 0 && len(group.Users) > b.cfg.MaxGroupMembers:
+			return nil, nil, fmt.Errorf("The user group %s has %d members, more than the %d a secret can be shared with.", ref, len(group.Users), b.cfg.MaxGroupMembers)
+		}
+		groupIDs = append(groupIDs, group.ID)
+		members = appendNew(members, group.Users...)
+	}
+	return groupIDs, members, nil
+}
+
+// mentionGroupID returns the user group ID in ref if it is an escaped
+// mention or a bare group ID.
+func mentionGroupID(ref string) (string, bool) {
+	if strings.HasPrefix(ref, "<!subteam^") && strings.HasSuffix(ref, ">") {
+		id, _, _ := strings.Cut(ref[len("<!subteam^"):len(ref)-1], "|")
+		return id, isGroupID(id)
+	}
+	return ref, isGroupID(ref)
+}
+
+func isGroupID(s string) bool {
+	if len(s) < 2 || s[0] != 'S' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// appendNew adds the IDs in add that are not already in ids.
+func appendNew(ids []string, add ...string) []string {
+	for _, id := range add {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// describeRecipients names who a restricted secret was shared with: the
+// users given with --to and the groups given with --group, with how many
+// people that is in all when groups were given.
+func describeRecipients(users, groupIDs, recipients []string) string {
+	if len(groupIDs) == 0 {
+		return formatMentions(recipients)
+	}
+	names := make([]string, 0, len(groupIDs)+len(users))
+	for _, id := range groupIDs {
+		names = append(names, "<!subteam^"+id+">")
+	}
+	if len(users) > 0 {
+		names = append(names, formatMentions(users))
+	}
+	return fmt.Sprintf("%s (%d %s)", strings.Join(names, ", "), len(recipients), plural(len(recipients), "person", "people"))
+}
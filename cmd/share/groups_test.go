@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestShareWithGroup(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	var mu sync.Mutex
+	var dms []string
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/usergroups.list":
+			w.Write([]byte(`{"ok":true,"usergroups":[
+				{"id":"S1","handle":"oncall","users":["U1","U2","UGONE"]},
+				{"id":"S2","handle":"empty","users":[]},
+				{"id":"S3","handle":"everyone","users":["U1","U2","U3","U4"]}]}`))
+		case "/chat.postMessage":
+			r.ParseForm()
+			if r.Form.Get("channel") == "UGONE" {
+				w.Write([]byte(`{"ok":false,"error":"user_not_found"}`))
+				return
+			}
+			mu.Lock()
+			dms = append(dms, r.Form.Get("channel")+": "+r.Form.Get("text"))
+			mu.Unlock()
+			w.Write([]byte(`{"ok":true}`))
+		default:
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer slackAPI.Close()
+	b.workspaces = newWorkspaces(b.cfg, slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+	b.cfg.MaxGroupMembers = 3
+	share := func(text string) string {
+		t.Helper()
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U9", ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	got := share("--group <!subteam^S1|@oncall> --to U2,U5 hunter2")
+	if !strings.Contains(got, "shared with <!subteam^S1>, <@U2>, <@U5> (4 people)") || !strings.Contains(got, "3 of 4 personal links were delivered") || !strings.Contains(got, "could not be sent to <@UGONE>") {
+		t.Errorf("reply = %q, want the group, the delivered count and the failure", got)
+	}
+	if strings.Contains(got, "hvs.") {
+		t.Errorf("reply = %q, want no token", got)
+	}
+	if len(dms) != 3 || !strings.HasPrefix(dms[0], "U2: ") || !strings.Contains(dms[0], "u=U2") {
+		t.Errorf("DMs = %q, want one personal link for each member and other recipient", dms)
+	}
+	meta, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), tokens.created[0].Metadata["secret_id"])
+	if err != nil || strings.Join(meta.AllowedUsers, ",") != "U2,U5,U1,UGONE" {
+		t.Errorf("AllowedUsers = %q, %v, want each member once", meta.AllowedUsers, err)
+	}
+
+	for text, want := range map[string]string{
+		"--group @nobody hunter2":        "Could not find a Slack user group called @nobody",
+		"--group empty hunter2":          "has no members",
+		"--group @everyone hunter2":      "has 4 members, more than the 3",
+		"--group @oncall --code hunter2": "cannot be combined with `--code`",
+		"--group , hunter2":              "Please name the user group",
+	} {
+		if got := share(text); !strings.Contains(got, want) {
+			t.Errorf("reply to %q = %q, want %q", text, got, want)
+		}
+	}
+	if len(tokens.created) != 1 {
+		t.Errorf("%d tokens created, want only the first share to go ahead", len(tokens.created))
+	}
+}
+
+func TestShareWithLargeGroup(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.MaxGroupMembers = defaultMaxGroupMembers
+	b.dmInterval = time.Millisecond
+
+	// More members than fit in one custom metadata value.
+	members := make([]string, defaultMaxGroupMembers)
+	for i := range members {
+		members[i] = fmt.Sprintf("U%010d", i)
+	}
+	group, _ := json.Marshal(members)
+	var mu sync.Mutex
+	var dms []string
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/usergroups.list":
+			fmt.Fprintf(w, `{"ok":true,"usergroups":[{"id":"S1","handle":"eng","users":%s}]}`, group)
+		case "/chat.postMessage":
+			r.ParseForm()
+			mu.Lock()
+			dms = append(dms, r.Form.Get("channel"))
+			mu.Unlock()
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	defer slackAPI.Close()
+	b.workspaces = newWorkspaces(b.cfg, slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--group @eng hunter2", UserID: "U9", ResponseURL: responseURL})
+	got := replies()
+	if len(got) != 1 || !strings.Contains(got[0], "10 of the first 10 personal links were delivered. The other 90 are being sent now") {
+		t.Fatalf("replies = %q, want the first links reported and the rest promised", got)
+	}
+	b.inflight.Wait()
+	if got := replies(); len(got) != 2 || !strings.Contains(got[1], "90 of the remaining 90 personal links") {
+		t.Errorf("replies = %q, want a follow-up once the rest were sent", got)
+	}
+	if len(dms) != len(members) {
+		t.Errorf("%d DMs sent, want one for each of the %d members", len(dms), len(members))
+	}
+	meta, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), tokens.created[0].Metadata["secret_id"])
+	if err != nil || !slices.Equal(meta.AllowedUsers, members) {
+		t.Errorf("AllowedUsers = %d users, %v, want every member", len(meta.AllowedUsers), err)
+	}
+}
+
+func TestMentionGroupID(t *testing.T) {
+	tests := []struct {
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{"<!subteam^S0614TZR7|@oncall>", "S0614TZR7", true},
+		{"<!subteam^S42>", "S42", true},
+		{"S0614TZR7", "S0614TZR7", true},
+		{"@oncall", "", false},
+		{"<@U123>", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := mentionGroupID(tt.ref)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("mentionGroupID(%q) = %q, %v, want %q, %v", tt.ref, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// probeTimeout bounds each dependency check made by /readyz.
+const probeTimeout = 2 * time.Second
+
+// errVaultSealed is reported by checkVault while Vault is sealed.
+var errVaultSealed = errors.New("sealed")
+
+// healthServer answers liveness and readiness probes.
+type healthServer struct {
+	vault      *api.Client
+	workspaces *workspaces
+	// socket is the Socket Mode connection's status, or nil in HTTP mode.
+	socket *socketStatus
+}
+
+func newHealthServer(vaultClient *api.Client, ws *workspaces, cfg *Config, socket *socketStatus) *http.Server {
+	hs := &healthServer{vault: vaultClient, workspaces: ws, socket: socket}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", hs.handleHealthz)
+	mux.HandleFunc("GET /readyz", hs.handleReadyz)
+	return &http.Server{Addr: cfg.HealthAddr, Handler: mux}
+}
+
+// handleHealthz reports that the process is up.
+func (hs *healthServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the bot can reach both Vault and Slack,
+// naming whichever cannot be reached, and whether Vault is sealed. In
+// Socket Mode, Slack is only reachable while the connection is up.
+func (hs *healthServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	var failures []string
+	if err := checkVault(r.Context(), hs.vault); err != nil {
+		failures = append(failures, "vault: "+err.Error())
+	}
+	if err := hs.checkSlack(r.Context()); err != nil {
+		failures = append(failures, "slack: "+err.Error())
+	}
+	if hs.socket != nil {
+		if err := hs.socket.check(); err != nil {
+			failures = append(failures, "slack: "+err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		http.Error(w, strings.Join(failures, "\n"), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// checkVault reports whether client can reach an unsealed Vault and its
+// token is still valid, within probeTimeout.
+func checkVault(ctx context.Context, client *api.Client) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	status, err := client.Sys().SealStatusWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Sealed {
+		return errVaultSealed
+	}
+	_, err = client.Auth().Token().LookupSelfWithContext(ctx)
+	return err
+}
+
+// checkSlack checks the bot token of every workspace, naming the team of
+// any that fails when there are several.
+func (hs *healthServer) checkSlack(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	var errs []error
+	for team, client := range hs.workspaces.all() {
+		if _, err := client.AuthTestContext(ctx); err != nil {
+			if team != "" {
+				err = fmt.Errorf("%s: %w", team, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
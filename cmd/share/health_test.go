@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		vaultOK    bool
+		sealed     bool
+		slackOK    bool
+		socketDown bool
+		wantStatus int
+		wantBody   string
+	}{
+		{"ready", true, false, true, false, http.StatusOK, "ok"},
+		{"vault down", false, false, true, false, http.StatusServiceUnavailable, "vault:"},
+		{"vault sealed", true, true, true, false, http.StatusServiceUnavailable, "vault: sealed"},
+		{"slack down", true, false, false, false, http.StatusServiceUnavailable, "slack:"},
+		{"socket not connected", true, false, true, true, http.StatusServiceUnavailable, "slack: socket mode connection connecting"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1/sys/seal-status" {
+					fmt.Fprintf(w, `{"sealed":%t}`, tt.sealed)
+					return
+				}
+				if !tt.vaultOK {
+					http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+					return
+				}
+				w.Write([]byte(`{"data":{"id":"hvs.test"}}`))
+			}))
+			defer vault.Close()
+			slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if !tt.slackOK {
+					w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+					return
+				}
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer slackAPI.Close()
+
+			vaultClient, err := newVaultClient(&Config{VaultAddr: vault.URL, VaultToken: "hvs.test"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			vaultClient.SetMaxRetries(0)
+			var socket *socketStatus
+			if tt.socketDown {
+				socket = newSocketStatus(defaultSlackPingTimeout)
+			}
+			srv := newHealthServer(vaultClient, &workspaces{fallback: slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/"))}, &Config{}, socket)
+
+			rec := httptest.NewRecorder()
+			srv.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus || !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("GET /readyz = %d %q, want %d containing %q", rec.Code, rec.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Values of MODE.
+const (
+	modeSocket = "socket"
+	modeHTTP   = "http"
+)
+
+const defaultSlackHTTPAddr = ":3000"
+
+// newSlackHTTPServer receives slash commands and interaction payloads that
+// Slack POSTs to the request URLs configured for the app, as an alternative
+// to socket mode. Every request must carry a valid Slack signature.
+func newSlackHTTPServer(b *bot) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("POST /slack/commands", verifySlackRequests(b.cfg.SlackSigningSecret, http.HandlerFunc(b.handleCommandRequest)))
+	mux.Handle("POST /slack/interactivity", verifySlackRequests(b.cfg.SlackSigningSecret, http.HandlerFunc(b.handleInteractivityRequest)))
+	mux.Handle("POST /slack/events", verifySlackRequests(b.cfg.SlackSigningSecret, http.HandlerFunc(b.handleEventsRequest)))
+	return &http.Server{Addr: b.cfg.SlackHTTPAddr, Handler: forwardedFor(b.cfg.TrustedProxies, mux)}
+}
+
+// handleCommandRequest acknowledges a slash command with an empty response
+// and runs it in the background, replying through its response URL as in
+// socket mode.
+func (b *bot) handleCommandRequest(w http.ResponseWriter, r *http.Request) {
+	cmd, err := slack.SlashCommandParse(r)
+	if err != nil {
+		http.Error(w, "invalid slash command", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	b.dispatchCommand(cmd)
+}
+
+// handleInteractivityRequest handles an interaction payload. Its
+// acknowledgement is the HTTP response.
+func (b *bot) handleInteractivityRequest(w http.ResponseWriter, r *http.Request) {
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &callback); err != nil {
+		http.Error(w, "invalid interaction payload", http.StatusBadRequest)
+		return
+	}
+
+	b.handleInteraction(func(payload ...interface{}) {
+		if len(payload) == 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(payload[0]); err != nil {
+			slog.Error("Failed to write interaction response", "error", err)
+		}
+	}, callback)
+}
+
+// handleEventsRequest handles an Events API request, answering the
+// url_verification challenge Slack sends when the request URL is set.
+func (b *bot) handleEventsRequest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+	// The signature has already been checked, so the deprecated
+	// verification token is not.
+	event, err := slackevents.ParseEvent(body, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		http.Error(w, "invalid event", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case slackevents.URLVerification:
+		var challenge slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &challenge); err != nil {
+			http.Error(w, "invalid challenge", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(challenge.Challenge))
+	case slackevents.CallbackEvent:
+		w.WriteHeader(http.StatusOK)
+		b.handleEventsAPI(event)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
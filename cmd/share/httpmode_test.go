@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSlackHTTPServer(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.cfg.SlackSigningSecret = testSigningSecret
+	handler := newSlackHTTPServer(b).Handler
+
+	post := func(path string, form url.Values, secret string) *httptest.ResponseRecorder {
+		body := form.Encode()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		signSlackRequest(req.Header, secret, body, time.Now())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("command", func(t *testing.T) {
+		form := url.Values{"command": {"/help"}, "user_id": {"U1"}, "response_url": {responseURL}}
+		if rec := post("/slack/commands", form, testSigningSecret); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		b.inflight.Wait()
+		if got := replies(); len(got) != 1 || !strings.Contains(got[0], "/share") {
+			t.Errorf("replies = %q, want the help text", got)
+		}
+	})
+
+	t.Run("forged command", func(t *testing.T) {
+		before := len(replies())
+		form := url.Values{"command": {"/help"}, "user_id": {"U1"}, "response_url": {responseURL}}
+		if rec := post("/slack/commands", form, "other-secret"); rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", rec.Code)
+		}
+		b.inflight.Wait()
+		if got := replies(); len(got) != before {
+			t.Errorf("forged command was handled: %q", got[before:])
+		}
+	})
+
+	t.Run("view submission", func(t *testing.T) {
+		b.shareLimiter = newRateLimiter(1, time.Minute)
+		b.shareLimiter.Allow("U1")
+
+		payload, _ := json.Marshal(slack.InteractionCallback{
+			Type: slack.InteractionTypeViewSubmission,
+			User: slack.User{ID: "U1"},
+			View: slack.View{CallbackID: shareModalCallbackID},
+		})
+		rec := post("/slack/interactivity", url.Values{"payload": {string(payload)}}, testSigningSecret)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var resp slack.ViewSubmissionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decoding acknowledgement: %v", err)
+		}
+		if resp.ResponseAction != slack.RAErrors || resp.Errors[shareSecretBlockID] != rateLimitedMessage {
+			t.Errorf("acknowledgement = %+v, want the rate limit error on the secret field", resp)
+		}
+	})
+}
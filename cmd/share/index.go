@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+)
+
+// indexField is the field of a user's index secret that lists their secret
+// IDs, comma-separated and oldest first.
+const indexField = "secret_ids"
+
+// readSecretIndex returns the IDs of the secrets userID has shared. Entries
+// may refer to secrets that have since been retrieved or have expired.
+func readSecretIndex(ctx context.Context, store SecretStore, paths kvPaths, userID string) ([]string, error) {
+	resp, err := store.ReadWithContext(ctx, paths.index(userID))
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	v, _ := paths.dataFields(resp)[indexField].(string)
+	if v == "" {
+		return nil, nil
+	}
+	return strings.Split(v, ","), nil
+}
+
+func writeSecretIndex(ctx context.Context, store SecretStore, paths kvPaths, userID string, ids []string) error {
+	fields := map[string]string{indexField: strings.Join(ids, ",")}
+	_, err := store.WriteWithContext(ctx, paths.index(userID), paths.dataBody(fields))
+	return err
+}
+
+// indexedUser reports whether userID is a user the bot keeps an index of
+// secrets for. Any Slack user ID is, but a share made from the command line
+// without --user is not, and the ID becomes part of the index's Vault path,
+// so it is held to the characters secret IDs may use.
+func indexedUser(userID string) bool {
+	return validSecretID(userID)
+}
+
+// updateIndex replaces the index of userID in teamID's workspace with the
+// result of update.
+func (b *bot) updateIndex(ctx context.Context, teamID, userID string, update func([]string) []string) error {
+	if !indexedUser(userID) {
+		return nil
+	}
+
+	b.indexMu.Lock()
+	defer b.indexMu.Unlock()
+
+	paths := b.cfg.kvPaths(teamID)
+	ids, err := readSecretIndex(ctx, b.secrets, paths, userID)
+	if err != nil {
+		return err
+	}
+	return writeSecretIndex(ctx, b.secrets, paths, userID, update(ids))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultMaxInflight = 20
+
+	// inflightQueueWait is how long a handler waits for a free slot before
+	// the user is told the bot is busy.
+	inflightQueueWait = 2 * time.Second
+
+	defaultCommandTimeout = 30 * time.Second
+
+	// defaultTimeoutGrace is how long a handler has to notice that it timed
+	// out, and reply itself, before the user is told.
+	defaultTimeoutGrace = 2 * time.Second
+)
+
+// busyMessage is the reply to a command that found every handler slot taken.
+const busyMessage = "The bot is busy right now. Please try again in a moment."
+
+// commandTimeoutMessage is the follow-up to a command that ran past
+// COMMAND_TIMEOUT.
+const commandTimeoutMessage = "Your command took too long and was stopped, most likely because Vault is responding slowly. Run `/list` to check whether it went through before trying again."
+
+// handlerSlots bounds how many command handlers, which nearly all make Vault
+// requests, run at once. A nil *handlerSlots imposes no limit.
+type handlerSlots struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newHandlerSlots returns slots for n concurrent handlers, or nil, for no
+// limit, when n is not positive.
+func newHandlerSlots(n int) *handlerSlots {
+	if n <= 0 {
+		return nil
+	}
+	return &handlerSlots{slots: make(chan struct{}, n), wait: inflightQueueWait}
+}
+
+// acquire takes a slot, waiting up to s.wait for one to free up, and
+// reports whether it got one.
+func (s *handlerSlots) acquire() bool {
+	if s == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+	}
+	t := time.NewTimer(s.wait)
+	defer t.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+func (s *handlerSlots) release() {
+	if s != nil {
+		<-s.slots
+	}
+}
+
+// runHandler runs fn in the background once a handler slot is free, telling
+// the user that the bot is busy through responseURL, if there is one, when
+// none frees up in time. Shutdown waits for it.
+//
+// fn's context is cancelled after COMMAND_TIMEOUT, so that a stuck Vault
+// request cannot hold its slot and goroutine forever. A handler that is
+// still running b.timeoutGrace later has not replied, so the user is
+// told through responseURL instead.
+func (b *bot) runHandler(event, userID, responseURL string, fn func(ctx context.Context)) {
+	b.inflight.Add(1)
+	go func() {
+		defer b.inflight.Done()
+		if !b.slots.acquire() {
+			slog.Warn("Rejected command because every handler slot is busy", "event", event, "user_id", userID, "max_inflight", b.cfg.MaxInflight)
+			busyTotal.Inc()
+			if responseURL != "" {
+				sendSlackResponse(b.slack, responseURL, busyMessage)
+			}
+			return
+		}
+		defer b.slots.release()
+		inflightHandlers.Inc()
+		defer inflightHandlers.Dec()
+
+		ctx := context.Background()
+		// A hand-built Config, as in tests, sets no timeout.
+		if b.cfg.CommandTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, b.cfg.CommandTimeout)
+			defer cancel()
+		}
+		done, watched := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(watched)
+			b.watchHandler(ctx, done, event, userID, responseURL)
+		}()
+		// Shutdown waits for the watcher too.
+		defer func() {
+			close(done)
+			<-watched
+		}()
+		fn(ctx)
+	}()
+}
+
+// watchHandler waits for the handler running with ctx to close done, and
+// follows up on it through responseURL if it runs out of time.
+func (b *bot) watchHandler(ctx context.Context, done <-chan struct{}, event, userID, responseURL string) {
+	select {
+	case <-done:
+		return
+	case <-ctx.Done():
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return
+	}
+	slog.Warn("Command ran past its timeout", "event", event, "user_id", userID, "timeout", b.cfg.CommandTimeout)
+	commandTimeoutsTotal.Inc()
+
+	t := time.NewTimer(b.timeoutGrace)
+	defer t.Stop()
+	select {
+	case <-done:
+	case <-t.C:
+		if responseURL != "" {
+			sendSlackResponse(b.slack, responseURL, commandTimeoutMessage)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHandlerSlots(t *testing.T) {
+	s := newHandlerSlots(1)
+	s.wait = 10 * time.Millisecond
+
+	if !s.acquire() {
+		t.Fatal("acquire() on a free slot = false")
+	}
+	if s.acquire() {
+		t.Fatal("acquire() with every slot taken = true")
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond)
+		s.release()
+	}()
+	s.wait = time.Second
+	if !s.acquire() {
+		t.Error("acquire() while a slot frees up = false")
+	}
+
+	var unlimited *handlerSlots
+	if !unlimited.acquire() {
+		t.Error("acquire() without a limit = false")
+	}
+	unlimited.release()
+}
+
+func TestDispatchCommandBusy(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.slots = newHandlerSlots(1)
+	b.slots.wait = 10 * time.Millisecond
+	b.slots.acquire()
+
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	b.inflight.Wait()
+	if got := replies(); len(got) != 1 || got[0] != busyMessage {
+		t.Fatalf("replies while busy = %q, want %q", got, busyMessage)
+	}
+
+	b.slots.release()
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	b.inflight.Wait()
+	if got := replies(); len(got) != 2 || got[1] == busyMessage {
+		t.Errorf("reply once a slot is free = %q, want the share", got[len(got)-1])
+	}
+}
+
+func TestRunHandlerTimeout(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.cfg.CommandTimeout = 20 * time.Millisecond
+	b.timeoutGrace = 10 * time.Millisecond
+
+	// A handler that gives up when its context does replies itself.
+	b.runHandler("command", "U1", responseURL, func(ctx context.Context) {
+		<-ctx.Done()
+		sendSlackResponse(b.slack, responseURL, vaultFailure(ctx.Err(), ""))
+	})
+	// Wait returns only once the handler's watcher has exited.
+	b.inflight.Wait()
+	if got := replies(); len(got) != 1 || got[0] != vaultTimeoutMessage {
+		t.Fatalf("replies = %q, want only the handler's own", got)
+	}
+
+	// One stuck past the grace period is followed up on.
+	b.runHandler("command", "U1", responseURL, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * b.timeoutGrace)
+	})
+	b.inflight.Wait()
+	if got := replies(); len(got) != 2 || got[1] != commandTimeoutMessage {
+		t.Errorf("replies = %q, want the timeout follow-up", got)
+	}
+}
@@ -0,0 +1,234 @@
+//go:build integration
+
+package main
+
+// These tests run the bot's Vault code against a real Vault dev server:
+//
+//	go test -tags integration ./cmd/share
+//
+// They start the vault binary found on PATH, or at VAULT_BINARY, and are
+// skipped when there is none. The Redis state store is tested against the
+// server at REDIS_ADDR, if set.
+
+import (
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/redis/go-redis/v9"
+)
+
+const integrationRootToken = "root"
+
+// startVaultDevServer runs a Vault dev server for the duration of the test,
+// with a KV v2 engine at secrets, and returns a config pointing at it with
+// the root token.
+func startVaultDevServer(t *testing.T) *Config {
+	t.Helper()
+	bin := os.Getenv("VAULT_BINARY")
+	if bin == "" {
+		var err error
+		if bin, err = exec.LookPath("vault"); err != nil {
+			t.Skip("vault binary not found; set VAULT_BINARY or add vault to PATH")
+		}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	cmd := exec.Command(bin, "server", "-dev", "-dev-root-token-id="+integrationRootToken, "-dev-listen-address="+addr)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting vault: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	cfg := &Config{
+		VaultAddr:         "http://" + addr,
+		VaultToken:        integrationRootToken,
+		VaultSecretsMount: defaultSecretsMount,
+		VaultKVVersion:    2,
+		VaultMaxAttempts:  1,
+		VaultTimeout:      defaultVaultTimeout,
+	}
+	client := newIntegrationClient(t, cfg, integrationRootToken)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if _, err := client.Sys().Health(); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("vault dev server did not start: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	err = client.Sys().Mount(cfg.VaultSecretsMount, &api.MountInput{Type: "kv", Options: map[string]string{"version": "2"}})
+	if err != nil {
+		t.Fatalf("mounting KV engine: %v", err)
+	}
+	return cfg
+}
+
+func newIntegrationClient(t *testing.T, cfg *Config, token string) *api.Client {
+	t.Helper()
+	client, err := newVaultClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(token)
+	return client
+}
+
+func TestIntegrationShareAndRetrieve(t *testing.T) {
+	cfg := startVaultDevServer(t)
+	ctx := context.Background()
+	bot := newIntegrationClient(t, cfg, integrationRootToken)
+	paths := cfg.kvPaths("")
+
+	const uses = 3
+	id, err := newSecretID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storeSecret(ctx, vaultStore(bot, cfg), paths, id, secretPayload{Text: "hunter2"}, nil); err != nil {
+		t.Fatalf("storeSecret() error = %v", err)
+	}
+	if err := writeSecretPolicy(ctx, vaultStore(bot, cfg), paths, id); err != nil {
+		t.Fatalf("writeSecretPolicy() error = %v", err)
+	}
+	token, accessor, err := createVaultToken(ctx, vaultTokens(bot, cfg), cfg, []string{paths.policyName(id)}, id, "U1", "alice", time.Minute, uses)
+	if err != nil {
+		t.Fatalf("createVaultToken() error = %v", err)
+	}
+	if accessor == "" {
+		t.Error("createVaultToken() returned no accessor")
+	}
+
+	recipient := newIntegrationClient(t, cfg, token).Logical()
+	for i := 0; i < uses; i++ {
+		got, err := readSecret(ctx, recipient, paths, id, nil)
+		if err != nil || got.Text != "hunter2" {
+			t.Fatalf("read %d with the recipient token = %+v, %v, want hunter2", i+1, got, err)
+		}
+	}
+	if _, err := readSecret(ctx, recipient, paths, id, nil); err == nil {
+		t.Errorf("read %d with a %d-use token succeeded", uses+1, uses)
+	}
+}
+
+func TestIntegrationSelfTest(t *testing.T) {
+	cfg := startVaultDevServer(t)
+	bot := newIntegrationClient(t, cfg, integrationRootToken)
+	if err := selfTest(context.Background(), bot, cfg); err != nil {
+		t.Fatalf("selfTest() error = %v", err)
+	}
+	if ids, err := listSecretIDs(context.Background(), vaultStore(bot, cfg), cfg.kvPaths("")); err != nil || len(ids) != 0 {
+		t.Errorf("secrets left = %q, %v, want none", ids, err)
+	}
+}
+
+func TestIntegrationTokenPolicy(t *testing.T) {
+	cfg := startVaultDevServer(t)
+	ctx := context.Background()
+	bot := newIntegrationClient(t, cfg, integrationRootToken)
+	paths := cfg.kvPaths("")
+
+	if err := storeSecret(ctx, vaultStore(bot, cfg), paths, "secret-1", secretPayload{Text: "hunter2"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSecretMetadata(ctx, vaultStore(bot, cfg), paths, "secret-1", secretMetadata{SharedBy: "U1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSecretPolicy(ctx, vaultStore(bot, cfg), paths, "secret-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeSecret(ctx, vaultStore(bot, cfg), paths, "secret-2", secretPayload{Text: "someone else's"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each check gets a fresh token so that a denied request does not use
+	// up the one that follows.
+	denied := map[string]func(SecretStore) error{
+		"metadata": func(s SecretStore) error {
+			_, err := readSecretMetadata(ctx, s, paths, "secret-1")
+			return err
+		},
+		"write": func(s SecretStore) error {
+			return storeSecret(ctx, s, paths, "secret-1", secretPayload{Text: "overwritten"}, nil)
+		},
+		"delete": func(s SecretStore) error {
+			return deleteSecret(ctx, s, paths, "secret-1")
+		},
+		"sibling": func(s SecretStore) error {
+			_, err := readSecret(ctx, s, paths, "secret-2", nil)
+			return err
+		},
+	}
+	for name, op := range denied {
+		token, _, err := createVaultToken(ctx, vaultTokens(bot, cfg), cfg, []string{paths.policyName("secret-1")}, "secret-1", "U1", "alice", time.Minute, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := op(newIntegrationClient(t, cfg, token).Logical()); err == nil {
+			t.Errorf("%s with a recipient token succeeded", name)
+		}
+	}
+
+	if got, err := readSecret(ctx, vaultStore(bot, cfg), paths, "secret-1", nil); err != nil || got.Text != "hunter2" {
+		t.Errorf("secret after denied requests = %+v, %v, want it unchanged", got, err)
+	}
+
+	if err := deleteSecret(ctx, vaultStore(bot, cfg), paths, "secret-1"); err != nil {
+		t.Fatal(err)
+	}
+	if policy, err := bot.Sys().GetPolicy(paths.policyName("secret-1")); err != nil || policy != "" {
+		t.Errorf("policy after deleteSecret() = %q, %v, want it deleted", policy, err)
+	}
+}
+
+// TestIntegrationRedisState runs the Redis state store against the server at
+// REDIS_ADDR, and is skipped when it is not set.
+func TestIntegrationRedisState(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	s := newRedisState(client)
+	ctx := context.Background()
+	prefix := "integration-" + strconv.FormatInt(time.Now().UnixNano(), 10) + ":"
+
+	if ok, err := s.SetNX(ctx, prefix+"a", []byte("1"), time.Minute); err != nil || !ok {
+		t.Fatalf("SetNX() = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.SetNX(ctx, prefix+"a", []byte("2"), time.Minute); ok {
+		t.Error("SetNX() on a set key = true")
+	}
+	if v, ok, err := s.Take(ctx, prefix+"a"); err != nil || !ok || string(v) != "1" {
+		t.Errorf("Take() = %q, %v, %v, want 1", v, ok, err)
+	}
+	if _, ok, _ := s.Take(ctx, prefix+"a"); ok {
+		t.Error("second Take() = true")
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		if n, err := s.Incr(ctx, prefix+"n", time.Minute); err != nil || n != want {
+			t.Errorf("Incr() = %d, %v, want %d", n, err, want)
+		}
+	}
+	if ttl := client.PTTL(ctx, redisKeyPrefix+prefix+"n").Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("counter TTL = %s, want it to expire within a minute", ttl)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// ackFunc acknowledges an interactive payload, optionally with a response
+// such as modal validation errors. In socket mode it answers over the
+// websocket and in HTTP mode it writes the HTTP response.
+type ackFunc func(payload ...interface{})
+
+// socketAck returns the ackFunc for a socket mode request.
+func (b *bot) socketAck(req *socketmode.Request) ackFunc {
+	return func(payload ...interface{}) {
+		b.slack.Ack(*req, payload...)
+	}
+}
+
+// handleInteraction routes an interactive payload (a modal submission,
+// button click or shortcut) to its handler. Every payload is acknowledged
+// exactly once, before handleInteraction returns; view submissions are
+// acknowledged by their handler since the acknowledgement can carry
+// validation errors back to the modal.
+func (b *bot) handleInteraction(ack ackFunc, callback slack.InteractionCallback) {
+	slog.Info("Event received", "event_type", socketmode.EventTypeInteractive, "interaction_type", callback.Type, "user_id", callback.User.ID)
+	callback.Team.ID = b.workspaces.installation(callback.Enterprise.ID, callback.Team.ID)
+	if _, ok := b.workspaces.client(callback.Team.ID); !ok {
+		ack()
+		slog.Warn("Ignored interaction from unknown workspace", "interaction_type", callback.Type, "user_id", callback.User.ID, "team_id", callback.Team.ID)
+		return
+	}
+
+	switch callback.Type {
+	case slack.InteractionTypeViewSubmission:
+		b.handleViewSubmission(ack, callback)
+	case slack.InteractionTypeBlockActions:
+		ack()
+		b.handleBlockActions(callback)
+	case slack.InteractionTypeShortcut, slack.InteractionTypeMessageAction:
+		ack()
+		b.handleShortcut(callback)
+	default:
+		ack()
+		slog.Debug("Ignored unsupported interaction", "type", callback.Type)
+	}
+}
+
+func (b *bot) handleViewSubmission(ack ackFunc, callback slack.InteractionCallback) {
+	switch callback.View.CallbackID {
+	case shareModalCallbackID:
+		// The form may have been opened before /share was turned off.
+		if b.shareDisabled("/share", "share", callback.User.ID) {
+			ack(slack.NewErrorsViewSubmissionResponse(map[string]string{shareSecretBlockID: disabledMessage("/share")}))
+			return
+		}
+		if !b.shareLimiter.Allow(callback.User.ID) {
+			slog.Warn("Share rate limit exceeded", "event", "share", "user_id", callback.User.ID)
+			sharesTotal.WithLabelValues(outcomeDenied).Inc()
+			ack(slack.NewErrorsViewSubmissionResponse(map[string]string{shareSecretBlockID: rateLimitedMessage}))
+			return
+		}
+
+		args, file, fieldErrs := parseShareSubmission(callback, b.cfg)
+		if fieldErrs != nil {
+			ack(slack.NewErrorsViewSubmissionResponse(fieldErrs))
+			return
+		}
+		ack()
+
+		channelID, responseURL := parseModalMetadata(callback.View.PrivateMetadata)
+		share := shareRequest{
+			shareArgs:   args,
+			teamID:      callback.Team.ID,
+			channelID:   channelID,
+			userID:      callback.User.ID,
+			userName:    callback.User.Name,
+			responseURL: responseURL,
+		}
+		b.runHandler("share", share.userID, share.responseURL, func(ctx context.Context) {
+			if file != nil {
+				f, err := downloadSlackFile(b.api(callback.Team.ID), *file, b.cfg.MaxFileBytes)
+				if err != nil {
+					slog.Error("Failed to download shared file", "event", "share", "user_id", callback.User.ID, "file_id", file.ID, "error", err)
+					sendSlackResponse(b.slack, share.responseURL, "Failed to read the uploaded file. Please try again.")
+					return
+				}
+				share.file = f
+			}
+			b.shareSecret(ctx, share)
+		})
+	default:
+		ack()
+		slog.Warn("Unsupported view submission", "callback_id", callback.View.CallbackID, "user_id", callback.User.ID)
+	}
+}
+
+func (b *bot) handleBlockActions(callback slack.InteractionCallback) {
+	for _, action := range callback.ActionCallback.BlockActions {
+		switch action.ActionID {
+		case confirmShareActionID, cancelShareActionID:
+			b.handleShareConfirmation(callback, action)
+		case homeRevokeActionID, homePageActionID:
+			b.handleHomeAction(callback, action)
+		case reshareDeleteActionID:
+			b.handleReshareDelete(callback, action)
+		case adminRevokeActionID, adminCancelActionID:
+			b.handleAdminAction(callback, action)
+		default:
+			slog.Warn("Unsupported block action", "action_id", action.ActionID, "user_id", callback.User.ID)
+		}
+	}
+}
+
+func (b *bot) handleShortcut(callback slack.InteractionCallback) {
+	switch callback.CallbackID {
+	case reshareShortcutID:
+		b.handleReshareShortcut(callback)
+	default:
+		slog.Warn("Unsupported shortcut", "callback_id", callback.CallbackID, "user_id", callback.User.ID)
+	}
+}
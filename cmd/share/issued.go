@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// issuedTokens remembers the accessors of the recipient tokens this process
+// has issued and not yet revoked, so that REVOKE_ON_SHUTDOWN can revoke them
+// all on exit and the reconciler can check them against their secrets.
+type issuedTokens struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	accessors map[string]issuedToken
+}
+
+// issuedToken is what is remembered about an issued token.
+type issuedToken struct {
+	// expires is when the token expires. Tokens issued without a TTL never
+	// do and are recorded with the zero time.
+	expires time.Time
+	// teamID and secretID identify the secret the token reads.
+	teamID   string
+	secretID string
+}
+
+func (tok issuedToken) live(now time.Time) bool {
+	return tok.expires.IsZero() || now.Before(tok.expires)
+}
+
+func newIssuedTokens() *issuedTokens {
+	return &issuedTokens{now: time.Now, accessors: make(map[string]issuedToken)}
+}
+
+// add records a token issued for ttl to read secretID in teamID's
+// workspace, forgetting any that have since expired.
+func (t *issuedTokens) add(accessor, teamID, secretID string, ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for a, tok := range t.accessors {
+		if !tok.live(now) {
+			delete(t.accessors, a)
+		}
+	}
+	tok := issuedToken{teamID: teamID, secretID: secretID}
+	if ttl > 0 {
+		tok.expires = now.Add(ttl)
+	}
+	t.accessors[accessor] = tok
+}
+
+// live returns the tokens that have not yet expired, keyed by accessor.
+func (t *issuedTokens) live() map[string]issuedToken {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	live := make(map[string]issuedToken, len(t.accessors))
+	for a, tok := range t.accessors {
+		if tok.live(now) {
+			live[a] = tok
+		}
+	}
+	return live
+}
+
+// remove forgets a token that has been revoked.
+func (t *issuedTokens) remove(accessor string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.accessors, accessor)
+}
+
+// revokeAll revokes every token that has not yet expired and returns how many
+// were revoked. It carries on past failures, joining their errors.
+func (t *issuedTokens) revokeAll(ctx context.Context, tokens TokenCreator) (int, error) {
+	revoked := 0
+	var errs []error
+	for a := range t.live() {
+		if err := revokeTokenAccessor(ctx, tokens, a); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t.remove(a)
+		revoked++
+	}
+	return revoked, errors.Join(errs...)
+}
+
+// revokeIssuedTokens revokes the tokens the bot has issued, for
+// REVOKE_ON_SHUTDOWN. The secrets stay in Vault until the sweep deletes them.
+func (b *bot) revokeIssuedTokens() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	revoked, err := b.issued.revokeAll(ctx, b.tokens)
+	if err != nil {
+		slog.Error("Failed to revoke some issued tokens on shutdown", "event", "shutdown", "revoked", revoked, "error", err)
+		return
+	}
+	slog.Info("Revoked issued tokens on shutdown", "event", "shutdown", "revoked", revoked)
+}
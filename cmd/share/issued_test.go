@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestIssuedTokensRevokeAll(t *testing.T) {
+	now := time.Unix(0, 0)
+	issued := newIssuedTokens()
+	issued.now = func() time.Time { return now }
+
+	issued.add("short", "", "secret-1", time.Minute)
+	issued.add("long", "", "secret-2", time.Hour)
+	issued.add("unlimited", "", "secret-3", 0)
+	issued.add("revoked", "", "secret-4", time.Hour)
+	issued.remove("revoked")
+
+	now = now.Add(30 * time.Minute)
+	tokens := &fakeTokenCreator{}
+	revoked, err := issued.revokeAll(context.Background(), tokens)
+	if err != nil {
+		t.Fatalf("revokeAll() error = %v", err)
+	}
+	slices.Sort(tokens.revoked)
+	if want := []string{"long", "unlimited"}; revoked != 2 || !slices.Equal(tokens.revoked, want) {
+		t.Errorf("revokeAll() = %d, revoked %q, want %q", revoked, tokens.revoked, want)
+	}
+
+	if revoked, err := issued.revokeAll(context.Background(), tokens); err != nil || revoked != 0 {
+		t.Errorf("second revokeAll() = %d, %v, want nothing left to revoke", revoked, err)
+	}
+}
+
+func TestShareTracksIssuedToken(t *testing.T) {
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, newFakeSecretStore(), tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	b.revokeIssuedTokens()
+
+	if !slices.Equal(tokens.revoked, []string{"accessor-1"}) {
+		t.Errorf("revoked on shutdown = %q, want the share's token", tokens.revoked)
+	}
+}
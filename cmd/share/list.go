@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// listPageSize is how many secrets /list shows at a time.
+const listPageSize = 10
+
+type listedSecret struct {
+	id   string
+	meta secretMetadata
+}
+
+// handleListCommand shows the caller the secrets they have shared that can
+// still be retrieved. Index entries for secrets that are gone are pruned.
+func (b *bot) handleListCommand(ctx context.Context, cmd slack.SlashCommand) {
+	page := 1
+	if v := strings.TrimSpace(cmd.Text); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Invalid page %q. Usage: `/list [page]`", v))
+			return
+		}
+		page = n
+	}
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	now := time.Now()
+	live, err := b.activeSecrets(ctx, cmd.TeamID, cmd.UserID, now)
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Failed to read secret index from Vault", storeFailure("list your secrets", err), "event", "list", "user_id", cmd.UserID)
+		return
+	}
+
+	sendSlackResponse(b.slack, cmd.ResponseURL, formatSecretList(live, page, now))
+}
+
+// activeSecrets returns the secrets userID has shared in teamID's workspace
+// that can still be retrieved, oldest first, pruning index entries for
+// secrets that are gone.
+func (b *bot) activeSecrets(ctx context.Context, teamID, userID string, now time.Time) ([]listedSecret, error) {
+	paths := b.cfg.kvPaths(teamID)
+	ids, err := readSecretIndex(ctx, b.secrets, paths, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var live []listedSecret
+	var gone []string
+	for _, id := range ids {
+		meta, err := readSecretMetadata(ctx, b.secrets, paths, id)
+		switch {
+		case errors.Is(err, errSecretNotFound), err == nil && meta.expired(now):
+			gone = append(gone, id)
+		case err != nil:
+			slog.Error("Failed to read secret metadata from Vault", "event", "list", "secret_id", id, "user_id", userID, "error", err)
+		default:
+			live = append(live, listedSecret{id: id, meta: meta})
+		}
+	}
+	if len(gone) > 0 {
+		err := b.updateIndex(ctx, teamID, userID, func(ids []string) []string {
+			return slices.DeleteFunc(ids, func(id string) bool { return slices.Contains(gone, id) })
+		})
+		if err != nil {
+			slog.Error("Failed to prune secret index", "event", "list", "user_id", userID, "error", err)
+		}
+	}
+	return live, nil
+}
+
+// formatSecretList renders one page of secrets, newest first.
+func formatSecretList(secrets []listedSecret, page int, now time.Time) string {
+	if len(secrets) == 0 {
+		return "You have no active shared secrets."
+	}
+	pages := (len(secrets) + listPageSize - 1) / listPageSize
+	if page > pages {
+		return fmt.Sprintf("There is no page %d. You have %d %s of active secrets.", page, pages, plural(pages, "page", "pages"))
+	}
+
+	newest := slices.Clone(secrets)
+	slices.Reverse(newest)
+	start := (page - 1) * listPageSize
+	end := min(start+listPageSize, len(newest))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*Your active secrets* (%d, page %d of %d)\n", len(secrets), page, pages)
+	for _, s := range newest[start:end] {
+		fmt.Fprintf(&sb, "• %s", describeSecret(s.id, s.meta.Label))
+		if !s.meta.CreatedAt.IsZero() {
+			fmt.Fprintf(&sb, " shared %s", formatSlackDate(s.meta.CreatedAt))
+		}
+		fmt.Fprintf(&sb, ", expires in %s, %s\n", formatRemaining(s.meta.ExpiresAt.Sub(now)), formatUsesLeft(s.meta.UsesRemaining))
+	}
+	if page < pages {
+		fmt.Fprintf(&sb, "Run `/list %d` for more.", page+1)
+	}
+	return sb.String()
+}
+
+// describeSecret renders a secret's ID for a Slack message, followed by its
+// label when it has one.
+func describeSecret(id, label string) string {
+	if label == "" {
+		return fmt.Sprintf("`%s`", id)
+	}
+	return fmt.Sprintf("`%s` (%s)", id, escapeSlackText(label))
+}
+
+// slackTextEscaper escapes the characters Slack treats as markup, so that
+// user-supplied text cannot mention people or channels.
+var slackTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeSlackText(s string) string {
+	return slackTextEscaper.Replace(s)
+}
+
+// formatRemaining renders the time left on a secret to the minute.
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "under a minute"
+	}
+	return formatDuration(d)
+}
+
+// formatUsesLeft describes the remaining uses, where zero means unlimited.
+func formatUsesLeft(uses int) string {
+	if uses == 0 {
+		return "unlimited uses"
+	}
+	return fmt.Sprintf("%d %s left", uses, plural(uses, "use", "uses"))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
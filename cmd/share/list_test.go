@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestHandleListCommand(t *testing.T) {
+	store := newFakeSecretStore()
+	b, responseURL, replies := newTestBot(t, store, &fakeTokenCreator{})
+
+	for _, text := range []string{"first", "--uses 3 second"} {
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U123", ResponseURL: responseURL})
+	}
+	ids, err := readSecretIndex(context.Background(), store, b.cfg.kvPaths(""), "U123")
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("index = %q, %v, want two IDs", ids, err)
+	}
+
+	// The first secret was retrieved, so its metadata is gone.
+	store.DeleteWithContext(context.Background(), b.cfg.kvPaths("").metadata(ids[0]))
+
+	b.handleListCommand(context.Background(), slack.SlashCommand{Command: "/list", UserID: "U123", ResponseURL: responseURL})
+	got := replies()
+	list := got[len(got)-1]
+	if strings.Contains(list, ids[0]) || !strings.Contains(list, ids[1]) || !strings.Contains(list, "3 uses left") {
+		t.Errorf("list = %q, want only %s with 3 uses left", list, ids[1])
+	}
+	if ids, _ := readSecretIndex(context.Background(), store, b.cfg.kvPaths(""), "U123"); len(ids) != 1 {
+		t.Errorf("index after list = %q, want the retrieved secret pruned", ids)
+	}
+}
+
+func TestFormatSecretListPages(t *testing.T) {
+	now := time.Now()
+	var secrets []listedSecret
+	for i := range listPageSize + 1 {
+		secrets = append(secrets, listedSecret{id: fmt.Sprintf("secret-%d", i), meta: secretMetadata{ExpiresAt: now.Add(time.Hour), UsesRemaining: 1}})
+	}
+
+	first := formatSecretList(secrets, 1, now)
+	if !strings.Contains(first, fmt.Sprintf("secret-%d", listPageSize)) || strings.Contains(first, "`secret-0`") || !strings.Contains(first, "/list 2") {
+		t.Errorf("page 1 = %q, want the newest %d secrets and a pointer to page 2", first, listPageSize)
+	}
+	second := formatSecretList(secrets, 2, now)
+	if !strings.Contains(second, "`secret-0`") || strings.Contains(second, "/list 3") {
+		t.Errorf("page 2 = %q, want only the oldest secret", second)
+	}
+	if got := formatSecretList(secrets, 3, now); !strings.Contains(got, "no page 3") {
+		t.Errorf("page 3 = %q", got)
+	}
+}
+
+func TestShareLabel(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--label \"prod\n<!channel>\u202e db\" hunter2", UserID: "U123", ResponseURL: responseURL})
+	if len(tokens.created) != 1 {
+		t.Fatalf("tokens created = %d, want 1", len(tokens.created))
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+	if meta, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), id); err != nil || meta.Label != "prod <!channel> db" {
+		t.Fatalf("metadata = %+v, %v, want the cleaned up label", meta, err)
+	}
+
+	want := "`" + id + "` (prod &lt;!channel&gt; db)"
+	b.handleListCommand(context.Background(), slack.SlashCommand{Command: "/list", UserID: "U123", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], want) {
+		t.Errorf("list = %q, want the escaped label", got[len(got)-1])
+	}
+	b.handleRevokeCommand(context.Background(), slack.SlashCommand{Command: "/revoke", Text: id, UserID: "U123", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], want+" has been revoked") {
+		t.Errorf("revoke reply = %q, want the label", got[len(got)-1])
+	}
+
+	for _, text := range []string{"--label \"\u200b \" hunter2", "--label " + strings.Repeat("x", maxLabelLength+1) + " hunter2"} {
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U123", ResponseURL: responseURL})
+	}
+	if len(tokens.created) != 1 {
+		t.Errorf("tokens created = %d, want empty and long labels refused", len(tokens.created))
+	}
+}
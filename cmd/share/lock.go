@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// secretLockTTL is how long a secret's lock outlives a bot that stopped while
+// holding it. Locks are held for a few Vault round trips, each bounded by
+// VAULT_TIMEOUT.
+const secretLockTTL = time.Minute
+
+// secretLockPoll is how often a lock held by someone else is tried again.
+const secretLockPoll = 10 * time.Millisecond
+
+// lockSecret takes the lock on secretID's metadata, waiting for as long as
+// ctx allows, and returns the function that releases it. Every change to a
+// secret's metadata and every deletion takes it, so that a retrieval
+// spending a use, a command updating the secret and a deletion cannot undo
+// one another. Vault's KV metadata has no check-and-set, so the locks are
+// kept in state instead, where replicas sharing a redis STATE_BACKEND take
+// the same ones.
+func lockSecret(ctx context.Context, state StateStore, paths kvPaths, secretID string) (func(), error) {
+	key := "secretlock:" + paths.metadata(secretID)
+	for {
+		ok, err := state.SetNX(ctx, key, nil, secretLockTTL)
+		if err != nil {
+			return nil, fmt.Errorf("locking secret: %w", err)
+		}
+		if ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("locking secret: %w", ctx.Err())
+		case <-time.After(secretLockPoll):
+		}
+	}
+	return func() {
+		if _, _, err := state.Take(context.WithoutCancel(ctx), key); err != nil {
+			slog.Error("Failed to unlock secret", "secret_id", secretID, "error", err)
+		}
+	}, nil
+}
+
+// deleteSecretLocked deletes secretID, as deleteSecret does, under its lock.
+func deleteSecretLocked(ctx context.Context, state StateStore, store SecretStore, paths kvPaths, secretID string) error {
+	unlock, err := lockSecret(ctx, state, paths, secretID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return deleteSecret(ctx, store, paths, secretID)
+}
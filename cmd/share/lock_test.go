@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLockSecret(t *testing.T) {
+	state := newMemoryState()
+	paths := (&Config{VaultSecretsMount: defaultSecretsMount, VaultKVVersion: defaultKVVersion}).kvPaths("")
+
+	unlock, err := lockSecret(context.Background(), state, paths, "secret-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Other secrets are not held up.
+	unlockOther, err := lockSecret(context.Background(), state, paths, "secret-2")
+	if err != nil {
+		t.Fatalf("locking another secret error = %v", err)
+	}
+	unlockOther()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*secretLockPoll)
+	defer cancel()
+	if _, err := lockSecret(ctx, state, paths, "secret-1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("locking a held secret error = %v, want it to wait until the deadline", err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := lockSecret(context.Background(), state, paths, "secret-1")
+		if err == nil {
+			unlock()
+		}
+		close(locked)
+	}()
+	time.Sleep(2 * secretLockPoll)
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock was not taken after it was released")
+	}
+}
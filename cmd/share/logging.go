@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"log/slog"
+	"regexp"
+
+	"github.com/slack-go/slack"
+)
+
+// sanitizedCommand is a loggable view of a slash command. It omits the
+// command text, which for /share is the secret itself, and the response URL,
+// which lets anyone holding it post as the bot.
+type sanitizedCommand slack.SlashCommand
+
+func (c sanitizedCommand) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("name", c.Command),
+		slog.String("user_id", c.UserID),
+		slog.String("user_name", c.UserName),
+		slog.String("team_id", c.TeamID),
+		slog.String("enterprise_id", c.EnterpriseID),
+		slog.String("channel_id", c.ChannelID),
+		slog.Int("text_length", len(c.Text)),
+	)
+}
+
+var (
+	// slackLogJSONFields matches the JSON fields of Slack payloads that can
+	// hold a secret, a token or a response URL: message and block text, modal
+	// input values and private metadata.
+	slackLogJSONFields = regexp.MustCompile(`"(text|value|initial_value|response_url|private_metadata|token)"\s*:\s*"(?:[^"\\]|\\.)*"`)
+	// slackLogFormFields matches the same in form-encoded requests.
+	slackLogFormFields = regexp.MustCompile(`(^|[&?\s])(text|blocks|response_url|token)=[^&\s]*`)
+)
+
+// slackLogger is the logger of the Slack clients when SLACK_DEBUG is on. The
+// debug log includes raw payloads, such as the /share command and the share
+// reply with its token, so their sensitive fields are redacted first.
+type slackLogger struct {
+	logger *log.Logger
+}
+
+// newSlackLogger writes the Slack clients' logs to handler. They are logged
+// at info, since SLACK_DEBUG turning them on is independent of LOG_LEVEL.
+func newSlackLogger(handler slog.Handler) slackLogger {
+	return slackLogger{logger: slog.NewLogLogger(handler.WithAttrs([]slog.Attr{slog.String("component", "slack")}), slog.LevelInfo)}
+}
+
+func (l slackLogger) Output(calldepth int, s string) error {
+	return l.logger.Output(calldepth+1, redactSlackLog(s))
+}
+
+// redactSlackLog replaces the values of sensitive fields in s.
+func redactSlackLog(s string) string {
+	s = slackLogJSONFields.ReplaceAllString(s, `"$1":"[redacted]"`)
+	return slackLogFormFields.ReplaceAllString(s, `$1$2=[redacted]`)
+}
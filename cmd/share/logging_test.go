@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestSanitizedCommandOmitsText(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	cmd := slack.SlashCommand{
+		Command:     "/share",
+		Text:        "hunter2-super-secret",
+		UserID:      "U123",
+		ResponseURL: "https://hooks.slack.com/commands/T1/2/3",
+	}
+	logger.Info("Event received", "command", sanitizedCommand(cmd))
+
+	out := buf.String()
+	if strings.Contains(out, cmd.Text) || strings.Contains(out, cmd.ResponseURL) {
+		t.Errorf("log output leaks command text or response URL: %s", out)
+	}
+	if !strings.Contains(out, `"user_id":"U123"`) {
+		t.Errorf("log output missing user ID: %s", out)
+	}
+}
+
+func TestHandleSocketModeDoesNotLogSecret(t *testing.T) {
+	const secret = "correct-horse-battery-staple"
+
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&lockedWriter{w: &buf, mu: &mu}, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	// Vault fails every request so the share flow exercises its error logging.
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["boom"]}`, http.StatusInternalServerError)
+	}))
+	defer vault.Close()
+	responded := make(chan struct{}, 2)
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+		responded <- struct{}{}
+	}))
+	defer slackAPI.Close()
+
+	vaultClient, err := newVaultClient(&Config{VaultAddr: vault.URL, VaultToken: "hvs.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultClient.SetMaxRetries(0)
+	client := socketmode.New(slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses}, RetrievalAddr: defaultRetrievalAddr, ShareRateLimit: defaultShareRateLimit}
+	b := newBot(client, newWorkspaces(cfg, &client.Client), vaultClient, cfg, multiAuditLogger(nil), newMemoryState())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.handleSocketMode(ctx)
+	}()
+
+	// Both commands reply, so once two responses have arrived both have been
+	// logged.
+	unknown := slack.SlashCommand{Command: "/unknown", Text: secret, ResponseURL: slackAPI.URL + "/response"}
+	client.Events <- socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: unknown, Request: &socketmode.Request{EnvelopeID: "1"}}
+	cmd := slack.SlashCommand{Command: "/share", Text: secret, UserID: "U123", ResponseURL: slackAPI.URL + "/response"}
+	client.Events <- socketmode.Event{Type: socketmode.EventTypeSlashCommand, Data: cmd, Request: &socketmode.Request{EnvelopeID: "2"}}
+	<-responded
+	<-responded
+	cancel()
+	<-done
+	b.inflight.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if buf.Len() == 0 {
+		t.Fatal("no log output captured")
+	}
+	if strings.Contains(buf.String(), secret) {
+		t.Errorf("log output contains the secret:\n%s", buf.String())
+	}
+}
+
+type lockedWriter struct {
+	w  *bytes.Buffer
+	mu *sync.Mutex
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func TestRedactSlackLog(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{
+			`Received WebSocket message: {"payload":{"command":"/share","text":"hunter2 \"quoted\"","response_url":"https://hooks.slack.com/commands/T1/2/x","user_id":"U1"}}`,
+			`Received WebSocket message: {"payload":{"command":"/share","text":"[redacted]","response_url":"[redacted]","user_id":"U1"}}`,
+		},
+		{
+			`{"view":{"private_metadata":"https://hooks.slack.com/x","state":{"values":{"secret":{"input":{"type":"plain_text_input","value":"hunter2"}}}}}}`,
+			`{"view":{"private_metadata":"[redacted]","state":{"values":{"secret":{"input":{"type":"plain_text_input","value":"[redacted]"}}}}}}`,
+		},
+		{
+			`Sending request: token=xoxb-1&channel=U1&text=hunter2&blocks=%5B%5D`,
+			`Sending request: token=[redacted]&channel=U1&text=[redacted]&blocks=[redacted]`,
+		},
+		{"WebSocket connection succeeded on try 1", "WebSocket connection succeeded on try 1"},
+	}
+	for _, tt := range tests {
+		if got := redactSlackLog(tt.in); got != tt.want {
+			t.Errorf("redactSlackLog(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
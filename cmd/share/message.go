@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// defaultShareMessage is the reply to a share when no SHARE_MESSAGE_TEMPLATE
+// is configured.
+const defaultShareMessage = "{{.Subject}} been securely shared, is valid for {{.TTL}} and can be retrieved {{.Uses}}. Open this link to view it:\n{{.URL}}\n{{with .Instructions}}\n{{.}}\n{{end}}To destroy it early, run `/revoke {{.SecretID}}`."
+
+var defaultShareTemplate = template.Must(template.New("share").Parse(defaultShareMessage))
+
+// shareMessageData is what a share message template is executed with.
+type shareMessageData struct {
+	// Subject is "Your secret has", or what was shared in its place, such
+	// as "Your file `id_rsa` has".
+	Subject  string
+	SecretID string
+	// URL is the retrieval page link.
+	URL string
+	// TTL and Uses are how long the secret is valid for, such as "1h", and
+	// how many times it can be retrieved, such as "once".
+	TTL  string
+	Uses string
+	// Token and VaultURL are the recipient's Vault token and the URL to
+	// read the secret with it directly.
+	Token    string
+	VaultURL string
+	// Instructions tell the recipient how to read the secret from a
+	// terminal in the --format the sharer chose, or are empty for
+	// --format url.
+	Instructions string
+}
+
+// loadShareTemplate parses text as a share message template and checks that
+// it executes against sample data, so that a template naming a field that
+// does not exist is caught at startup rather than on the first share.
+func loadShareTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("share").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("is not a valid template: %w", err)
+	}
+	sample := shareMessageData{
+		Subject:  "Your secret has",
+		SecretID: "secret-1",
+		URL:      "https://hush.example.com/s/secret-1",
+		TTL:      "1h",
+		Uses:     "once",
+		Token:    "hvs.token",
+		VaultURL: "https://vault.example.com/v1/secrets/data/shared/secret-1",
+
+		Instructions: "Or from a terminal: ...",
+	}
+	if err := tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("cannot be rendered: %w", err)
+	}
+	return tmpl, nil
+}
+
+// shareTemplateEnv loads the share message template from
+// SHARE_MESSAGE_TEMPLATE or the file named by SHARE_MESSAGE_TEMPLATE_FILE,
+// returning nil if neither is set.
+func shareTemplateEnv(errs *[]error) *template.Template {
+	text, file := os.Getenv("SHARE_MESSAGE_TEMPLATE"), os.Getenv("SHARE_MESSAGE_TEMPLATE_FILE")
+	name := "SHARE_MESSAGE_TEMPLATE"
+	switch {
+	case text != "" && file != "":
+		*errs = append(*errs, fmt.Errorf("SHARE_MESSAGE_TEMPLATE and SHARE_MESSAGE_TEMPLATE_FILE cannot both be set"))
+		return nil
+	case file != "":
+		name = "SHARE_MESSAGE_TEMPLATE_FILE"
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("%s cannot be read: %w", name, err))
+			return nil
+		}
+		text = string(raw)
+	case text == "":
+		return nil
+	}
+	tmpl, err := loadShareTemplate(text)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("%s %w", name, err))
+	}
+	return tmpl
+}
+
+// shareMessage renders the reply to a share with cfg's template, or the
+// default one.
+func shareMessage(cfg *Config, data shareMessageData) (string, error) {
+	tmpl := cfg.ShareTemplate
+	if tmpl == nil {
+		tmpl = defaultShareTemplate
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestLoadShareTemplate(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"Secret {{.SecretID}}: {{.URL}} ({{.TTL}}, {{.Uses}})", ""},
+		{"{{.URL", "not a valid template"},
+		{"{{.Password}}", "cannot be rendered"},
+	}
+	for _, tt := range tests {
+		_, err := loadShareTemplate(tt.text)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("loadShareTemplate(%q) error = %v", tt.text, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("loadShareTemplate(%q) error = %v, want it to mention %q", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigShareTemplate(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SHARE_MESSAGE_TEMPLATE", "Open {{.URL}}")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got, err := shareMessage(cfg, shareMessageData{URL: "https://hush/s/secret-1"}); err != nil || got != "Open https://hush/s/secret-1" {
+		t.Errorf("shareMessage() = %q, %v", got, err)
+	}
+
+	t.Setenv("SHARE_MESSAGE_TEMPLATE", "{{.Nope}}")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "SHARE_MESSAGE_TEMPLATE") {
+		t.Errorf("LoadConfig() with a bad template error = %v, want it to name SHARE_MESSAGE_TEMPLATE", err)
+	}
+}
+
+func TestShareCustomMessage(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	tmpl, err := loadShareTemplate("Shared {{.SecretID}} for {{.TTL}}, {{.Uses}}: {{.URL}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.cfg.ShareTemplate = tmpl
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--ttl 30m hunter2", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "Shared secret-") || !strings.Contains(got[0], "for 30m, once: http://") {
+		t.Errorf("replies = %q, want the custom message", got)
+	}
+	if strings.Contains(got[0], "curl") {
+		t.Errorf("reply %q still contains the default curl command", got[0])
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outcome label values. Metrics are labelled by what happened, never by
+// anything derived from a secret.
+const (
+	outcomeSuccess  = "success"
+	outcomeError    = "error"
+	outcomeNotFound = "not_found"
+	outcomeDenied   = "denied"
+)
+
+var (
+	sharesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_shares_total",
+		Help: "Secrets shared, by outcome.",
+	}, []string{"outcome"})
+	retrievalsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_retrievals_total",
+		Help: "Secret retrievals through the retrieval server, by endpoint and outcome.",
+	}, []string{"endpoint", "outcome"})
+	revocationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_revocations_total",
+		Help: "Secret revocations, by outcome.",
+	}, []string{"outcome"})
+	extensionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_extensions_total",
+		Help: "Secret TTL extensions, by outcome.",
+	}, []string{"outcome"})
+	statusChecksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_status_checks_total",
+		Help: "Secret status checks through the retrieval server, by outcome.",
+	}, []string{"outcome"})
+	transfersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_transfers_total",
+		Help: "Secret ownership transfers, by outcome.",
+	}, []string{"outcome"})
+	rotationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_rotations_total",
+		Help: "Secret rotations, by outcome.",
+	}, []string{"outcome"})
+	emailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_emails_total",
+		Help: "Retrieval links sent by email, by outcome.",
+	}, []string{"outcome"})
+	sweptTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_swept_secrets_total",
+		Help: "Secrets deleted by the expiry sweeper, by outcome.",
+	}, []string{"outcome"})
+	reconciledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_reconciled_orphans_total",
+		Help: "Orphaned tokens revoked and orphaned secrets deleted by the reconciler, by kind and outcome.",
+	}, []string{"kind", "outcome"})
+	vaultBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hush_vault_breaker_state",
+		Help: "State of the Vault circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+	vaultBreakerRejections = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hush_vault_breaker_rejections_total",
+		Help: "Vault operations failed fast because the circuit breaker was open.",
+	})
+	inflightHandlers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "hush_inflight_handlers",
+		Help: "Command handlers currently running.",
+	})
+	busyTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hush_busy_rejections_total",
+		Help: "Commands turned away because every handler slot was busy.",
+	})
+	commandTimeoutsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "hush_command_timeouts_total",
+		Help: "Commands stopped for running past COMMAND_TIMEOUT.",
+	})
+	slackRateLimitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "hush_slack_rate_limits_total",
+		Help: "Posts to Slack that were rate limited, by whether they were retried or dropped.",
+	}, []string{"outcome"})
+	vaultRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "hush_vault_request_duration_seconds",
+		Help:    "Latency of Vault requests, by operation and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+)
+
+// newMetricsServer serves the Prometheus metrics on cfg.MetricsAddr.
+func newMetricsServer(cfg *Config) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.Handler())
+	return &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+}
+
+func errOutcome(err error) string {
+	if err != nil {
+		return outcomeError
+	}
+	return outcomeSuccess
+}
+
+// observeVault starts timing and tracing a Vault request to path. Calling the
+// function it returns with the request's error records its latency and ends
+// its span.
+func observeVault(ctx context.Context, operation, path string) (context.Context, func(error)) {
+	start := time.Now()
+	attrs := []attribute.KeyValue{attrOperation.String(operation)}
+	if path != "" {
+		attrs = append(attrs, attrPath.String(path))
+	}
+	ctx, span := tracer.Start(ctx, "vault."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		outcome := errOutcome(err)
+		vaultRequestDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+		if err != nil {
+			span.RecordError(err)
+		}
+		endSpan(span, outcome)
+	}
+}
+
+// instrumentedStore is a SecretStore that records request latency and traces
+// each request.
+type instrumentedStore struct {
+	SecretStore
+}
+
+func (s instrumentedStore) ReadWithContext(ctx context.Context, path string) (*api.Secret, error) {
+	ctx, done := observeVault(ctx, "read", path)
+	resp, err := s.SecretStore.ReadWithContext(ctx, path)
+	done(err)
+	return resp, err
+}
+
+func (s instrumentedStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	ctx, done := observeVault(ctx, "write", path)
+	resp, err := s.SecretStore.WriteWithContext(ctx, path, data)
+	done(err)
+	return resp, err
+}
+
+func (s instrumentedStore) DeleteWithContext(ctx context.Context, path string) (*api.Secret, error) {
+	ctx, done := observeVault(ctx, "delete", path)
+	resp, err := s.SecretStore.DeleteWithContext(ctx, path)
+	done(err)
+	return resp, err
+}
+
+func (s instrumentedStore) ListWithContext(ctx context.Context, path string) (*api.Secret, error) {
+	ctx, done := observeVault(ctx, "list", path)
+	resp, err := s.SecretStore.ListWithContext(ctx, path)
+	done(err)
+	return resp, err
+}
+
+// instrumentedTokens is a TokenCreator that records request latency and
+// traces each request.
+type instrumentedTokens struct {
+	TokenCreator
+}
+
+func (t instrumentedTokens) CreateWithContext(ctx context.Context, opts *api.TokenCreateRequest) (*api.Secret, error) {
+	ctx, done := observeVault(ctx, "token_create", "")
+	resp, err := t.TokenCreator.CreateWithContext(ctx, opts)
+	done(err)
+	return resp, err
+}
+
+func (t instrumentedTokens) RevokeAccessorWithContext(ctx context.Context, accessor string) error {
+	ctx, done := observeVault(ctx, "token_revoke", "")
+	err := t.TokenCreator.RevokeAccessorWithContext(ctx, accessor)
+	done(err)
+	return err
+}
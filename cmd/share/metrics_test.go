@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slack-go/slack"
+)
+
+func TestShareMetrics(t *testing.T) {
+	successes := testutil.ToFloat64(sharesTotal.WithLabelValues(outcomeSuccess))
+	failures := testutil.ToFloat64(sharesTotal.WithLabelValues(outcomeError))
+
+	b, responseURL, _ := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	b.tokens = &fakeTokenCreator{createErr: errors.New("denied")}
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+
+	if got := testutil.ToFloat64(sharesTotal.WithLabelValues(outcomeSuccess)) - successes; got != 1 {
+		t.Errorf("successful shares counted = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(sharesTotal.WithLabelValues(outcomeError)) - failures; got != 1 {
+		t.Errorf("failed shares counted = %v, want 1", got)
+	}
+}
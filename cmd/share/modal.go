@@ -0,0 +1,177 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// noNotifyOption and burnOption are the values of the modal checkboxes
+// matching --no-notify and --burn.
+const (
+	noNotifyOption = "no_notify"
+	burnOption     = "burn"
+)
+
+const (
+	shareModalCallbackID = "share_modal"
+
+	shareSecretBlockID = "secret"
+	shareFileBlockID   = "file"
+	shareTTLBlockID    = "ttl"
+	shareUsesBlockID   = "uses"
+	shareNotifyBlockID = "notify"
+	shareBurnBlockID   = "burn"
+	shareToBlockID     = "to"
+	shareInputActionID = "value"
+)
+
+// openShareModal opens a form for the secret and its options. The command's
+// channel and response URL are carried in the view's private metadata so the
+// submission can reply in the conversation /share was run from.
+func (b *bot) openShareModal(cmd slack.SlashCommand) {
+	secretInput := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, "Paste the secret here", false, false), shareInputActionID)
+	secretInput.Multiline = true
+	secretBlock := slack.NewInputBlock(shareSecretBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Secret", false, false), nil, secretInput)
+	secretBlock.Optional = true
+
+	fileBlock := slack.NewInputBlock(shareFileBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Or share a file", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "A key, certificate or .env file of up to "+formatBytes(b.cfg.MaxFileBytes)+".", false, false),
+		slack.NewFileInputBlockElement(shareInputActionID).WithMaxFiles(1))
+	fileBlock.Optional = true
+
+	ttlInput := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, b.cfg.defaultTTL().String(), false, false), shareInputActionID)
+	ttlBlock := slack.NewInputBlock(shareTTLBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Valid for", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "A duration such as 30m or 2h. Defaults to "+formatDuration(b.cfg.defaultTTL())+".", false, false), ttlInput)
+	ttlBlock.Optional = true
+
+	usesInput := slack.NewPlainTextInputBlockElement(slack.NewTextBlockObject(slack.PlainTextType, strconv.Itoa(b.cfg.defaultUses()), false, false), shareInputActionID)
+	usesBlock := slack.NewInputBlock(shareUsesBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Number of retrievals", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "How many times the secret can be retrieved. Defaults to "+strconv.Itoa(b.cfg.defaultUses())+".", false, false), usesInput)
+	usesBlock.Optional = true
+
+	toBlock := slack.NewInputBlock(shareToBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Only these people can open it", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Each gets a personal link by DM. Leave empty for anyone with the link.", false, false),
+		slack.NewOptionsMultiSelectBlockElement(slack.MultiOptTypeUser, slack.NewTextBlockObject(slack.PlainTextType, "Anyone with the link", false, false), shareInputActionID))
+	toBlock.Optional = true
+
+	noNotify := slack.NewOptionBlockObject(noNotifyOption, slack.NewTextBlockObject(slack.PlainTextType, "Don't notify me when it is retrieved", false, false), nil)
+	notifyBlock := slack.NewInputBlock(shareNotifyBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Notifications", false, false), nil,
+		slack.NewCheckboxGroupsBlockElement(shareInputActionID, noNotify))
+	notifyBlock.Optional = true
+
+	burn := slack.NewOptionBlockObject(burnOption, slack.NewTextBlockObject(slack.PlainTextType, "Destroy it as soon as it is viewed", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "It can then only be retrieved once.", false, false))
+	burnBlock := slack.NewInputBlock(shareBurnBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Burn after reading", false, false), nil,
+		slack.NewCheckboxGroupsBlockElement(shareInputActionID, burn))
+	burnBlock.Optional = true
+
+	view := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      shareModalCallbackID,
+		PrivateMetadata: cmd.ChannelID + " " + cmd.ResponseURL,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Share a secret", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Share", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			secretBlock,
+			fileBlock,
+			ttlBlock,
+			usesBlock,
+			toBlock,
+			notifyBlock,
+			burnBlock,
+		}},
+	}
+
+	if _, err := b.api(cmd.TeamID).OpenView(cmd.TriggerID, view); err != nil {
+		slog.Error("Failed to open share modal", "user_id", cmd.UserID, "error", err)
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Failed to open the share form. You can also run `/share [--ttl 30m] [--uses 1] [--no-notify] [--burn] <secret>`.")
+	}
+}
+
+// parseModalMetadata splits the private metadata of a share modal into the
+// channel it was opened from and its response URL. Modals opened before the
+// channel was recorded carry only the URL.
+func parseModalMetadata(metadata string) (channelID, responseURL string) {
+	channelID, responseURL, ok := strings.Cut(metadata, " ")
+	if !ok {
+		return "", metadata
+	}
+	return channelID, responseURL
+}
+
+// parseShareSubmission validates a submitted share modal and returns the
+// options along with the uploaded file, if any. On failure it returns errors
+// keyed by block ID for display next to the offending fields.
+func parseShareSubmission(callback slack.InteractionCallback, cfg *Config) (shareArgs, *slack.File, map[string]string) {
+	args := cfg.defaultArgs()
+	values := callback.View.State.Values
+	fieldErrs := map[string]string{}
+
+	var file *slack.File
+	if files := values[shareFileBlockID][shareInputActionID].Files; len(files) > 0 {
+		file = &files[0]
+	}
+
+	args.secret = values[shareSecretBlockID][shareInputActionID].Value
+	hasText := strings.TrimSpace(args.secret) != ""
+	switch {
+	case hasText && file != nil:
+		fieldErrs[shareFileBlockID] = "Share either a secret or a file, not both."
+	case !hasText && file == nil:
+		fieldErrs[shareSecretBlockID] = "Please provide a secret or a file to share."
+	case file != nil:
+		if err := cfg.checkFileSize(file.Size); err != nil {
+			fieldErrs[shareFileBlockID] = err.Error()
+		}
+	case hasText:
+		fields, err := parseSecretFields(args.secret)
+		if err == nil {
+			err = checkSecretSize(args.secret, cfg)
+		}
+		if err != nil {
+			fieldErrs[shareSecretBlockID] = err.Error()
+		}
+		args.fields = fields
+	}
+	if v := strings.TrimSpace(values[shareTTLBlockID][shareInputActionID].Value); v != "" {
+		ttl, err := parseTTL(v, cfg)
+		if err != nil {
+			fieldErrs[shareTTLBlockID] = err.Error()
+		}
+		args.ttl = ttl
+	}
+	usesValue := strings.TrimSpace(values[shareUsesBlockID][shareInputActionID].Value)
+	if usesValue != "" {
+		uses, err := parseUses(usesValue, cfg)
+		if err != nil {
+			fieldErrs[shareUsesBlockID] = err.Error()
+		}
+		args.uses = uses
+	}
+	args.to = values[shareToBlockID][shareInputActionID].SelectedUsers
+	for _, opt := range values[shareNotifyBlockID][shareInputActionID].SelectedOptions {
+		if opt.Value == noNotifyOption {
+			args.notify = false
+		}
+	}
+	for _, opt := range values[shareBurnBlockID][shareInputActionID].SelectedOptions {
+		if opt.Value == burnOption {
+			args.burn = true
+		}
+	}
+	if args.burn {
+		if usesValue != "" && args.uses != 1 && fieldErrs[shareUsesBlockID] == "" {
+			fieldErrs[shareUsesBlockID] = "Secrets that are destroyed when viewed can only be retrieved once."
+		}
+		args.uses = 1
+	}
+
+	if len(fieldErrs) > 0 {
+		return args, nil, fieldErrs
+	}
+	return args, file, nil
+}
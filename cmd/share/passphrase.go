@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// minPassphraseLength is the fewest characters a --passphrase may have.
+	minPassphraseLength = 4
+	// maxPassphraseBytes is the most bcrypt hashes; it rejects anything
+	// longer rather than silently ignoring the rest.
+	maxPassphraseBytes = 72
+	// passphraseMaxFailures wrong passphrases destroy the secret.
+	passphraseMaxFailures = 5
+)
+
+// errWrongPassphrase is returned when a secret is opened with the wrong
+// passphrase, and errPassphraseLockout when that was the last attempt it
+// had, so that it has been destroyed.
+var (
+	errWrongPassphrase   = errors.New("wrong passphrase")
+	errPassphraseLockout = errors.New("too many wrong passphrases")
+)
+
+// passphraseNote is added to the reply to a share made with --passphrase.
+const passphraseNote = "The link only opens with the passphrase you set. Give it to the recipient separately, such as by phone or in person, never in the same message as the link."
+
+// parsePassphrase checks a --passphrase and returns its bcrypt hash, which
+// is all that is stored.
+func parsePassphrase(value string) (string, error) {
+	if utf8.RuneCountInString(value) < minPassphraseLength {
+		return "", fmt.Errorf("Please give `--passphrase` a word of at least %d characters for the recipient to enter, e.g. `--passphrase tangerine`.", minPassphraseLength)
+	}
+	if len(value) > maxPassphraseBytes {
+		return "", fmt.Errorf("`--passphrase` can be at most %d bytes long.", maxPassphraseBytes)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(value), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hashing passphrase: %w", err)
+	}
+	return string(hash), nil
+}
+
+// checkPassphrase reports whether typed is the passphrase that meta was
+// shared with. A secret without one accepts anything.
+func checkPassphrase(meta secretMetadata, typed string) bool {
+	return meta.PassphraseHash == "" || bcrypt.CompareHashAndPassword([]byte(meta.PassphraseHash), []byte(typed)) == nil
+}
+
+var (
+	passphrasePage = template.Must(template.New("passphrase").Parse(pageHeader + `
+<h1>A secret has been shared with you</h1>
+<p>{{.Notice}} The sender set a passphrase for it, which they should have
+given you separately from the link.</p>
+{{with .Error}}<p><strong>{{.}}</strong></p>
+{{end}}<form method="post">
+<input type="password" name="passphrase" autocomplete="off" autofocus required placeholder="Passphrase">
+<button type="submit">Reveal the secret</button>
+</form>
+` + pageFooter))
+
+	lockedOutPage = template.Must(template.New("locked").Parse(pageHeader + `
+<h1>This secret has been destroyed</h1>
+<p>The wrong passphrase was entered too many times. Ask the sender to share
+it again.</p>
+` + pageFooter))
+)
+
+// passphraseForm is what passphrasePage is executed with.
+type passphraseForm struct {
+	Notice string
+	Error  string
+}
+
+// wrongPassphraseMessage tells the viewer how many attempts at the
+// passphrase meta has left.
+func wrongPassphraseMessage(meta secretMetadata) string {
+	left := passphraseMaxFailures - meta.PassphraseFailures
+	return fmt.Sprintf("That passphrase is not right. The secret is destroyed after %d more wrong %s.", left, plural(left, "attempt", "attempts"))
+}
+
+// notifyLockedOut tells the sharer, in teamID's workspace, that their secret
+// was destroyed after too many wrong passphrases, since someone holding the
+// link may be guessing it.
+func (rs *retrievalServer) notifyLockedOut(teamID, userID, secretID string) {
+	client, ok := rs.workspaces.client(teamID)
+	if userID == "" || !ok {
+		return
+	}
+	text := fmt.Sprintf("Your shared secret `%s` was destroyed after the wrong passphrase was entered %d times. If you did not expect that, the link may have reached someone else; share the secret again with a new link and passphrase.", secretID, passphraseMaxFailures)
+	if err := postSlack(client, "notify", userID, slack.MsgOptionText(text, false)); err != nil {
+		slog.Error("Failed to notify sharer of passphrase lockout", "event", "notify", "secret_id", secretID, "user_id", userID, "error", err)
+	}
+}
+
+// recordPassphraseFailure counts a wrong passphrase for secretID under its
+// lock, destroying it once it has had passphraseMaxFailures, and returns the
+// error to report along with its metadata as updated.
+func (rs *retrievalServer) recordPassphraseFailure(ctx context.Context, paths kvPaths, secretID string) (secretMetadata, error) {
+	unlock, err := lockSecret(ctx, rs.state, paths, secretID)
+	if err != nil {
+		return secretMetadata{}, err
+	}
+	defer unlock()
+	meta, err := readSecretMetadata(ctx, rs.secrets, paths, secretID)
+	if err != nil {
+		return meta, err
+	}
+	meta.PassphraseFailures++
+	if meta.PassphraseFailures >= passphraseMaxFailures {
+		if err := deleteSecret(ctx, rs.secrets, paths, secretID); err != nil {
+			return meta, fmt.Errorf("deleting secret after too many wrong passphrases: %w", err)
+		}
+		return meta, errPassphraseLockout
+	}
+	if err := writeSecretMetadata(ctx, rs.secrets, paths, secretID, meta); err != nil {
+		return meta, fmt.Errorf("counting wrong passphrase: %w", err)
+	}
+	return meta, errWrongPassphrase
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSharePassphrase(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	for _, text := range []string{"--passphrase abc hunter2", "--passphrase tangerine --format vault hunter2"} {
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", ResponseURL: responseURL})
+		if got := replies(); !strings.Contains(got[len(got)-1], "--passphrase") || len(tokens.created) != 0 {
+			t.Fatalf("reply to %q = %q, want it rejected", text, got[len(got)-1])
+		}
+	}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--passphrase tangerine --uses 2 --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if reply := got[len(got)-1]; !strings.Contains(reply, passphraseNote) || strings.Contains(reply, "hvs.recipient") || strings.Contains(reply, "tangerine") {
+		t.Fatalf("reply = %q, want the reminder without the token or the passphrase", reply)
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+	paths := b.cfg.kvPaths("")
+	meta, err := readSecretMetadata(context.Background(), store, paths, id)
+	if err != nil || meta.PassphraseHash == "" || strings.Contains(meta.PassphraseHash, "tangerine") {
+		t.Fatalf("metadata = %+v, %v, want only a hash of the passphrase", meta, err)
+	}
+
+	rs := &retrievalServer{secrets: store, workspaces: b.workspaces, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	open := func(method, passphrase string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/s/"+id, strings.NewReader(url.Values{"passphrase": {passphrase}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return rec
+	}
+
+	if body := open(http.MethodGet, "").Body.String(); !strings.Contains(body, `name="passphrase"`) || strings.Contains(body, "hunter2") {
+		t.Errorf("GET = %q, want the passphrase form without the secret", body)
+	}
+	rec := open(http.MethodPost, "orange")
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "after 4 more wrong attempts") || strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("POST with the wrong passphrase = %d %q, want it refused", rec.Code, rec.Body.String())
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, id); err != nil || meta.UsesRemaining != 2 || meta.PassphraseFailures != 1 {
+		t.Errorf("metadata after a wrong passphrase = %+v, %v, want no use spent and 1 failure", meta, err)
+	}
+	if rec := open(http.MethodPost, "tangerine"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("POST with the passphrase = %d %q, want the secret", rec.Code, rec.Body.String())
+	}
+
+	sent := len(replies())
+	for i := 2; i < passphraseMaxFailures; i++ {
+		if rec := open(http.MethodPost, "orange"); rec.Code != http.StatusForbidden {
+			t.Fatalf("wrong passphrase %d = %d, want 403", i, rec.Code)
+		}
+	}
+	if rec := open(http.MethodPost, "orange"); rec.Code != http.StatusGone {
+		t.Errorf("last wrong passphrase = %d, want 410", rec.Code)
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+		t.Errorf("read after the lockout = %v, want the secret destroyed", err)
+	}
+	if got := len(replies()) - sent; got != 1 {
+		t.Errorf("messages sent on the lockout = %d, want the sharer told", got)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SharePolicy holds the limits every share is held to, and the defaults it
+// gets for what it does not set, whichever way it was made: the slash
+// command, the modal, the command line, email, /generate or a rotation.
+// Config embeds it, loaded from the environment. A zero limit, as in
+// hand-built configs, is not enforced.
+type SharePolicy struct {
+	// MaxTTL is the longest TTL a user may request with --ttl, and
+	// DefaultTTL the TTL of a share that does not ask for one.
+	MaxTTL     time.Duration
+	DefaultTTL time.Duration
+	// MaxUses is the most retrievals a user may request with --uses, and
+	// DefaultUses the number a share allows when it does not say.
+	MaxUses     int
+	DefaultUses int
+	// AllowUnlimitedUses permits --uses 0.
+	AllowUnlimitedUses bool
+	// MaxFileBytes is the largest file that may be shared.
+	MaxFileBytes int
+	// MaxSecretBytes is the largest secret that may be typed or pasted in.
+	MaxSecretBytes int
+}
+
+// defaultArgs returns the options of a share that sets none.
+func (p SharePolicy) defaultArgs() shareArgs {
+	return shareArgs{ttl: p.defaultTTL(), uses: p.defaultUses(), notify: true}
+}
+
+func (p SharePolicy) defaultTTL() time.Duration {
+	if p.DefaultTTL > 0 {
+		return p.DefaultTTL
+	}
+	return defaultTokenTTL
+}
+
+func (p SharePolicy) defaultUses() int {
+	if p.DefaultUses > 0 {
+		return p.DefaultUses
+	}
+	return defaultTokenUses
+}
+
+var errBurnUses = errors.New("`--burn` secrets can only be retrieved once, so it cannot be combined with `--uses`.")
+
+// Validate reports why req breaks the policy, or nil if it does not. Every
+// share is checked just before it is written to Vault, whatever its options
+// were parsed from; the parsers check the same limits as each option is
+// read, so that errors name the option at fault.
+func (p SharePolicy) Validate(req shareRequest) error {
+	if err := p.checkTTL(req.ttl); err != nil {
+		return err
+	}
+	if err := p.checkUses(req.uses); err != nil {
+		return err
+	}
+	if req.burn && req.uses != 1 {
+		return errBurnUses
+	}
+	if req.file != nil {
+		return p.checkFileSize(len(req.file.Content))
+	}
+	return p.checkSecretSize(req.secret)
+}
+
+func (p SharePolicy) checkTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("The TTL must be positive (maximum %s).", formatDuration(p.MaxTTL))
+	}
+	if p.MaxTTL > 0 && ttl > p.MaxTTL {
+		return fmt.Errorf("TTL %s exceeds the maximum of %s.", formatDuration(ttl), formatDuration(p.MaxTTL))
+	}
+	return nil
+}
+
+func (p SharePolicy) checkUses(uses int) error {
+	if uses < 0 {
+		return fmt.Errorf("Uses %d is negative. Use a whole number between 1 and %d.", uses, p.MaxUses)
+	}
+	if uses == 0 && !p.AllowUnlimitedUses {
+		return fmt.Errorf("Unlimited uses (`--uses 0`) are not allowed. Use a whole number between 1 and %d.", p.MaxUses)
+	}
+	if p.MaxUses > 0 && uses > p.MaxUses {
+		return fmt.Errorf("Uses %d exceeds the maximum of %d.", uses, p.MaxUses)
+	}
+	return nil
+}
+
+func (p SharePolicy) checkSecretSize(secret string) error {
+	if p.MaxSecretBytes > 0 && len(secret) > p.MaxSecretBytes {
+		return fmt.Errorf("The secret is %s, more than the maximum of %s. Please share something smaller.", formatBytes(len(secret)), formatBytes(p.MaxSecretBytes))
+	}
+	return nil
+}
+
+func (p SharePolicy) checkFileSize(size int) error {
+	if p.MaxFileBytes > 0 && size > p.MaxFileBytes {
+		return fmt.Errorf("Files can be at most %s.", formatBytes(p.MaxFileBytes))
+	}
+	return nil
+}
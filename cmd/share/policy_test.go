@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSharePolicyValidate(t *testing.T) {
+	p := SharePolicy{MaxTTL: 2 * time.Hour, MaxUses: 3, MaxSecretBytes: 8, MaxFileBytes: 16}
+	unlimited := p
+	unlimited.AllowUnlimitedUses = true
+	share := func(ttl time.Duration, uses int, secret string) shareRequest {
+		return shareRequest{shareArgs: shareArgs{ttl: ttl, uses: uses, secret: secret}}
+	}
+	file := func(size int) shareRequest {
+		req := share(time.Hour, 1, "")
+		req.file = &secretFile{Name: "key.pem", Content: make([]byte, size)}
+		return req
+	}
+	burn := share(time.Hour, 2, "hunter2")
+	burn.burn = true
+
+	tests := []struct {
+		name    string
+		policy  SharePolicy
+		req     shareRequest
+		wantErr string
+	}{
+		{"within every limit", p, share(time.Hour, 1, "hunter2"), ""},
+		{"TTL at the maximum", p, share(2*time.Hour, 1, "hunter2"), ""},
+		{"TTL a second over", p, share(2*time.Hour+time.Second, 1, "hunter2"), "exceeds the maximum of 2h"},
+		{"no TTL", p, share(0, 1, "hunter2"), "must be positive"},
+		{"uses at the maximum", p, share(time.Hour, 3, "hunter2"), ""},
+		{"uses one over", p, share(time.Hour, 4, "hunter2"), "Uses 4 exceeds the maximum of 3"},
+		{"unlimited uses not allowed", p, share(time.Hour, 0, "hunter2"), "Unlimited uses"},
+		{"unlimited uses allowed", unlimited, share(time.Hour, 0, "hunter2"), ""},
+		{"negative uses", unlimited, share(time.Hour, -1, "hunter2"), "negative"},
+		{"burn with more than one use", p, burn, "can only be retrieved once"},
+		{"secret at the maximum", p, share(time.Hour, 1, "12345678"), ""},
+		{"secret a byte over", p, share(time.Hour, 1, "123456789"), "more than the maximum of 8 bytes"},
+		{"file at the maximum", p, file(16), ""},
+		{"file a byte over", p, file(17), "Files can be at most 16 bytes"},
+		{"zero limits are not enforced", SharePolicy{}, share(1000*time.Hour, 1000, strings.Repeat("x", 1<<20)), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.req)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSharePolicyDefaults(t *testing.T) {
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, DefaultTTL: 15 * time.Minute, MaxUses: defaultMaxUses, DefaultUses: 3}}
+	args, err := parseShareArgs("hunter2", cfg)
+	if err != nil || args.ttl != 15*time.Minute || args.uses != 3 {
+		t.Errorf("parseShareArgs() = %s, %d uses, %v, want the configured defaults", args.ttl, args.uses, err)
+	}
+	if args, err := parseShareArgs("--burn hunter2", cfg); err != nil || args.uses != 1 {
+		t.Errorf("parseShareArgs(--burn) = %d uses, %v, want a single use", args.uses, err)
+	}
+	if _, err := parseShareArgs("--burn --uses 3 hunter2", cfg); err == nil {
+		t.Error("parseShareArgs(--burn --uses 3) succeeded, want an error")
+	}
+
+	// Hand-built configs fall back to the built-in defaults.
+	if args := (SharePolicy{}).defaultArgs(); args.ttl != defaultTokenTTL || args.uses != defaultTokenUses {
+		t.Errorf("zero policy defaults = %s, %d uses", args.ttl, args.uses)
+	}
+}
+
+func TestCreateShareEnforcesPolicy(t *testing.T) {
+	store := newFakeSecretStore()
+	b, _, _ := newTestBot(t, store, &fakeTokenCreator{})
+
+	// A share parsed before the limits were lowered, such as one waiting
+	// for confirmation, is still held to them.
+	req := shareRequest{shareArgs: shareArgs{ttl: time.Hour, uses: b.cfg.MaxUses + 1, secret: "hunter2"}, userID: "U1"}
+	if _, _, _, err := b.createShare(context.Background(), &req, nil); !errors.Is(err, errValidation) {
+		t.Errorf("createShare() with too many uses = %v, want a validation error", err)
+	}
+	if len(store.data) != 0 {
+		t.Errorf("store = %v, want nothing written to Vault", store.data)
+	}
+}
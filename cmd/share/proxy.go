@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// parsePublicBaseURL checks PUBLIC_BASE_URL, the address recipients reach
+// the retrieval server at through a reverse proxy, and returns it without
+// a trailing slash.
+func parsePublicBaseURL(value string) (string, error) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("PUBLIC_BASE_URL %q is not a valid http(s) URL", value)
+	}
+	return strings.TrimSuffix(value, "/"), nil
+}
+
+// parseTrustedProxies parses TRUSTED_PROXIES, a comma-separated list of IP
+// addresses and CIDR ranges.
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(p); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(p)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES entry %q is not an IP address or CIDR range", p)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// trusted reports whether addr is one of proxies.
+func trusted(proxies []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range proxies {
+		if p.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor sets the RemoteAddr of requests passed on by one of proxies
+// to the client named in their X-Forwarded-For header, so that logs, audit
+// events and the short code lockout see the recipient rather than the
+// proxy. The header is read from the right, skipping proxies, since
+// anything to the left of the last untrusted address may have been made up
+// by the client. Requests from anywhere else are left alone.
+func forwardedFor(proxies []netip.Prefix, next http.Handler) http.Handler {
+	if len(proxies) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if client, ok := forwardedClient(proxies, r); ok {
+			r = r.Clone(r.Context())
+			r.RemoteAddr = client.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func forwardedClient(proxies []netip.Prefix, r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !trusted(proxies, peer) {
+		return netip.Addr{}, false
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if !trusted(proxies, addr) {
+			return addr.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
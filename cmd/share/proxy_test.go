@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestPublicBaseURL(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	b.cfg.PublicBaseURL = "https://hush.example.com"
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	got := replies()[0]
+	if !strings.Contains(got, "https://hush.example.com/s/secret-") || !strings.Contains(got, "https://hush.example.com/v1/secrets/secret-") {
+		t.Errorf("reply = %q, want the link and curl command on the public URL", got)
+	}
+	if strings.Contains(got, "127.0.0.1") || strings.Contains(got, "localhost") {
+		t.Errorf("reply = %q, want no internal addresses", got)
+	}
+
+	for _, v := range []string{"hush.example.com", "ftp://hush.example.com", "https://hush.example.com/?x=1"} {
+		if _, err := parsePublicBaseURL(v); err == nil {
+			t.Errorf("parsePublicBaseURL(%q) succeeded, want error", v)
+		}
+	}
+	if got, err := parsePublicBaseURL("https://example.com/hush/"); err != nil || got != "https://example.com/hush" {
+		t.Errorf("parsePublicBaseURL() = %q, %v, want the trailing slash trimmed", got, err)
+	}
+}
+
+func TestForwardedFor(t *testing.T) {
+	proxies, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen string
+	handler := forwardedFor(proxies, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { seen = r.RemoteAddr }))
+
+	tests := []struct {
+		name, remoteAddr, forwarded, want string
+	}{
+		{"direct", "203.0.113.7:5000", "", "203.0.113.7:5000"},
+		{"untrusted peer", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7:5000"},
+		{"trusted proxy", "10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		{"chain of proxies", "192.0.2.1:5000", "198.51.100.1, 10.9.9.9", "198.51.100.1"},
+		{"spoofed left of the client", "10.1.2.3:5000", "6.6.6.6, 198.51.100.1", "198.51.100.1"},
+		{"no header from a proxy", "10.1.2.3:5000", "", "10.1.2.3:5000"},
+		{"garbled header", "10.1.2.3:5000", "not-an-ip", "10.1.2.3:5000"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/code", nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if seen != tt.want {
+			t.Errorf("%s: RemoteAddr = %q, want %q", tt.name, seen, tt.want)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Error("parseTrustedProxies(10.0.0.0/33) succeeded, want error")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultMaxActiveSecrets        = 10000
+	defaultMaxActiveSecretsPerUser = 100
+)
+
+// checkQuota refuses req if its sharer already has MaxActiveSecretsPerUser
+// secrets that can still be retrieved, or their workspace has
+// MaxActiveSecrets stored, so that secrets nobody revokes cannot pile up in
+// Vault without bound. A rotation replaces a secret rather than adding one,
+// so it is never refused. Shares made at the same moment may go one over.
+func (b *bot) checkQuota(ctx context.Context, req *shareRequest) error {
+	if req.replaces != "" || b.cfg.DryRun {
+		return nil
+	}
+	paths := b.cfg.kvPaths(req.teamID)
+
+	if limit := b.cfg.MaxActiveSecretsPerUser; limit > 0 && indexedUser(req.userID) {
+		// The index also lists secrets that have since gone, so only
+		// count the live ones once it is long enough to matter.
+		ids, err := readSecretIndex(ctx, b.secrets, paths, req.userID)
+		if err != nil {
+			return storeFailure("store the secret", fmt.Errorf("reading secret index: %w", err))
+		}
+		if len(ids) >= limit {
+			live, err := b.activeSecrets(ctx, req.teamID, req.userID, time.Now())
+			if err != nil {
+				return storeFailure("store the secret", fmt.Errorf("reading secret index: %w", err))
+			}
+			if len(live) >= limit {
+				slog.Warn("Per-user secret quota reached", "event", "share", "user_id", req.userID, "active", len(live), "limit", limit)
+				return invalid(fmt.Errorf("You already have %d active %s, the most you can have at once. Revoke any you no longer need, which `/list` shows, with `/revoke <secretID>` and try again.", len(live), plural(len(live), "secret", "secrets")))
+			}
+		}
+	}
+
+	if limit := b.cfg.MaxActiveSecrets; limit > 0 {
+		ids, err := listSecretIDs(ctx, b.secrets, paths)
+		if err != nil {
+			return storeFailure("store the secret", fmt.Errorf("listing secrets: %w", err))
+		}
+		if len(ids) >= limit {
+			slog.Warn("Workspace secret quota reached", "event", "share", "team_id", req.teamID, "user_id", req.userID, "stored", len(ids), "limit", limit)
+			return invalid(fmt.Errorf("This workspace already has %d secrets stored, the most it can hold. Revoke any of yours you no longer need, which `/list` shows, with `/revoke <secretID>`, or ask an admin to clear out old ones, and try again.", len(ids)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestShareQuota(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.MaxActiveSecretsPerUser = 2
+	b.cfg.MaxActiveSecrets = 3
+	paths := b.cfg.kvPaths("")
+	share := func(userID string) string {
+		t.Helper()
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify hunter2", UserID: userID, ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	share("U1")
+	share("U1")
+	if got := share("U1"); !strings.Contains(got, "You already have 2 active secrets") || !strings.Contains(got, "/revoke") {
+		t.Errorf("third share = %q, want it refused with a hint to revoke", got)
+	}
+	if len(tokens.created) != 2 {
+		t.Fatalf("%d tokens created, want 2", len(tokens.created))
+	}
+
+	// Secrets that have expired no longer count.
+	first := tokens.created[0].Metadata["secret_id"]
+	meta, err := readSecretMetadata(context.Background(), store, paths, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := writeSecretMetadata(context.Background(), store, paths, first, meta); err != nil {
+		t.Fatal(err)
+	}
+	if got := share("U1"); !strings.Contains(got, "been securely shared") {
+		t.Errorf("share after one expired = %q, want it shared", got)
+	}
+
+	// The expired secret is still stored until the sweep, so the workspace
+	// is full.
+	if got := share("U2"); !strings.Contains(got, "This workspace already has 3 secrets stored") {
+		t.Errorf("share past the workspace quota = %q, want it refused", got)
+	}
+	if len(tokens.created) != 3 {
+		t.Errorf("%d tokens created, want 3", len(tokens.created))
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// limiter decides whether a request for key may go ahead.
+type limiter interface {
+	Allow(key string) bool
+}
+
+// newLimiter returns a limiter of limit requests per interval for each key.
+// With a shared state store the limit is counted there, so that it holds
+// across replicas.
+func newLimiter(state StateStore, limit int, interval time.Duration) limiter {
+	if _, ok := state.(*memoryState); ok {
+		return newRateLimiter(limit, interval)
+	}
+	return newWindowLimiter(state, limit, interval)
+}
+
+// rateLimiter is an in-memory token bucket per key. Each bucket holds up to
+// limit tokens and refills at limit tokens per interval.
+type rateLimiter struct {
+	limit    float64
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit int, interval time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:    float64(limit),
+		interval: interval,
+		now:      time.Now,
+		buckets:  make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token from key's bucket and reports whether one was
+// available.
+func (l *rateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	// Forget full buckets so that the map does not grow without bound.
+	if now.Sub(l.lastPrune) >= l.interval {
+		l.prune(now)
+		l.lastPrune = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.limit, last: now}
+		l.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.last)
+	b.tokens = min(l.limit, b.tokens+l.limit*float64(elapsed)/float64(l.interval))
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune drops buckets that have refilled completely, since they behave the
+// same as a new bucket. It must be called with l.mu held.
+func (l *rateLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+l.limit*float64(now.Sub(b.last))/float64(l.interval) >= l.limit {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// windowLimiter allows limit requests per key in each fixed window of
+// interval, counted in a state store. A burst can straddle two windows, which
+// a token bucket would smooth out, but a counter needs only one request to
+// the store.
+type windowLimiter struct {
+	state    StateStore
+	limit    int64
+	interval time.Duration
+	now      func() time.Time
+}
+
+func newWindowLimiter(state StateStore, limit int, interval time.Duration) *windowLimiter {
+	return &windowLimiter{state: state, limit: int64(limit), interval: interval, now: time.Now}
+}
+
+// Allow counts a request in key's current window and reports whether it is
+// within the limit. If the state store cannot be reached the request is
+// allowed rather than turning every user away.
+func (l *windowLimiter) Allow(key string) bool {
+	window := l.now().UnixNano() / int64(l.interval)
+	ctx, cancel := context.WithTimeout(context.Background(), stateTimeout)
+	defer cancel()
+	n, err := l.state.Incr(ctx, "ratelimit:"+key+":"+strconv.FormatInt(window, 10), l.interval)
+	if err != nil {
+		slog.Error("Failed to count request against the rate limit", "event", "state", "error", err)
+		return true
+	}
+	return n <= l.limit
+}
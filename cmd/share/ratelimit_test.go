@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(3, time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow("U1") {
+			t.Fatalf("request %d denied, want allowed", i+1)
+		}
+	}
+	if l.Allow("U1") {
+		t.Fatal("request beyond the limit allowed")
+	}
+	if !l.Allow("U2") {
+		t.Fatal("other user denied by U1's bucket")
+	}
+
+	// A third of the interval refills one token.
+	now = now.Add(20 * time.Second)
+	if !l.Allow("U1") {
+		t.Fatal("request denied after refill")
+	}
+	if l.Allow("U1") {
+		t.Fatal("more than one token refilled")
+	}
+
+	// Buckets never hold more than the limit.
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !l.Allow("U1") {
+			t.Fatalf("request %d after idle period denied", i+1)
+		}
+	}
+	if l.Allow("U1") {
+		t.Fatal("bucket refilled beyond the limit")
+	}
+}
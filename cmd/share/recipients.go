@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// resolveRecipients turns the --to references in refs into Slack user IDs.
+// A reference is an escaped mention such as <@U123|alice>, a user ID, or a
+// user name with or without a leading @, which is looked up in teamID's
+// workspace.
+func (b *bot) resolveRecipients(teamID string, refs []string) ([]string, error) {
+	var ids []string
+	var users []slack.User
+	for _, ref := range refs {
+		if id, ok := mentionUserID(ref); ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		if users == nil {
+			var err error
+			if users, err = b.api(teamID).GetUsers(); err != nil {
+				return nil, fmt.Errorf("Failed to look up %s. Please try again, or use their member ID.", ref)
+			}
+		}
+		name := strings.TrimPrefix(ref, "@")
+		id := ""
+		for _, u := range users {
+			if !u.Deleted && (strings.EqualFold(u.Name, name) || strings.EqualFold(u.Profile.DisplayName, name)) {
+				id = u.ID
+				break
+			}
+		}
+		if id == "" {
+			return nil, fmt.Errorf("Could not find a Slack user called %s.", ref)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// mentionUserID returns the user ID in ref if it is an escaped mention or a
+// bare user ID.
+func mentionUserID(ref string) (string, bool) {
+	if strings.HasPrefix(ref, "<@") && strings.HasSuffix(ref, ">") {
+		id, _, _ := strings.Cut(ref[2:len(ref)-1], "|")
+		return id, isUserID(id)
+	}
+	return ref, isUserID(ref)
+}
+
+func isUserID(s string) bool {
+	if len(s) < 2 || (s[0] != 'U' && s[0] != 'W') {
+		return false
+	}
+	for _, r := range s[1:] {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// formatMentions renders user IDs as Slack mentions.
+func formatMentions(ids []string) string {
+	mentions := make([]string, len(ids))
+	for i, id := range ids {
+		mentions[i] = "<@" + id + ">"
+	}
+	return strings.Join(mentions, ", ")
+}
+
+// signLink returns the signature that ties a retrieval link for secretID to
+// userID.
+func signLink(key []byte, secretID, userID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(secretID + "\n" + userID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyLink reports whether sig is signLink's signature for secretID and
+// userID.
+func verifyLink(key []byte, secretID, userID, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(signLink(key, secretID, userID)))
+}
+
+// recipientURL returns the personal retrieval link for userID.
+func recipientURL(cfg *Config, teamID, secretID, userID string) string {
+	q := url.Values{"u": {userID}, "sig": {signLink(cfg.LinkSigningKey, secretID, userID)}}
+	return fmt.Sprintf("%s/s/%s?%s", retrievalBaseURL(cfg), secretURLPath(cfg, teamID, secretID), q.Encode())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMentionUserID(t *testing.T) {
+	tests := []struct {
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{"<@U123ABC|alice>", "U123ABC", true},
+		{"<@W42>", "W42", true},
+		{"U123ABC", "U123ABC", true},
+		{"@alice", "", false},
+		{"alice", "", false},
+		{"<#C123|general>", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := mentionUserID(tt.ref)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("mentionUserID(%q) = %q, %v, want %q, %v", tt.ref, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRestrictedSecretPage(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cfg := &Config{LinkSigningKey: key, RetrievalAddr: defaultRetrievalAddr, VaultSecretsMount: defaultSecretsMount, VaultKVVersion: defaultKVVersion}
+
+	tests := []struct {
+		name       string
+		query      url.Values
+		wantStatus int
+	}{
+		{"no signature", nil, http.StatusForbidden},
+		{"other user", url.Values{"u": {"U999"}, "sig": {signLink(key, "secret-1", "U999")}}, http.StatusForbidden},
+		{"forged signature", url.Values{"u": {"U123"}, "sig": {signLink(key, "secret-1", "U999")}}, http.StatusForbidden},
+		{"recipient", url.Values{"u": {"U123"}, "sig": {signLink(key, "secret-1", "U123")}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeSecretStore()
+			paths := cfg.kvPaths("")
+			storeSecret(context.Background(), store, paths, "secret-1", secretPayload{Text: "hunter2"}, nil)
+			writeSecretMetadata(context.Background(), store, paths, "secret-1", secretMetadata{ExpiresAt: time.Now().Add(time.Hour), UsesRemaining: 1, AllowedUsers: []string{"U123"}})
+			rs := &retrievalServer{secrets: store, cfg: cfg, audit: multiAuditLogger(nil), state: newMemoryState()}
+
+			req := httptest.NewRequest(http.MethodPost, "/s/secret-1?"+tt.query.Encode(), nil)
+			req.SetPathValue("secretID", "secret-1")
+			rec := httptest.NewRecorder()
+			rs.handlePage(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if _, err := readSecretMetadata(context.Background(), store, paths, "secret-1"); (err == nil) != (tt.wantStatus != http.StatusOK) {
+				t.Errorf("secret consumed = %v, want %v", err != nil, tt.wantStatus == http.StatusOK)
+			}
+		})
+	}
+}
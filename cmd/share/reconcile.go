@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+const defaultReconcileInterval = time.Hour
+
+// reconciler cross-checks the recipient tokens this process has issued
+// against the secrets in Vault. A token is orphaned when its secret is gone,
+// or its secret was given a new token, but the token was not revoked, such
+// as when a revocation failed. A secret is orphaned when it was stored but
+// never recorded a token, because its share failed part-way. Orphaned tokens
+// are revoked and orphaned secrets deleted. A secret whose token has expired
+// is not an orphan, since its retrieval link still works until the sweeper
+// deletes it.
+type reconciler struct {
+	b *bot
+	// suspects are the orphans found on the last pass. Each is only cleaned
+	// up if it is found again on the next, so that a share or extension in
+	// progress, which issues its token before recording it, is left alone.
+	suspects map[string]bool
+}
+
+func newReconciler(b *bot) *reconciler {
+	return &reconciler{b: b, suspects: map[string]bool{}}
+}
+
+// run reconciles every cfg.ReconcileInterval, starting straight away, until
+// ctx is cancelled.
+func (r *reconciler) run(ctx context.Context) {
+	ticker := time.NewTicker(r.b.cfg.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := r.reconcile(ctx); err != nil {
+			slog.Error("Failed to reconcile tokens and secrets", "event", "reconcile", "cleaned", n, "error", err)
+		} else if n > 0 {
+			slog.Info("Cleaned up orphaned tokens and secrets", "event", "reconcile", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile logs every orphan it finds, cleans up those that were also
+// found on the last pass and returns how many it cleaned up.
+func (r *reconciler) reconcile(ctx context.Context) (int, error) {
+	found := map[string]bool{}
+	cleaned := 0
+	var errs []error
+
+	for accessor, tok := range r.b.issued.live() {
+		orphaned, err := r.tokenOrphaned(ctx, accessor, tok)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !orphaned {
+			continue
+		}
+		key := "token/" + accessor
+		found[key] = true
+		if !r.suspects[key] {
+			slog.Warn("Found a token whose secret no longer uses it", "event", "reconcile", "secret_id", tok.secretID, "team_id", tok.teamID)
+			continue
+		}
+		if err := r.revokeToken(ctx, accessor, tok); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cleaned++
+	}
+
+	teams, err := r.b.storedTeams(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, team := range teams {
+		paths := r.b.cfg.kvPaths(team)
+		listCtx, cancel := vaultContext(ctx, r.b.cfg)
+		ids, err := listSecretIDs(listCtx, r.b.secrets, paths)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, id := range ids {
+			orphaned, err := r.secretOrphaned(ctx, paths, id)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !orphaned {
+				continue
+			}
+			key := "secret/" + team + "/" + id
+			found[key] = true
+			if !r.suspects[key] {
+				slog.Warn("Found a secret that has no token", "event", "reconcile", "secret_id", id, "team_id", team)
+				continue
+			}
+			if err := r.deleteSecret(ctx, paths, id); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cleaned++
+		}
+	}
+
+	r.suspects = found
+	return cleaned, errors.Join(errs...)
+}
+
+// tokenOrphaned reports whether the secret tok was issued for is gone or
+// records a different token.
+func (r *reconciler) tokenOrphaned(ctx context.Context, accessor string, tok issuedToken) (bool, error) {
+	ctx, cancel := vaultContext(ctx, r.b.cfg)
+	defer cancel()
+	meta, err := readSecretMetadata(ctx, r.b.secrets, r.b.cfg.kvPaths(tok.teamID), tok.secretID)
+	if errors.Is(err, errSecretNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		slog.Error("Failed to read secret metadata from Vault", "event", "reconcile", "secret_id", tok.secretID, "error", err)
+		return false, err
+	}
+	return meta.TokenAccessor != accessor, nil
+}
+
+// secretOrphaned reports whether id has no token recorded.
+func (r *reconciler) secretOrphaned(ctx context.Context, paths kvPaths, id string) (bool, error) {
+	ctx, cancel := vaultContext(ctx, r.b.cfg)
+	defer cancel()
+	meta, err := readSecretMetadata(ctx, r.b.secrets, paths, id)
+	if err != nil && !errors.Is(err, errSecretNotFound) {
+		slog.Error("Failed to read secret metadata from Vault", "event", "reconcile", "secret_id", id, "error", err)
+		return false, err
+	}
+	return meta.TokenAccessor == "", nil
+}
+
+func (r *reconciler) revokeToken(ctx context.Context, accessor string, tok issuedToken) error {
+	ctx, cancel := vaultContext(ctx, r.b.cfg)
+	defer cancel()
+	if err := revokeTokenAccessor(ctx, r.b.tokens, accessor); err != nil {
+		slog.Error("Failed to revoke orphaned token", "event", "reconcile", "secret_id", tok.secretID, "team_id", tok.teamID, "error", err)
+		reconciledTotal.WithLabelValues("token", outcomeError).Inc()
+		return err
+	}
+	r.b.issued.remove(accessor)
+	reconciledTotal.WithLabelValues("token", outcomeSuccess).Inc()
+	slog.Warn("Revoked orphaned token", "event", "reconcile", "secret_id", tok.secretID, "team_id", tok.teamID)
+	return nil
+}
+
+func (r *reconciler) deleteSecret(ctx context.Context, paths kvPaths, id string) error {
+	ctx, cancel := vaultContext(ctx, r.b.cfg)
+	defer cancel()
+	if err := deleteSecretLocked(ctx, r.b.state, r.b.secrets, paths, id); err != nil {
+		slog.Error("Failed to delete orphaned secret from Vault", "event", "reconcile", "secret_id", id, "team_id", paths.team, "error", err)
+		reconciledTotal.WithLabelValues("secret", outcomeError).Inc()
+		return err
+	}
+	reconciledTotal.WithLabelValues("secret", outcomeSuccess).Inc()
+	slog.Warn("Deleted orphaned secret", "event", "reconcile", "secret_id", id, "team_id", paths.team)
+	return nil
+}
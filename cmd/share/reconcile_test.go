@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestReconcile(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, store, tokens)
+	paths := b.cfg.kvPaths("")
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	shared := tokens.created[0].Metadata["secret_id"]
+	// A token whose secret was deleted without revoking it, and a secret
+	// whose share failed before its token was recorded.
+	b.issued.add("accessor-gone", "", "secret-gone", time.Hour)
+	if err := storeSecret(context.Background(), store, paths, "secret-partial", secretPayload{Text: "hunter3"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	store.WriteWithContext(context.Background(), paths.metadata("secret-partial"), map[string]interface{}{})
+
+	r := newReconciler(b)
+	if n, err := r.reconcile(context.Background()); err != nil || n != 0 {
+		t.Fatalf("first reconcile() = %d, %v, want the orphans only noted", n, err)
+	}
+	if len(tokens.revoked) != 0 {
+		t.Fatalf("revoked on the first pass = %q, want nothing", tokens.revoked)
+	}
+
+	if n, err := r.reconcile(context.Background()); err != nil || n != 2 {
+		t.Errorf("second reconcile() = %d, %v, want both orphans cleaned up", n, err)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-gone"}) {
+		t.Errorf("revoked = %q, want only the orphaned token", tokens.revoked)
+	}
+	if ids, _ := listSecretIDs(context.Background(), store, paths); !slices.Equal(ids, []string{shared}) {
+		t.Errorf("secrets left = %q, want only %s", ids, shared)
+	}
+	if _, ok := b.issued.live()["accessor-1"]; !ok {
+		t.Error("the shared secret's token is no longer tracked")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// pageCSP is the Content-Security-Policy of the retrieval pages. Secrets are
+// escaped by html/template wherever they appear, and never written into a
+// script; the policy is there in case that ever slips, allowing no script
+// or style but the pages' own, no loading of anything from elsewhere, and
+// no framing.
+var pageCSP = "default-src 'none'; style-src " + cspHash(pageStyle) + "; script-src " + cspHash(copyScriptSource) + "; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+
+func cspHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
+
+// setPageHeaders marks a response as one of the retrieval pages: HTML that
+// is never cached, sniffed as anything else or run with any script but its
+// own.
+func setPageHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", pageCSP)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+}
+
+// prettyJSON returns text indented for reading if it is a JSON object or
+// array, and reports whether it was. Anything else, including JSON that is
+// only a string or number, is left as it is.
+func prettyJSON(text string) (string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return text, false
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(trimmed), "", "  "); err != nil {
+		return text, false
+	}
+	return buf.String(), true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSecretPageRendering(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, store, tokens)
+	b.cfg.PrettyPrintJSON = true
+	rs := &retrievalServer{secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	reveal := func(secret string) *httptest.ResponseRecorder {
+		t.Helper()
+		tokens.created = nil
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify -- " + secret, UserID: "U1", ResponseURL: responseURL})
+		id := tokens.created[0].Metadata["secret_id"]
+		req := httptest.NewRequest(http.MethodPost, "/s/"+id, nil)
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return rec
+	}
+
+	rec := reveal(`</pre><script>alert(1)</script>`)
+	body := rec.Body.String()
+	if strings.Contains(body, "<script>alert") || !strings.Contains(body, `<pre id="value">&lt;/pre&gt;&lt;script&gt;alert(1)&lt;/script&gt;</pre>`) {
+		t.Errorf("page = %q, want the secret escaped", body)
+	}
+	csp := rec.Header().Get("Content-Security-Policy")
+	_, script, _ := strings.Cut(body, "<script>")
+	script, _, _ = strings.Cut(script, "</script>")
+	_, style, _ := strings.Cut(body, "<style>")
+	style, _, _ = strings.Cut(style, "</style>")
+	if !strings.Contains(csp, "default-src 'none'") || !strings.Contains(csp, "script-src "+cspHash(script)+";") || !strings.Contains(csp, "style-src "+cspHash(style)+";") {
+		t.Errorf("Content-Security-Policy = %q, want only the page's own script and style allowed", csp)
+	}
+
+	body = reveal(`{"type":"service_account","scopes":["a","b"]}`).Body.String()
+	if !strings.Contains(body, "{\n  &#34;type&#34;: &#34;service_account&#34;,\n  &#34;scopes&#34;: [\n    &#34;a&#34;,") || !strings.Contains(body, "shown indented") {
+		t.Errorf("page = %q, want the JSON indented", body)
+	}
+
+	b.cfg.PrettyPrintJSON = false
+	if body := reveal(`{"a":1}`).Body.String(); !strings.Contains(body, `<pre id="value">{&#34;a&#34;:1}</pre>`) {
+		t.Errorf("page = %q, want the JSON as it was shared", body)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	tests := []struct {
+		text, want string
+		wantOK     bool
+	}{
+		{`{"a":1}`, "{\n  \"a\": 1\n}", true},
+		{` [1,2] `, "[\n  1,\n  2\n]", true},
+		{`{"a":`, `{"a":`, false},
+		{`"just a string"`, `"just a string"`, false},
+		{`hunter2`, `hunter2`, false},
+	}
+	for _, tt := range tests {
+		if got, ok := prettyJSON(tt.text); got != tt.want || ok != tt.wantOK {
+			t.Errorf("prettyJSON(%q) = %q, %t, want %q, %t", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// reloginRetryInterval is how long to wait before retrying a failed AppRole
+// login.
+const reloginRetryInterval = 30 * time.Second
+
+// renewVaultToken keeps the bot's own Vault token alive until ctx is
+// cancelled. login is the AppRole login returned by newVaultClient, or nil
+// when a static token is used. When the token can no longer be renewed the
+// bot logs in with AppRole again if it is configured.
+func renewVaultToken(ctx context.Context, client *api.Client, cfg *Config, login *api.Secret) {
+	for {
+		secret := login
+		if secret == nil {
+			var err error
+			secret, err = selfTokenSecret(client)
+			if err != nil {
+				slog.Error("Failed to look up Vault token for renewal", "error", err)
+				return
+			}
+			if secret == nil {
+				slog.Debug("Vault token does not expire; renewal not needed")
+				return
+			}
+		}
+
+		watcher, err := client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: secret})
+		if err != nil {
+			slog.Error("Failed to start Vault token renewal", "error", err)
+			return
+		}
+		go watcher.Start()
+
+		if !watchToken(ctx, watcher) {
+			return
+		}
+
+		if !cfg.useAppRole() {
+			slog.Error("Vault token can no longer be renewed; Vault calls will fail once it expires")
+			return
+		}
+		if login = reloginAppRole(ctx, client, cfg); login == nil {
+			return
+		}
+	}
+}
+
+// watchToken logs renewals until the watcher stops. It returns false if ctx
+// was cancelled first.
+func watchToken(ctx context.Context, watcher *api.LifetimeWatcher) bool {
+	defer watcher.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case err := <-watcher.DoneCh():
+			if err != nil {
+				slog.Warn("Vault token renewal stopped", "error", err)
+			} else {
+				slog.Info("Vault token reached the end of its renewable lifetime")
+			}
+			return true
+		case renewal := <-watcher.RenewCh():
+			slog.Info("Renewed Vault token", "renewed_at", renewal.RenewedAt, "lease_duration", renewal.Secret.Auth.LeaseDuration)
+		}
+	}
+}
+
+// reloginAppRole logs in with AppRole until it succeeds or ctx is cancelled,
+// in which case it returns nil.
+func reloginAppRole(ctx context.Context, client *api.Client, cfg *Config) *api.Secret {
+	for {
+		login, err := appRoleLogin(ctx, client, cfg.VaultRoleID, cfg.VaultSecretID)
+		if err == nil {
+			slog.Info("Logged in to Vault with AppRole", "lease_duration", login.Auth.LeaseDuration, "renewable", login.Auth.Renewable)
+			return login
+		}
+		slog.Error("Failed to log in to Vault with AppRole", "error", err, "retry_in", reloginRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reloginRetryInterval):
+		}
+	}
+}
+
+// selfTokenSecret describes the client's current token in the form the
+// lifetime watcher expects. It returns nil if the token never expires.
+func selfTokenSecret(client *api.Client) (*api.Secret, error) {
+	self, err := client.Auth().Token().LookupSelf()
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := self.TokenTTL()
+	if err != nil {
+		return nil, err
+	}
+	if ttl == 0 {
+		return nil, nil
+	}
+	renewable, err := self.TokenIsRenewable()
+	if err != nil {
+		return nil, err
+	}
+	return &api.Secret{
+		Auth: &api.SecretAuth{
+			ClientToken:   client.Token(),
+			Renewable:     renewable,
+			LeaseDuration: int(ttl / time.Second),
+		},
+	}, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	// reshareShortcutID is the callback ID of the "Share securely" message
+	// shortcut.
+	reshareShortcutID     = "reshare_message"
+	reshareDeleteActionID = "reshare_delete"
+)
+
+// slackTextUnescaper undoes the escaping Slack applies to message text.
+var slackTextUnescaper = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">")
+
+// handleReshareShortcut shares the text of the message the shortcut was used
+// on, as /share would with its defaults, for a secret that was posted in
+// plain text. The author of the message is then offered a button to delete
+// it.
+func (b *bot) handleReshareShortcut(callback slack.InteractionCallback) {
+	teamID, userID, responseURL := callback.Team.ID, callback.User.ID, callback.ResponseURL
+	b.runHandler("share", userID, responseURL, func(ctx context.Context) {
+		if b.shareDisabled("/share", "reshare", userID) {
+			sendSlackResponse(b.slack, responseURL, disabledMessage("/share"))
+			return
+		}
+		if !b.shareLimiter.Allow(userID) {
+			slog.Warn("Share rate limit exceeded", "event", "reshare", "user_id", userID)
+			sharesTotal.WithLabelValues(outcomeDenied).Inc()
+			sendSlackResponse(b.slack, responseURL, rateLimitedMessage)
+			return
+		}
+
+		if !b.cfg.shareChannelAllowed(callback.Channel.ID, callback.Channel.Name) {
+			slog.Warn("Share attempted from a channel that is not allowed", "event", "reshare", "user_id", userID, "channel_id", callback.Channel.ID)
+			sharesTotal.WithLabelValues(outcomeDenied).Inc()
+			sendSlackResponse(b.slack, responseURL, "Secrets cannot be shared from this channel. Copy the message into `/share` in one of these instead: "+formatChannels(b.cfg.ShareAllowedChannels)+".")
+			return
+		}
+
+		secret := slackTextUnescaper.Replace(callback.Message.Text)
+		if strings.TrimSpace(secret) == "" {
+			sendSlackResponse(b.slack, responseURL, "That message has no text to share. Files and attachments cannot be shared from a message; use `/share` instead.")
+			return
+		}
+		args, err := newShareArgs(nil, secret, b.cfg)
+		if err == nil {
+			args.fields, err = parseSecretFields(secret)
+		}
+		if err != nil {
+			b.reportFailure(responseURL, "Rejected message shortcut", invalid(err), "event", "reshare", "user_id", userID)
+			return
+		}
+
+		req := shareRequest{
+			shareArgs:   args,
+			description: "The text of that message has",
+			teamID:      teamID,
+			channelID:   callback.Channel.ID,
+			userID:      userID,
+			userName:    callback.User.Name,
+			responseURL: responseURL,
+		}
+		if b.shareSecret(ctx, req) == "" {
+			return
+		}
+		b.offerDelete(callback)
+	})
+}
+
+// offerDelete follows a reshare with a button that deletes the original
+// message, if the user who reshared it wrote it. Anyone else is asked to
+// have its author delete it, so that the shortcut cannot be used to remove
+// other people's messages.
+func (b *bot) offerDelete(callback slack.InteractionCallback) {
+	msg := callback.Message
+	if msg.User != callback.User.ID {
+		sendSlackResponse(b.slack, callback.ResponseURL, "The original message is still visible in the channel. Ask <@"+msg.User+"> to delete it.")
+		return
+	}
+	text := "The original message is still visible in the channel. Delete it now?"
+	remove := slack.NewButtonBlockElement(reshareDeleteActionID, callback.Channel.ID+" "+msg.Timestamp, slack.NewTextBlockObject(slack.PlainTextType, "Delete message", false, false)).WithStyle(slack.StyleDanger)
+	err := postSlack(
+		&b.slack.Client, "reshare", "",
+		slack.MsgOptionResponseURL(callback.ResponseURL, slack.ResponseTypeEphemeral),
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("", remove),
+		),
+	)
+	if err != nil {
+		slog.Error("Failed to send response to Slack", "error", err)
+	}
+}
+
+// handleReshareDelete deletes the message a secret was reshared from when
+// the button offerDelete posted is clicked. The bot's token can usually only
+// delete messages the bot itself posted, so a refusal is explained rather
+// than treated as a failure.
+func (b *bot) handleReshareDelete(callback slack.InteractionCallback, action *slack.BlockAction) {
+	channelID, ts, _ := strings.Cut(action.Value, " ")
+	teamID, userID := callback.Team.ID, callback.User.ID
+	b.runHandler("reshare", userID, callback.ResponseURL, func(ctx context.Context) {
+		err := slackRetries.do(ctx, "delete_message", func() error {
+			_, _, err := b.api(teamID).DeleteMessageContext(ctx, channelID, ts)
+			return err
+		})
+		var slackErr slack.SlackErrorResponse
+		switch {
+		case err == nil:
+			slog.Info("Deleted reshared message", "event", "reshare", "user_id", userID, "channel_id", channelID)
+			replaceSlackResponse(b.slack, callback.ResponseURL, "The original message has been deleted.")
+		case errors.As(err, &slackErr) && slackErr.Err == "message_not_found":
+			replaceSlackResponse(b.slack, callback.ResponseURL, "The original message has already been deleted.")
+		case errors.As(err, &slackErr):
+			slog.Warn("Cannot delete reshared message", "event", "reshare", "user_id", userID, "channel_id", channelID, "error", err)
+			replaceSlackResponse(b.slack, callback.ResponseURL, "The bot is not allowed to delete that message. Please delete it yourself from the message's `⋯` menu.")
+		default:
+			slog.Error("Failed to delete reshared message", "event", "reshare", "user_id", userID, "channel_id", channelID, "error", err)
+			replaceSlackResponse(b.slack, callback.ResponseURL, "Failed to delete the original message. Please delete it yourself from the message's `⋯` menu.")
+		}
+	})
+}
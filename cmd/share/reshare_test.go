@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestReshareShortcut(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	reshare := func(userID, author string) {
+		callback := slack.InteractionCallback{Type: slack.InteractionTypeMessageAction, CallbackID: reshareShortcutID, User: slack.User{ID: userID}, ResponseURL: responseURL}
+		callback.Channel.ID = "C1"
+		callback.Message = slack.Message{Msg: slack.Msg{User: author, Timestamp: "1700000000.000100", Text: "p&amp;ss&lt;word&gt;"}}
+		b.handleShortcut(callback)
+		b.inflight.Wait()
+	}
+
+	reshare("U1", "U1")
+	if len(tokens.created) != 1 {
+		t.Fatalf("tokens created = %d, want the message shared", len(tokens.created))
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+	if stored, err := readSecret(context.Background(), store, b.cfg.kvPaths(""), id, nil); err != nil || stored.Text != "p&ss<word>" {
+		t.Errorf("stored secret = %q, %v, want the message text unescaped", stored.Text, err)
+	}
+	got := replies()
+	if len(got) != 2 || !strings.Contains(got[0], "The text of that message has been securely shared") || !strings.Contains(got[1], "Delete it now?") {
+		t.Fatalf("replies = %q, want the link and an offer to delete the message", got)
+	}
+
+	click := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, User: slack.User{ID: "U1"}, ResponseURL: responseURL}
+	click.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: reshareDeleteActionID, Value: "C1 1700000000.000100"}}
+	b.handleBlockActions(click)
+	b.inflight.Wait()
+	if got := replies(); !strings.Contains(got[len(got)-1], "has been deleted") {
+		t.Errorf("reply = %q, want the message deleted", got[len(got)-1])
+	}
+
+	reshare("U2", "U1")
+	if got := replies(); !strings.Contains(got[len(got)-1], "Ask <@U1> to delete it") {
+		t.Errorf("reply to someone else's message = %q, want its author asked to delete it", got[len(got)-1])
+	}
+}
+
+func TestReshareShortcutDisabled(t *testing.T) {
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), tokens)
+	b.cfg.FeatureFlags.Disabled = []string{"/share"}
+
+	callback := slack.InteractionCallback{Type: slack.InteractionTypeMessageAction, CallbackID: reshareShortcutID, User: slack.User{ID: "U1"}, ResponseURL: responseURL}
+	callback.Message = slack.Message{Msg: slack.Msg{User: "U1", Text: "hunter2"}}
+	b.handleShortcut(callback)
+	b.inflight.Wait()
+	if got := replies(); len(got) != 1 || got[0] != disabledMessage("/share") || len(tokens.created) != 0 {
+		t.Errorf("replies = %q, %d tokens created, want the shortcut refused", got, len(tokens.created))
+	}
+}
@@ -0,0 +1,581 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+)
+
+// retrievalServer serves shared secrets over HTTP, decrypting them when
+// client-side encryption is enabled.
+//
+// The /s/ page reads secrets with the bot's own Vault token and enforces the
+// expiry and use count recorded in the secret's metadata. The /v1/secrets API
+// instead authenticates with the recipient's short-lived Vault token, so
+// Vault enforces the token's TTL and use count, and it spends the secret's
+// own uses as the page does.
+type retrievalServer struct {
+	vault      *api.Client
+	secrets    SecretStore
+	workspaces *workspaces
+	cfg        *Config
+	audit      AuditLogger
+	// state holds the short codes that stand in for links, and
+	// codeLimiter bounds how often each address may try one.
+	state       StateStore
+	codeLimiter limiter
+}
+
+func newRetrievalServer(vaultClient *api.Client, ws *workspaces, cfg *Config, auditLogger AuditLogger, state StateStore) *http.Server {
+	rs := &retrievalServer{
+		vault:       vaultClient,
+		secrets:     vaultStore(vaultClient, cfg),
+		workspaces:  ws,
+		cfg:         cfg,
+		audit:       auditLogger,
+		state:       state,
+		codeLimiter: newLimiter(state, codeAttemptsPerMinute, time.Minute),
+	}
+
+	// With several workspaces, or SECRET_PATH_LAYOUT set to keep them
+	// apart, links name the team whose secret they open.
+	secret := "{secretID}"
+	if cfg.teamScoped() {
+		secret = "{teamID}/{secretID}"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /s/"+secret, rs.handlePage)
+	mux.HandleFunc("POST /s/"+secret, rs.handlePage)
+	mux.HandleFunc("GET /v1/secrets/"+secret, rs.handleRetrieve)
+	mux.HandleFunc("GET /v1/status/"+secret, rs.handleStatus)
+	mux.HandleFunc("GET /code", rs.handleCodePage)
+	mux.HandleFunc("POST /code", rs.handleCodePage)
+	return &http.Server{Addr: cfg.RetrievalAddr, Handler: forwardedFor(cfg.TrustedProxies, mux)}
+}
+
+// paths returns the Vault paths of the workspace r is for, reporting false
+// if the bot does not serve it.
+func (rs *retrievalServer) paths(r *http.Request) (kvPaths, bool) {
+	team := r.PathValue("teamID")
+	if rs.cfg.multiWorkspace() && rs.cfg.SlackWorkspaces[team] == "" {
+		return kvPaths{}, false
+	}
+	if rs.cfg.teamScoped() && !teamIDPattern.MatchString(team) {
+		return kvPaths{}, false
+	}
+	return rs.cfg.kvPaths(team), true
+}
+
+// secretURLPath returns the part of a retrieval URL that identifies
+// secretID, shared in teamID's workspace.
+func secretURLPath(cfg *Config, teamID, secretID string) string {
+	if cfg.teamScoped() {
+		return teamID + "/" + secretID
+	}
+	return secretID
+}
+
+// secretPageURL returns the link to the retrieval page of secretID, shared in
+// teamID's workspace.
+func secretPageURL(cfg *Config, teamID, secretID string) string {
+	return fmt.Sprintf("%s/s/%s", retrievalBaseURL(cfg), secretURLPath(cfg, teamID, secretID))
+}
+
+// retrievalBaseURL returns the URL recipients use to reach the retrieval
+// server.
+func retrievalBaseURL(cfg *Config) string {
+	if cfg.PublicBaseURL != "" {
+		return cfg.PublicBaseURL
+	}
+	host, port, err := net.SplitHostPort(cfg.RetrievalAddr)
+	if err != nil {
+		return "http://" + cfg.RetrievalAddr
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+func (rs *retrievalServer) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Vault-Token")
+	if token == "" {
+		retrievalsTotal.WithLabelValues("api", outcomeDenied).Inc()
+		http.Error(w, "missing X-Vault-Token header", http.StatusUnauthorized)
+		return
+	}
+
+	client, err := rs.vault.Clone()
+	if err != nil {
+		slog.Error("Failed to clone Vault client", "error", err)
+		retrievalsTotal.WithLabelValues("api", outcomeError).Inc()
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	client.SetToken(token)
+
+	secretID := r.PathValue("secretID")
+	paths, ok := rs.paths(r)
+	if !ok || !validSecretID(secretID) {
+		retrievalsTotal.WithLabelValues("api", outcomeNotFound).Inc()
+		http.Error(w, "secret not found or no longer available", http.StatusNotFound)
+		return
+	}
+	ctx, cancel := vaultContext(r.Context(), rs.cfg)
+	defer cancel()
+	secret, err := readSecret(ctx, vaultStore(client, rs.cfg), paths, secretID, newSecretCipher(rs.cfg, rs.secrets))
+	if errors.Is(err, context.DeadlineExceeded) {
+		slog.Error("Timed out retrieving secret", "event", "retrieve", "secret_id", secretID, "error", err)
+		retrievalsTotal.WithLabelValues("api", outcomeError).Inc()
+		http.Error(w, "Vault did not respond in time", http.StatusGatewayTimeout)
+		return
+	}
+	if vaultSealed(err) {
+		slog.Error("Vault is sealed", "event", "retrieve", "secret_id", secretID, "error", err)
+		retrievalsTotal.WithLabelValues("api", outcomeError).Inc()
+		http.Error(w, "Vault is sealed; contact an admin", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, errVaultUnavailable) {
+		retrievalsTotal.WithLabelValues("api", outcomeError).Inc()
+		http.Error(w, "the secret store is temporarily unavailable; try again in a minute", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		slog.Warn("Failed to retrieve secret", "event", "retrieve", "secret_id", secretID, "error", err)
+		retrievalsTotal.WithLabelValues("api", outcomeNotFound).Inc()
+		http.Error(w, "secret not found or no longer available", http.StatusNotFound)
+		return
+	}
+	retrievalsTotal.WithLabelValues("api", outcomeSuccess).Inc()
+
+	// The recipient's token cannot read the metadata, so spend the use
+	// with the bot's own token, as a reveal on the page would, so that the
+	// two ways of retrieving the secret draw on the same uses.
+	event := AuditEvent{Action: auditRetrieve, SecretID: secretID, RemoteAddr: r.RemoteAddr}
+	meta, err := rs.spendTokenRead(ctx, paths, secretID)
+	if err == nil {
+		event.SharedBy = meta.SharedBy
+		event.ExpiresAt = meta.ExpiresAt.UTC().Format(time.RFC3339)
+		event.UsesRemaining = usesRemaining(meta.UsesRemaining-1, meta.UsesRemaining == 0)
+		if meta.Notify {
+			defer rs.notifyRetrieved(paths.team, meta.SharedBy, secretID, time.Now())
+		}
+	} else {
+		slog.Warn("Failed to update secret metadata after retrieval", "event", "retrieve", "secret_id", secretID, "error", err)
+	}
+	audit(rs.audit, event)
+
+	if wantsDownload(r) {
+		serveFile(w, downloadFile(secret, meta.Label))
+		return
+	}
+	body := map[string]string{"secret": secret.Text}
+	if f := secret.File; f != nil {
+		body = map[string]string{
+			"filename":     f.Name,
+			"content_type": f.ContentType,
+			"content":      base64.StdEncoding.EncodeToString(f.Content),
+		}
+	}
+	if len(secret.Fields) > 0 {
+		body = map[string]string{}
+		for _, f := range secret.Fields {
+			body[f.Name] = f.Value
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Header.Get("X-Vault-Request") != "" {
+		// The Vault CLI, pointed here by `--format vault`, expects the
+		// secret's values under "data", as Vault returns them.
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": body})
+		return
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// spendTokenRead spends the use taken by a read of secretID with its token,
+// and returns the metadata from before. The secret's lock keeps two
+// concurrent reads from both spending its last use.
+func (rs *retrievalServer) spendTokenRead(ctx context.Context, paths kvPaths, secretID string) (secretMetadata, error) {
+	unlock, err := lockSecret(ctx, rs.state, paths, secretID)
+	if err != nil {
+		return secretMetadata{}, err
+	}
+	defer unlock()
+	meta, err := readSecretMetadata(ctx, rs.secrets, paths, secretID)
+	switch {
+	case err != nil:
+		return meta, err
+	case meta.Burn || meta.UsesRemaining == 1:
+		// The last use is spent, but the data would stay in Vault until
+		// the sweep without this.
+		return meta, deleteSecret(ctx, rs.secrets, paths, secretID)
+	case meta.UsesRemaining > 1 || meta.Notify:
+		next := meta
+		if next.UsesRemaining > 0 {
+			next.UsesRemaining--
+		}
+		next.Notify = false
+		return meta, writeSecretMetadata(ctx, rs.secrets, paths, secretID, next)
+	}
+	return meta, nil
+}
+
+var (
+	// secretPage keeps each value masked until the viewer reveals it, so
+	// that it is not on screen the moment the page loads. The copy buttons
+	// need JavaScript and stay hidden without it. Values only ever appear
+	// as element text, which html/template escapes.
+	secretPage = template.Must(template.New("secret").Parse(pageHeader + `
+<h1>Your shared secret</h1>
+<p>{{.Notice}}</p>
+{{if .Fields}}<dl>
+{{range $i, $f := .Fields}}<dt>{{$f.Name}}</dt>
+<dd><details><summary aria-label="Reveal or hide"></summary><pre id="value-{{$i}}">{{$f.Value}}</pre></details>
+<button type="button" class="copy" data-target="value-{{$i}}" hidden>Copy to clipboard</button></dd>
+{{end}}</dl>
+{{else}}{{if .Formatted}}<p>It is JSON, so it is shown indented for reading, and copied that way.</p>
+{{end}}<details><summary aria-label="Reveal or hide"></summary><pre id="value">{{.Secret}}</pre></details>
+<button type="button" class="copy" data-target="value" hidden>Copy to clipboard</button>
+{{end}}` + copyScript + pageFooter))
+
+	unavailablePage = template.Must(template.New("unavailable").Parse(pageHeader + `
+<h1>This secret is no longer available</h1>
+<p>It has already been viewed, has expired, or was never shared.
+Ask the sender to share it again.</p>
+` + pageFooter))
+
+	deniedPage = template.Must(template.New("denied").Parse(pageHeader + `
+<h1>This secret was shared with someone else</h1>
+<p>Only the people it was shared with can open it, using the link the bot
+sent them in Slack.</p>
+` + pageFooter))
+
+	burnPage = template.Must(template.New("burn").Parse(pageHeader + `
+<h1>A secret has been shared with you</h1>
+<p>This secret will be destroyed after viewing. Make sure you are ready to
+copy it somewhere safe before you open it.</p>
+<form method="post"><button type="submit">View the secret</button></form>
+` + pageFooter))
+
+	revealPage = template.Must(template.New("reveal").Parse(pageHeader + `
+<h1>A secret has been shared with you</h1>
+<p>{{.}} Opening this page does not count as a view; only revealing the
+secret does.</p>
+<form method="post"><button type="submit">Reveal the secret</button></form>
+` + pageFooter))
+
+	previewPage = template.Must(template.New("preview").Parse(pageHeader + `
+<h1>A secret has been shared with you</h1>
+<p>Open this link in your browser to view it.</p>
+` + pageFooter))
+)
+
+const pageHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="robots" content="noindex">
+<title>Hush</title>
+<style>` + pageStyle + `</style>
+</head>
+<body>`
+
+// pageStyle and copyScriptSource are the only style sheet and script the
+// pages may run, by their hashes in pageCSP.
+const pageStyle = `
+body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; }
+pre { background: #f4f4f4; padding: 1em; white-space: pre-wrap; word-break: break-all; }
+summary { cursor: pointer; }
+summary::after { content: "Reveal \2022\2022\2022\2022\2022\2022\2022\2022"; }
+details[open] summary::after { content: "Hide"; }
+`
+
+// copyScript shows the copy buttons of secretPage and copies the value each
+// names without revealing it. It reads the value from the page rather than
+// having it written into the script, where it could break out and run.
+const copyScript = `
+<script>` + copyScriptSource + `</script>`
+
+const copyScriptSource = `
+for (const button of document.querySelectorAll("button.copy")) {
+  button.hidden = false;
+  button.addEventListener("click", () => {
+    const value = document.getElementById(button.dataset.target).textContent;
+    navigator.clipboard.writeText(value).then(
+      () => { button.textContent = "Copied"; },
+      () => { button.textContent = "Copy failed; reveal and select it instead"; });
+  });
+}
+`
+
+const pageFooter = `
+</body>
+</html>`
+
+// handlePage renders a secret once per remaining use and deletes it when no
+// uses remain or it has expired. A GET only says how many uses remain; the
+// secret is shown, and a use spent, when the viewer confirms by POSTing back
+// to the page, so that browsers prefetching or reloading the link do not
+// use it up.
+func (rs *retrievalServer) handlePage(w http.ResponseWriter, r *http.Request) {
+	setPageHeaders(w)
+
+	// Slack fetches links to build previews; never spend a use on it.
+	if strings.Contains(r.UserAgent(), "Slackbot") {
+		previewPage.Execute(w, nil)
+		return
+	}
+
+	secretID := r.PathValue("secretID")
+	paths, ok := rs.paths(r)
+	if !ok || !validSecretID(secretID) {
+		retrievalsTotal.WithLabelValues("page", outcomeNotFound).Inc()
+		w.WriteHeader(http.StatusNotFound)
+		unavailablePage.Execute(w, nil)
+		return
+	}
+
+	// A personal link identifies the viewer of a secret shared with --to.
+	var viewer string
+	if u := r.URL.Query().Get("u"); u != "" && verifyLink(rs.cfg.LinkSigningKey, secretID, u, r.URL.Query().Get("sig")) {
+		viewer = u
+	}
+
+	ctx, cancel := vaultContext(r.Context(), rs.cfg)
+	defer cancel()
+	if r.Method == http.MethodGet {
+		rs.showReveal(ctx, w, paths, secretID, viewer, time.Now())
+		return
+	}
+	secret, meta, err := rs.consume(ctx, paths, secretID, viewer, r.PostFormValue("passphrase"), time.Now())
+	if errors.Is(err, errWrongPassphrase) {
+		slog.Warn("Wrong passphrase entered", "event", "retrieve", "secret_id", secretID, "remote_addr", r.RemoteAddr)
+		retrievalsTotal.WithLabelValues("page", outcomeDenied).Inc()
+		w.WriteHeader(http.StatusForbidden)
+		passphrasePage.Execute(w, passphraseForm{Notice: revealNotice(meta), Error: wrongPassphraseMessage(meta)})
+		return
+	}
+	if errors.Is(err, errPassphraseLockout) {
+		slog.Warn("Secret destroyed after too many wrong passphrases", "event", "retrieve", "secret_id", secretID, "remote_addr", r.RemoteAddr)
+		retrievalsTotal.WithLabelValues("page", outcomeDenied).Inc()
+		audit(rs.audit, AuditEvent{Action: auditLockout, SecretID: secretID, SharedBy: meta.SharedBy, Actor: viewer, RemoteAddr: r.RemoteAddr})
+		defer rs.notifyLockedOut(paths.team, meta.SharedBy, secretID)
+		w.WriteHeader(http.StatusGone)
+		lockedOutPage.Execute(w, nil)
+		return
+	}
+	if errors.Is(err, errAccessDenied) {
+		slog.Warn("Denied retrieval of restricted secret", "event", "retrieve", "secret_id", secretID, "remote_addr", r.RemoteAddr)
+		retrievalsTotal.WithLabelValues("page", outcomeDenied).Inc()
+		w.WriteHeader(http.StatusForbidden)
+		deniedPage.Execute(w, nil)
+		return
+	}
+	if err != nil {
+		outcome := outcomeNotFound
+		if !errors.Is(err, errSecretNotFound) {
+			slog.Error("Failed to retrieve secret", "event", "retrieve", "secret_id", secretID, "error", err)
+			outcome = outcomeError
+		}
+		retrievalsTotal.WithLabelValues("page", outcome).Inc()
+		w.WriteHeader(http.StatusNotFound)
+		unavailablePage.Execute(w, nil)
+		return
+	}
+
+	retrievalsTotal.WithLabelValues("page", outcomeSuccess).Inc()
+	remaining := meta.UsesRemaining - 1
+	audit(rs.audit, AuditEvent{
+		Action:        auditRetrieve,
+		SecretID:      secretID,
+		SharedBy:      meta.SharedBy,
+		Actor:         viewer,
+		ExpiresAt:     meta.ExpiresAt.UTC().Format(time.RFC3339),
+		UsesRemaining: usesRemaining(remaining, meta.UsesRemaining == 0),
+		RemoteAddr:    r.RemoteAddr,
+	})
+	if meta.Notify {
+		defer rs.notifyRetrieved(paths.team, meta.SharedBy, secretID, time.Now())
+	}
+
+	if secret.File != nil || wantsDownload(r) {
+		serveFile(w, downloadFile(secret, meta.Label))
+		return
+	}
+
+	var notice string
+	switch meta.UsesRemaining {
+	case 0:
+		notice = fmt.Sprintf("This link stays valid until %s.", meta.ExpiresAt.Format(time.RFC1123))
+	case 1:
+		notice = "This secret has now been deleted. Copy it somewhere safe before closing this page."
+	default:
+		notice = fmt.Sprintf("This secret can be revealed %s more before %s.", formatUses(meta.UsesRemaining-1), meta.ExpiresAt.Format(time.RFC1123))
+	}
+	text, formatted := secret.Text, false
+	if rs.cfg.PrettyPrintJSON {
+		text, formatted = prettyJSON(secret.Text)
+	}
+	secretPage.Execute(w, struct {
+		Secret    string
+		Formatted bool
+		Fields    []secretField
+		Notice    string
+	}{text, formatted, secret.Fields, notice})
+}
+
+// showReveal renders the page asking the viewer to reveal secretID, with the
+// uses it has left, without spending one.
+func (rs *retrievalServer) showReveal(ctx context.Context, w http.ResponseWriter, paths kvPaths, secretID, viewer string, now time.Time) {
+	meta, err := readSecretMetadata(ctx, rs.secrets, paths, secretID)
+	if err != nil || meta.expired(now) {
+		if err != nil && !errors.Is(err, errSecretNotFound) {
+			slog.Error("Failed to read secret metadata", "event", "retrieve", "secret_id", secretID, "error", err)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		unavailablePage.Execute(w, nil)
+		return
+	}
+	if len(meta.AllowedUsers) > 0 && !slices.Contains(meta.AllowedUsers, viewer) {
+		w.WriteHeader(http.StatusForbidden)
+		deniedPage.Execute(w, nil)
+		return
+	}
+	if meta.PassphraseHash != "" {
+		passphrasePage.Execute(w, passphraseForm{Notice: revealNotice(meta)})
+		return
+	}
+	if meta.Burn {
+		burnPage.Execute(w, nil)
+		return
+	}
+	revealPage.Execute(w, revealNotice(meta))
+}
+
+// revealNotice tells the viewer how many more times the secret described by
+// meta can be revealed.
+func revealNotice(meta secretMetadata) string {
+	switch {
+	case meta.Burn:
+		return "This secret will be destroyed after viewing. Make sure you are ready to copy it somewhere safe before you open it."
+	case meta.UsesRemaining == 0:
+		return fmt.Sprintf("It can be revealed any number of times until %s.", meta.ExpiresAt.Format(time.RFC1123))
+	case meta.UsesRemaining == 1:
+		return "It can be revealed once, and is deleted when you do. Make sure you are ready to copy it somewhere safe."
+	}
+	return fmt.Sprintf("It can be revealed %s more before %s.", formatUses(meta.UsesRemaining), meta.ExpiresAt.Format(time.RFC1123))
+}
+
+// serveFile sends f as a download. nosniff stops browsers from rendering
+// uploaded HTML or scripts in the context of the retrieval server.
+func serveFile(w http.ResponseWriter, f *secretFile) {
+	w.Header().Set("Cache-Control", "no-store")
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": f.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Should a browser render it anyway, it runs as if from nowhere.
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.Content)))
+	w.Write(f.Content)
+}
+
+// consume reads secretID on behalf of viewer, the verified Slack user ID of
+// the person opening it or "" if unknown, who typed passphrase, and uses up
+// one of its remaining retrievals, deleting the secret if that was the last
+// one. It returns the metadata as it was before the retrieval, so a set
+// Notify means this was the first. A wrong passphrase spends no use but
+// counts towards destroying the secret.
+func (rs *retrievalServer) consume(ctx context.Context, paths kvPaths, secretID, viewer, passphrase string, now time.Time) (secretPayload, secretMetadata, error) {
+	meta, err := readSecretMetadata(ctx, rs.secrets, paths, secretID)
+	if err != nil {
+		return secretPayload{}, meta, err
+	}
+	if meta.expired(now) {
+		if err := deleteSecretLocked(ctx, rs.state, rs.secrets, paths, secretID); err != nil {
+			slog.Error("Failed to delete expired secret", "secret_id", secretID, "error", err)
+		}
+		return secretPayload{}, meta, errSecretNotFound
+	}
+	if len(meta.AllowedUsers) > 0 && !slices.Contains(meta.AllowedUsers, viewer) {
+		return secretPayload{}, meta, errAccessDenied
+	}
+	// bcrypt is slow on purpose, so the passphrase is checked before the
+	// secret's lock is taken.
+	if !checkPassphrase(meta, passphrase) {
+		meta, err = rs.recordPassphraseFailure(ctx, paths, secretID)
+		return secretPayload{}, meta, err
+	}
+
+	unlock, err := lockSecret(ctx, rs.state, paths, secretID)
+	if err != nil {
+		return secretPayload{}, meta, err
+	}
+	defer unlock()
+	// Read again for the uses left now that no one else can spend them.
+	if meta, err = readSecretMetadata(ctx, rs.secrets, paths, secretID); err != nil {
+		return secretPayload{}, meta, err
+	}
+	secret, err := readSecret(ctx, rs.secrets, paths, secretID, newSecretCipher(rs.cfg, rs.secrets))
+	if err != nil {
+		return secretPayload{}, meta, err
+	}
+
+	switch {
+	case meta.Burn || meta.UsesRemaining == 1:
+		if err := deleteSecret(ctx, rs.secrets, paths, secretID); err != nil {
+			return secretPayload{}, meta, fmt.Errorf("deleting consumed secret: %w", err)
+		}
+	case meta.UsesRemaining == 0:
+		// Unlimited uses, but the first retrieval still clears Notify.
+		if meta.Notify {
+			next := meta
+			next.Notify = false
+			if err := writeSecretMetadata(ctx, rs.secrets, paths, secretID, next); err != nil {
+				return secretPayload{}, meta, fmt.Errorf("updating notification state: %w", err)
+			}
+		}
+	default:
+		next := meta
+		next.UsesRemaining--
+		next.Notify = false
+		if err := writeSecretMetadata(ctx, rs.secrets, paths, secretID, next); err != nil {
+			return secretPayload{}, meta, fmt.Errorf("updating remaining uses: %w", err)
+		}
+	}
+	return secret, meta, nil
+}
+
+// notifyRetrieved sends the sharer, in teamID's workspace, a DM saying
+// their secret was retrieved.
+func (rs *retrievalServer) notifyRetrieved(teamID, userID, secretID string, at time.Time) {
+	client, ok := rs.workspaces.client(teamID)
+	if userID == "" || !ok {
+		return
+	}
+	text := fmt.Sprintf("Your shared secret `%s` was accessed at %s.", secretID, formatSlackDate(at))
+	if err := postSlack(client, "notify", userID, slack.MsgOptionText(text, false)); err != nil {
+		slog.Error("Failed to notify sharer of retrieval", "event", "notify", "secret_id", secretID, "user_id", userID, "error", err)
+	}
+}
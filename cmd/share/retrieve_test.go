@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+)
+
+func TestBurnedSecret(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--uses 3 --burn hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "cannot be combined with `--uses`") {
+		t.Fatalf("replies to --uses 3 --burn = %q, want it rejected", got)
+	}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--burn --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	if len(tokens.created) != 1 || tokens.created[0].NumUses != 1 {
+		t.Fatalf("tokens created = %+v, want one with a single use", tokens.created)
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+	paths := b.cfg.kvPaths("")
+	if meta, err := readSecretMetadata(context.Background(), store, paths, id); err != nil || !meta.Burn {
+		t.Fatalf("metadata = %+v, %v, want Burn set", meta, err)
+	}
+
+	rs := &retrievalServer{secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	open := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/s/"+id, nil)
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return rec
+	}
+
+	rec := open(http.MethodGet)
+	if body := rec.Body.String(); !strings.Contains(body, "destroyed after viewing") || strings.Contains(body, "hunter2") {
+		t.Errorf("GET = %q, want the warning without the secret", body)
+	}
+	if _, err := readSecret(context.Background(), store, paths, id, nil); err != nil {
+		t.Fatalf("secret consumed by GET: %v", err)
+	}
+
+	if rec := open(http.MethodPost); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("POST = %d %q, want the secret", rec.Code, rec.Body.String())
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+		t.Errorf("read after viewing error = %v, want the secret destroyed", err)
+	}
+	if rec := open(http.MethodPost); rec.Code != http.StatusNotFound {
+		t.Errorf("second POST = %d, want 404", rec.Code)
+	}
+}
+
+func TestRevealSpendsUse(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--uses 2 --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "It has 2 uses left") {
+		t.Fatalf("replies = %q, want the remaining uses explained", got)
+	}
+	id := tokens.created[0].Metadata["secret_id"]
+	paths := b.cfg.kvPaths("")
+
+	rs := &retrievalServer{secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	open := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/s/"+id, nil)
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		rec := open(http.MethodGet)
+		if body := rec.Body.String(); !strings.Contains(body, "revealed 2 times more") || strings.Contains(body, "hunter2") {
+			t.Fatalf("GET %d = %q, want the remaining uses without the secret", i+1, body)
+		}
+	}
+	if rec := open(http.MethodPost); !strings.Contains(rec.Body.String(), "hunter2") || !strings.Contains(rec.Body.String(), "revealed once more") {
+		t.Errorf("POST = %q, want the secret and one use left", rec.Body.String())
+	} else if body := rec.Body.String(); !strings.Contains(body, `<pre id="value">hunter2</pre></details>`) || !strings.Contains(body, `data-target="value" hidden>Copy to clipboard`) {
+		t.Errorf("POST = %q, want the secret masked with a copy button", body)
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, id); err != nil || meta.UsesRemaining != 1 {
+		t.Errorf("metadata = %+v, %v, want 1 use left", meta, err)
+	}
+	if rec := open(http.MethodGet); !strings.Contains(rec.Body.String(), "revealed once, and is deleted") {
+		t.Errorf("GET = %q, want the last use explained", rec.Body.String())
+	}
+}
+
+// newStoreVault returns a client for a fake Vault that answers the
+// recipient's token with what the bot stored in store, until the secret's
+// metadata, and with it every version, is deleted.
+func newStoreVault(t *testing.T, store SecretStore) *api.Client {
+	t.Helper()
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		resp, _ := store.ReadWithContext(r.Context(), path)
+		if meta, _ := store.ReadWithContext(r.Context(), strings.Replace(path, "/data/", "/metadata/", 1)); resp == nil || meta == nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": resp.Data})
+	}))
+	t.Cleanup(vault.Close)
+	client, err := api.NewClient(&api.Config{Address: vault.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestTokenReadBurnsSecret(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--burn --format vault --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	id := tokens.created[0].Metadata["secret_id"]
+	if got := replies(); !strings.Contains(got[0], "VAULT_ADDR="+retrievalBaseURL(b.cfg)+" ") {
+		t.Fatalf("reply = %q, want the Vault CLI pointed at the retrieval server", got[0])
+	}
+
+	rs := &retrievalServer{vault: newStoreVault(t, store), secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/secrets/{secretID}", rs.handleRetrieve)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// Read it the way the Vault CLI in the reply would.
+	cli, err := api.NewClient(&api.Config{Address: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cli.SetToken("hvs.recipient")
+	secret, err := cli.Logical().Read("secrets/" + id)
+	if err != nil || secret == nil || secret.Data["secret"] != "hunter2" {
+		t.Fatalf("Vault CLI read = %+v, %v, want the secret", secret, err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/s/"+id, nil)
+	req.SetPathValue("secretID", id)
+	rec := httptest.NewRecorder()
+	rs.handlePage(rec, req)
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "hunter2") {
+		t.Errorf("reveal after a token read = %d %q, want the burnt secret gone", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTokenAndPageShareUses(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--uses 3 --no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	id := tokens.created[0].Metadata["secret_id"]
+	paths := b.cfg.kvPaths("")
+	// The curl command in the reply must be what read below serves, not a
+	// read straight from Vault, which would spend none of the uses.
+	apiURL := retrievalBaseURL(b.cfg) + "/v1/secrets/" + id
+	if got := replies(); !strings.Contains(got[0], "--request GET "+apiURL) {
+		t.Fatalf("reply = %q, want the curl command to read %s", got[0], apiURL)
+	}
+
+	rs := &retrievalServer{vault: newStoreVault(t, store), secrets: store, cfg: b.cfg, audit: multiAuditLogger(nil), state: b.state}
+	read := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, apiURL, nil)
+		req.Header.Set("X-Vault-Token", "hvs.recipient")
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handleRetrieve(rec, req)
+		return rec
+	}
+	reveal := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/s/"+id, nil)
+		req.SetPathValue("secretID", id)
+		rec := httptest.NewRecorder()
+		rs.handlePage(rec, req)
+		return rec
+	}
+
+	if rec := read(); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "hunter2") {
+		t.Fatalf("token read = %d %q, want the secret", rec.Code, rec.Body.String())
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, id); err != nil || meta.UsesRemaining != 2 {
+		t.Errorf("metadata after a token read = %+v, %v, want 2 uses left", meta, err)
+	}
+	if rec := reveal(); !strings.Contains(rec.Body.String(), "hunter2") || !strings.Contains(rec.Body.String(), "revealed once more") {
+		t.Errorf("reveal = %q, want the secret and one use left", rec.Body.String())
+	}
+	if rec := read(); rec.Code != http.StatusOK {
+		t.Fatalf("last token read = %d, want the secret", rec.Code)
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+		t.Errorf("metadata read after the last use error = %v, want the secret destroyed", err)
+	}
+	if rec := reveal(); rec.Code != http.StatusNotFound {
+		t.Errorf("reveal after the last use = %d, want 404", rec.Code)
+	}
+	if rec := read(); rec.Code != http.StatusNotFound {
+		t.Errorf("token read after the last use = %d, want 404", rec.Code)
+	}
+}
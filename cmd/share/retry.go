@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultVaultMaxAttempts = 3
+	retryBaseDelay          = 200 * time.Millisecond
+	retryMaxDelay           = 5 * time.Second
+)
+
+// retryPolicy retries transient failures with exponential backoff and full
+// jitter.
+type retryPolicy struct {
+	attempts int
+	base     time.Duration
+	max      time.Duration
+	// sleep waits for the delay or until ctx is done. It is replaced in
+	// tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryPolicy(attempts int) retryPolicy {
+	return retryPolicy{attempts: attempts, base: retryBaseDelay, max: retryMaxDelay, sleep: sleepContext}
+}
+
+// do calls fn until it succeeds, fails with an error that is not worth
+// retrying, or has been tried p.attempts times. If ctx is done while waiting
+// to retry, its error is returned.
+func (p retryPolicy) do(ctx context.Context, operation string, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !retryable(err) || attempt >= p.attempts {
+			return err
+		}
+		delay := p.backoff(attempt)
+		slog.Warn("Retrying Vault request", "operation", operation, "attempt", attempt, "delay", delay, "error", err)
+		if err := p.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff returns a random delay of up to base*2^(attempt-1), capped at max.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	ceiling := p.max
+	if shift := attempt - 1; shift < 30 && p.base<<shift < p.max {
+		ceiling = p.base << shift
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryable reports whether err may be transient: a network failure or a
+// 5xx or 429 from Vault. Other 4xx responses will not succeed on retry.
+func retryable(err error) bool {
+	var respErr *api.ResponseError
+	if vaultSealed(err) {
+		// Vault stays sealed until an operator unseals it.
+		return false
+	}
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500 || respErr.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryingStore is a SecretStore that retries transient failures. KV reads,
+// writes, deletes and lists are idempotent, so repeating them is safe.
+type retryingStore struct {
+	SecretStore
+	policy retryPolicy
+}
+
+func (s retryingStore) ReadWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.policy.do(ctx, "read", func() error {
+		resp, err = s.SecretStore.ReadWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+func (s retryingStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (resp *api.Secret, err error) {
+	err = s.policy.do(ctx, "write", func() error {
+		resp, err = s.SecretStore.WriteWithContext(ctx, path, data)
+		return err
+	})
+	return resp, err
+}
+
+func (s retryingStore) DeleteWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.policy.do(ctx, "delete", func() error {
+		resp, err = s.SecretStore.DeleteWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+func (s retryingStore) ListWithContext(ctx context.Context, path string) (resp *api.Secret, err error) {
+	err = s.policy.do(ctx, "list", func() error {
+		resp, err = s.SecretStore.ListWithContext(ctx, path)
+		return err
+	})
+	return resp, err
+}
+
+// retryingTokens is a TokenCreator that retries transient failures. A token
+// created by a request whose response was lost is orphaned, but it is
+// short-lived and can only read its own secret.
+type retryingTokens struct {
+	TokenCreator
+	policy retryPolicy
+}
+
+func (t retryingTokens) CreateWithContext(ctx context.Context, opts *api.TokenCreateRequest) (resp *api.Secret, err error) {
+	err = t.policy.do(ctx, "token_create", func() error {
+		resp, err = t.TokenCreator.CreateWithContext(ctx, opts)
+		return err
+	})
+	return resp, err
+}
+
+func (t retryingTokens) RevokeAccessorWithContext(ctx context.Context, accessor string) error {
+	return t.policy.do(ctx, "token_revoke", func() error {
+		return t.TokenCreator.RevokeAccessorWithContext(ctx, accessor)
+	})
+}
+
+// vaultStore returns the bot's SecretStore for client, with retries, metrics
+// and cfg's circuit breaker, which sees each operation once its retries are
+// done.
+func vaultStore(client *api.Client, cfg *Config) SecretStore {
+	return breakerStore{retryingStore{instrumentedStore{client.Logical()}, newRetryPolicy(cfg.VaultMaxAttempts)}, cfg.vaultBreaker}
+}
+
+// vaultTokens returns the bot's TokenCreator for client, with retries,
+// metrics and cfg's circuit breaker.
+func vaultTokens(client *api.Client, cfg *Config) TokenCreator {
+	return breakerTokens{retryingTokens{instrumentedTokens{client.Auth().Token()}, newRetryPolicy(cfg.VaultMaxAttempts)}, cfg.vaultBreaker}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"network", errors.New("connection refused"), true},
+		{"server error", &api.ResponseError{StatusCode: http.StatusBadGateway}, true},
+		{"throttled", &api.ResponseError{StatusCode: http.StatusTooManyRequests}, true},
+		{"unavailable", &api.ResponseError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"sealed", &api.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"Vault is sealed"}}, false},
+		{"forbidden", &api.ResponseError{StatusCode: http.StatusForbidden}, false},
+		{"bad request", &api.ResponseError{StatusCode: http.StatusBadRequest}, false},
+		{"cancelled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var slept []time.Duration
+	p := retryPolicy{attempts: 3, base: 100 * time.Millisecond, max: time.Second, sleep: func(_ context.Context, d time.Duration) error { slept = append(slept, d); return nil }}
+
+	calls := 0
+	err := p.do(context.Background(), "write", func() error {
+		calls++
+		if calls < 3 {
+			return &api.ResponseError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("do() = %v after %d calls, want success after 3", err, calls)
+	}
+	if len(slept) != 2 || slept[0] > 100*time.Millisecond || slept[1] > 200*time.Millisecond {
+		t.Errorf("backoff delays = %v, want two within 100ms and 200ms", slept)
+	}
+
+	calls = 0
+	err = p.do(context.Background(), "write", func() error {
+		calls++
+		return &api.ResponseError{StatusCode: http.StatusForbidden}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("do() on 403 = %v after %d calls, want failure after 1", err, calls)
+	}
+
+	calls = 0
+	err = p.do(context.Background(), "write", func() error {
+		calls++
+		return errors.New("connection reset")
+	})
+	if err == nil || calls != 3 {
+		t.Errorf("do() on persistent failure = %v after %d calls, want failure after 3", err, calls)
+	}
+}
+
+func TestRetryPolicyDoContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := retryPolicy{attempts: 3, base: time.Hour, max: time.Hour, sleep: sleepContext}
+
+	calls := 0
+	err := p.do(ctx, "write", func() error {
+		calls++
+		cancel()
+		return &api.ResponseError{StatusCode: http.StatusServiceUnavailable}
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("do() = %v after %d calls, want context.Canceled after 1", err, calls)
+	}
+}
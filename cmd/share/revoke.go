@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// handleRevokeCommand destroys a shared secret before it expires and revokes
+// the token issued for it. Only the user who shared the secret may revoke it.
+func (b *bot) handleRevokeCommand(ctx context.Context, cmd slack.SlashCommand) {
+	secretID := strings.TrimSpace(cmd.Text)
+	if secretID == "" {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Please provide the ID of the secret to revoke. Usage: `/revoke <secretID>`")
+		return
+	}
+	if !validSecretID(secretID) {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found.", secretID))
+		return
+	}
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	message, ok := b.revokeSecret(ctx, cmd.TeamID, cmd.UserID, secretID)
+	if !ok {
+		sendSlackResponse(b.slack, cmd.ResponseURL, message)
+		return
+	}
+	sendSlackResponseType(b.slack, cmd.ResponseURL, b.replyType(cmd.Command), message)
+}
+
+// revokeSecret destroys secretID on behalf of userID, who must have shared
+// it, and returns the message to show them. It reports whether the secret
+// was revoked.
+func (b *bot) revokeSecret(ctx context.Context, teamID, userID, secretID string) (string, bool) {
+	meta, err := readSecretMetadata(ctx, b.secrets, b.cfg.kvPaths(teamID), secretID)
+	if errors.Is(err, errSecretNotFound) {
+		revocationsTotal.WithLabelValues(outcomeNotFound).Inc()
+		return fmt.Sprintf("No secret with ID `%s` was found. It may have already expired or been retrieved.", secretID), false
+	}
+	if err != nil {
+		err = storeFailure("revoke the secret", err)
+		logFailure("Failed to read secret metadata from Vault", err, "event", "revoke", "secret_id", secretID, "user_id", userID)
+		revocationsTotal.WithLabelValues(outcomeError).Inc()
+		return failureMessage(err), false
+	}
+
+	if meta.SharedBy != userID {
+		revocationsTotal.WithLabelValues(outcomeDenied).Inc()
+		return "Only the person who shared this secret can revoke it.", false
+	}
+
+	if err := b.destroySecret(ctx, teamID, secretID, meta); err != nil {
+		err = storeFailure("revoke the secret", err)
+		logFailure("Failed to revoke secret", err, "event", "revoke", "secret_id", secretID, "user_id", userID)
+		revocationsTotal.WithLabelValues(outcomeError).Inc()
+		return failureMessage(err), false
+	}
+
+	revocationsTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret revoked", "event", "revoke", "secret_id", secretID, "user_id", userID)
+	audit(b.audit, AuditEvent{Action: auditRevoke, SecretID: secretID, SharedBy: meta.SharedBy, Actor: userID})
+	return fmt.Sprintf("Secret %s has been revoked and can no longer be retrieved.", describeSecret(secretID, meta.Label)), true
+}
+
+// destroySecret revokes the token of secretID, shared in teamID's workspace
+// with meta, deletes it from Vault and removes it from its owner's index.
+func (b *bot) destroySecret(ctx context.Context, teamID, secretID string, meta secretMetadata) error {
+	if meta.TokenAccessor != "" {
+		if err := revokeTokenAccessor(ctx, b.tokens, meta.TokenAccessor); err != nil {
+			return fmt.Errorf("revoking token: %w", err)
+		}
+		b.issued.remove(meta.TokenAccessor)
+	}
+
+	if err := deleteSecretLocked(ctx, b.state, b.secrets, b.cfg.kvPaths(teamID), secretID); err != nil {
+		return fmt.Errorf("deleting secret: %w", err)
+	}
+
+	// The index only powers /list, so a failure here does not fail the
+	// revocation
+	err := b.updateIndex(ctx, teamID, meta.SharedBy, func(ids []string) []string {
+		return slices.DeleteFunc(ids, func(id string) bool { return id == secretID })
+	})
+	if err != nil {
+		slog.Error("Failed to remove secret from index", "secret_id", secretID, "user_id", meta.SharedBy, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const rotateUsage = "Usage: `/rotate <secretID> [new value]`"
+
+// handleRotateCommand replaces a shared secret with a new value, given or
+// generated, under a new ID with the same limits, and then revokes the old
+// one. People it was shared with through --to are sent personal links to the
+// new value. Only the user who shared the secret may rotate it.
+func (b *bot) handleRotateCommand(ctx context.Context, cmd slack.SlashCommand) {
+	if !b.allowShareChannel(cmd) {
+		return
+	}
+	if !b.shareLimiter.Allow(cmd.UserID) {
+		slog.Warn("Share rate limit exceeded", "event", "rotate", "user_id", cmd.UserID)
+		rotationsTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, rateLimitedMessage)
+		return
+	}
+
+	secretID, rest, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	if secretID == "" {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Please provide the ID of the secret to rotate. "+rotateUsage)
+		return
+	}
+	if !validSecretID(secretID) {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found.", secretID))
+		return
+	}
+	// Only "--" and quoting are recognised, as for the secret given to
+	// /share.
+	_, value, err := splitFlags(rest, nil)
+	if err == nil && value != "" && strings.TrimSpace(value) == "" {
+		err = errors.New("The new value is only whitespace. Leave it out to generate one.")
+	}
+	if err == nil {
+		err = checkSecretSize(value, b.cfg)
+	}
+	if err != nil {
+		b.reportFailure(cmd.ResponseURL, "Rejected rotate command", invalid(err), "event", "rotate", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+
+	vaultCtx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	meta, err := readSecretMetadata(vaultCtx, b.secrets, b.cfg.kvPaths(cmd.TeamID), secretID)
+	cancel()
+	if errors.Is(err, errSecretNotFound) {
+		rotationsTotal.WithLabelValues(outcomeNotFound).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found. It may have already been retrieved, revoked or swept away. Share the new value instead.", secretID))
+		return
+	}
+	if err != nil {
+		rotationsTotal.WithLabelValues(outcomeError).Inc()
+		b.reportFailure(cmd.ResponseURL, "Failed to read secret metadata from Vault", storeFailure("rotate the secret", err), "event", "rotate", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+	if meta.SharedBy != cmd.UserID {
+		rotationsTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Only the person who shared this secret can rotate it.")
+		return
+	}
+
+	req := shareRequest{
+		shareArgs:   rotatedArgs(meta, b.cfg),
+		replaces:    secretID,
+		teamID:      cmd.TeamID,
+		channelID:   secretChannel(secretID),
+		userID:      cmd.UserID,
+		userName:    cmd.UserName,
+		responseURL: cmd.ResponseURL,
+	}
+	if value == "" {
+		if req.secret, err = generatePassword(defaultPasswordLength, charsetFull); err != nil {
+			slog.Error("Failed to generate password", "event", "rotate", "secret_id", secretID, "user_id", cmd.UserID, "error", err)
+			rotationsTotal.WithLabelValues(outcomeError).Inc()
+			sendSlackResponse(b.slack, cmd.ResponseURL, "Failed to generate a new value. Please try again.")
+			return
+		}
+		req.description = fmt.Sprintf("A newly generated value replacing `%s` has", secretID)
+	} else {
+		req.secret = value
+		if req.fields, err = parseSecretFields(value); err != nil {
+			b.reportFailure(cmd.ResponseURL, "Rejected rotate command", invalid(err), "event", "rotate", "secret_id", secretID, "user_id", cmd.UserID)
+			return
+		}
+		req.description = fmt.Sprintf("The new value replacing `%s` has", secretID)
+	}
+
+	newID := b.shareSecret(ctx, req)
+	if newID == "" {
+		rotationsTotal.WithLabelValues(outcomeError).Inc()
+		return
+	}
+
+	vaultCtx, cancel = vaultContext(ctx, b.cfg)
+	defer cancel()
+	if err := b.destroySecret(vaultCtx, cmd.TeamID, secretID, meta); err != nil {
+		slog.Error("Failed to revoke rotated secret", "event", "rotate", "secret_id", secretID, "user_id", cmd.UserID, "error", err)
+		rotationsTotal.WithLabelValues(outcomeError).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("The old secret `%s` could not be revoked, so it can still be retrieved. Run `/revoke %s` to destroy it.", secretID, secretID))
+		return
+	}
+
+	rotationsTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret rotated", "event", "rotate", "secret_id", secretID, "new_secret_id", newID, "user_id", cmd.UserID)
+	audit(b.audit, AuditEvent{Action: auditRotate, SecretID: secretID, SharedBy: meta.SharedBy, Actor: cmd.UserID})
+	sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("The old secret `%s` has been revoked and can no longer be retrieved.", secretID))
+}
+
+// rotatedArgs returns the limits that the secret shared with meta was shared
+// with, bounded by the current configuration, for its replacement.
+func rotatedArgs(meta secretMetadata, cfg *Config) shareArgs {
+	args := shareArgs{
+		ttl:    meta.ExpiresAt.Sub(meta.CreatedAt),
+		uses:   meta.UsesRemaining,
+		notify: meta.Notify,
+		burn:   meta.Burn,
+		to:     meta.AllowedUsers,
+		label:  meta.Label,
+		// The recipient already knows the passphrase, so the new value
+		// keeps it.
+		passphraseHash: meta.PassphraseHash,
+	}
+	if args.passphraseHash != "" {
+		args.format = formatURL
+	}
+	if args.ttl <= 0 {
+		args.ttl = cfg.defaultTTL()
+	}
+	args.ttl = min(args.ttl, cfg.MaxTTL)
+	if args.burn || (args.uses == 0 && !cfg.AllowUnlimitedUses) {
+		args.uses = 1
+	}
+	args.uses = min(args.uses, cfg.MaxUses)
+	return args
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestRotateCommand(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	paths := b.cfg.kvPaths("")
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify --ttl 2h --uses 2 hunter2", UserID: "U1", ResponseURL: responseURL})
+	if len(tokens.created) != 1 {
+		t.Fatalf("tokens created = %d, want 1", len(tokens.created))
+	}
+	oldID := tokens.created[0].Metadata["secret_id"]
+
+	b.handleRotateCommand(context.Background(), slack.SlashCommand{Command: "/rotate", Text: oldID + " hunter3", UserID: "U2", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], "Only the person who shared") {
+		t.Fatalf("reply to another user = %q, want a denial", got[len(got)-1])
+	}
+
+	b.handleRotateCommand(context.Background(), slack.SlashCommand{Command: "/rotate", Text: oldID + " hunter3", UserID: "U1", ResponseURL: responseURL})
+	if len(tokens.created) != 2 {
+		t.Fatalf("tokens created = %d, want a new token for the rotated secret", len(tokens.created))
+	}
+	newID := tokens.created[1].Metadata["secret_id"]
+	if newID == oldID {
+		t.Fatal("rotated secret kept its ID")
+	}
+	if tokens.created[1].TTL != "2h0m0s" || tokens.created[1].NumUses != 2 {
+		t.Errorf("new token = %+v, want the old 2h and 2 uses", tokens.created[1])
+	}
+	if stored, err := readSecret(context.Background(), store, paths, newID, nil); err != nil || stored.Text != "hunter3" {
+		t.Errorf("new secret = %q, %v, want hunter3", stored.Text, err)
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, oldID); !errors.Is(err, errSecretNotFound) {
+		t.Errorf("old secret metadata err = %v, want it deleted", err)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-1"}) {
+		t.Errorf("revoked = %q, want the old token", tokens.revoked)
+	}
+	got := replies()
+	if !strings.Contains(got[len(got)-2], "The new value replacing `"+oldID+"` has been securely shared") {
+		t.Errorf("reply = %q, want the new link", got[len(got)-2])
+	}
+	if !strings.Contains(got[len(got)-1], "has been revoked") {
+		t.Errorf("reply = %q, want the old secret revoked", got[len(got)-1])
+	}
+}
+
+func TestRotateCommandGeneratesValue(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	paths := b.cfg.kvPaths("")
+
+	created := time.Now()
+	meta := secretMetadata{SharedBy: "U1", CreatedAt: created, ExpiresAt: created.Add(time.Hour), UsesRemaining: 1, Burn: true}
+	if err := writeSecretMetadata(context.Background(), store, paths, "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+
+	b.handleRotateCommand(context.Background(), slack.SlashCommand{Command: "/rotate", Text: "secret-1", UserID: "U1", ResponseURL: responseURL})
+	if len(tokens.created) != 1 {
+		t.Fatalf("tokens created = %d, want 1", len(tokens.created))
+	}
+	newID := tokens.created[0].Metadata["secret_id"]
+	stored, err := readSecret(context.Background(), store, paths, newID, nil)
+	if err != nil || len(stored.Text) != defaultPasswordLength {
+		t.Errorf("new secret = %q, %v, want a generated password", stored.Text, err)
+	}
+	if rotated, err := readSecretMetadata(context.Background(), store, paths, newID); err != nil || !rotated.Burn {
+		t.Errorf("new metadata = %+v, %v, want --burn kept", rotated, err)
+	}
+	if got := replies(); !strings.Contains(got[0], "A newly generated value replacing `secret-1`") {
+		t.Errorf("reply = %q, want the generated value described", got[0])
+	}
+
+	b.handleRotateCommand(context.Background(), slack.SlashCommand{Command: "/rotate", Text: "secret-2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], "No secret with ID `secret-2`") {
+		t.Errorf("reply = %q, want not found", got[len(got)-1])
+	}
+
+	b.handleRotateCommand(context.Background(), slack.SlashCommand{Command: "/rotate", Text: newID + ` "  "`, UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], "only whitespace") || len(tokens.created) != 1 {
+		t.Errorf("reply = %q, want a blank new value rejected", got[len(got)-1])
+	}
+}
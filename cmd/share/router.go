@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+)
+
+// HandlerFunc handles a slash command.
+type HandlerFunc func(ctx context.Context, cmd slack.SlashCommand)
+
+// CommandRouter dispatches slash commands to the handler registered for
+// their name.
+type CommandRouter struct {
+	handlers map[string]HandlerFunc
+	// unknown handles commands that have no registered handler.
+	unknown HandlerFunc
+}
+
+func newCommandRouter(unknown HandlerFunc) *CommandRouter {
+	return &CommandRouter{handlers: map[string]HandlerFunc{}, unknown: unknown}
+}
+
+// Register routes the command called name, including its leading slash, to
+// handler. It panics if name is already registered.
+func (r *CommandRouter) Register(name string, handler HandlerFunc) {
+	if _, ok := r.handlers[name]; ok {
+		panic(fmt.Sprintf("command %s registered twice", name))
+	}
+	r.handlers[name] = handler
+}
+
+// Dispatch runs the handler for cmd with ctx, which bounds the whole
+// command.
+func (r *CommandRouter) Dispatch(ctx context.Context, cmd slack.SlashCommand) {
+	handler, ok := r.handlers[cmd.Command]
+	if !ok {
+		handler = r.unknown
+	}
+	handler(ctx, cmd)
+}
+
+// newBotRouter registers every command in the registry against b. Commands
+// that b's FeatureFlags turn off reply that they are disabled.
+func newBotRouter(b *bot) *CommandRouter {
+	r := newCommandRouter(b.handleUnknownCommand)
+	for _, c := range commands(b.cfg) {
+		run := c.run
+		r.Register(c.name, func(ctx context.Context, cmd slack.SlashCommand) {
+			if !b.cfg.FeatureFlags.Enabled(cmd.Command) {
+				b.handleDisabledCommand(ctx, cmd)
+				return
+			}
+			run(b, ctx, cmd)
+		})
+	}
+	return r
+}
+
+func (b *bot) handleUnknownCommand(ctx context.Context, cmd slack.SlashCommand) {
+	slog.Warn("Unsupported command", "command", sanitizedCommand(cmd))
+	sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Unknown command `%s`. Try `/help`.", cmd.Command))
+}
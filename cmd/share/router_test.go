@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestCommandRouterDispatch(t *testing.T) {
+	var got []string
+	r := newCommandRouter(func(_ context.Context, cmd slack.SlashCommand) { got = append(got, "unknown "+cmd.Command) })
+	r.Register("/share", func(_ context.Context, cmd slack.SlashCommand) { got = append(got, "share "+cmd.Text) })
+	r.Register("/help", func(_ context.Context, cmd slack.SlashCommand) { got = append(got, "help") })
+
+	r.Dispatch(context.Background(), slack.SlashCommand{Command: "/share", Text: "x"})
+	r.Dispatch(context.Background(), slack.SlashCommand{Command: "/help"})
+	r.Dispatch(context.Background(), slack.SlashCommand{Command: "/nope"})
+
+	want := []string{"share x", "help", "unknown /nope"}
+	if len(got) != len(want) {
+		t.Fatalf("dispatched %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dispatch %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCommandRouterRegisterTwicePanics(t *testing.T) {
+	r := newCommandRouter(func(context.Context, slack.SlashCommand) {})
+	r.Register("/share", func(context.Context, slack.SlashCommand) {})
+	defer func() {
+		if recover() == nil {
+			t.Error("second Register did not panic")
+		}
+	}()
+	r.Register("/share", func(context.Context, slack.SlashCommand) {})
+}
+
+func TestBotRouterRegistersEveryCommand(t *testing.T) {
+	b := &bot{cfg: &Config{}}
+	r := newBotRouter(b)
+	for _, c := range commands(b.cfg) {
+		if _, ok := r.handlers[c.name]; !ok {
+			t.Errorf("%s is not registered", c.name)
+		}
+	}
+}
+
+func TestBotRouterDisabledCommand(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+	var err error
+	if b.cfg.FeatureFlags.Disabled, err = parseDisabledCommands("generate, /share-channel", []string{"/share", "/share-channel", "/generate", "/help"}); err != nil {
+		t.Fatal(err)
+	}
+	r := newBotRouter(b)
+
+	r.Dispatch(context.Background(), slack.SlashCommand{Command: "/generate", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "This command is disabled") {
+		t.Errorf("replies = %q, want /generate refused", got)
+	}
+	r.Dispatch(context.Background(), slack.SlashCommand{Command: "/help", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if help := got[len(got)-1]; strings.Contains(help, "`/generate") || strings.Contains(help, "`/share-channel") || !strings.Contains(help, "`/share ") {
+		t.Errorf("/help = %q, want only the enabled commands", help)
+	}
+
+	if _, err := parseDisabledCommands("/nope", []string{"/share"}); err == nil {
+		t.Error("parseDisabledCommands(/nope) succeeded, want error")
+	}
+}
+
+func TestShareModalDisabled(t *testing.T) {
+	tokens := &fakeTokenCreator{}
+	b, _, _ := newTestBot(t, newFakeSecretStore(), tokens)
+	b.cfg.FeatureFlags.Disabled = []string{"/share"}
+
+	callback := slack.InteractionCallback{Type: slack.InteractionTypeViewSubmission, User: slack.User{ID: "U1"}, View: slack.View{CallbackID: shareModalCallbackID}}
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{shareSecretBlockID: {shareInputActionID: {Value: "hunter2"}}}}
+	var acked []interface{}
+	b.handleViewSubmission(func(payload ...interface{}) { acked = payload }, callback)
+	b.inflight.Wait()
+	if len(acked) != 1 {
+		t.Fatalf("acknowledgement = %+v, want one with errors", acked)
+	}
+	resp, ok := acked[0].(*slack.ViewSubmissionResponse)
+	if !ok || resp.Errors[shareSecretBlockID] != disabledMessage("/share") || len(tokens.created) != 0 {
+		t.Errorf("acknowledgement = %+v, %d tokens created, want the form refused", acked, len(tokens.created))
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// selfTestUser is recorded as the sharer of the self-test's throwaway
+// secret, so that it cannot be mistaken for anyone's real share.
+const selfTestUser = "hush-self-test"
+
+// selfTestTTL is the lifetime of the throwaway secret and its token. Should
+// the cleanup fail, the sweep removes the secret once this has passed.
+const selfTestTTL = time.Minute
+
+// runSelfTest shares a throwaway secret the way /share does, reads it back
+// with the token a recipient would be given, then revokes the token and
+// destroys the secret, so that missing Vault permissions or a bad policy
+// are found at startup rather than on the first real share. recipient
+// returns a store that reads from Vault with a recipient's token. The
+// secret is cleaned up even if a step fails.
+func runSelfTest(ctx context.Context, cfg *Config, store SecretStore, tokens TokenCreator, recipient func(token string) (SecretStore, error)) (err error) {
+	ctx, cancel := vaultContext(ctx, cfg)
+	defer cancel()
+	paths := cfg.kvPaths("")
+	cipher := newSecretCipher(cfg, store)
+
+	secretID, err := newSecretID()
+	if err != nil {
+		return fmt.Errorf("generating secret ID: %w", err)
+	}
+	value, err := generatePassword(defaultPasswordLength, charsetFull)
+	if err != nil {
+		return fmt.Errorf("generating secret: %w", err)
+	}
+
+	var accessor string
+	defer func() {
+		// Clean up with a fresh context, since the failure may have been
+		// the deadline.
+		cleanupCtx, cancel := vaultContext(context.WithoutCancel(ctx), cfg)
+		defer cancel()
+		if accessor != "" {
+			if revokeErr := revokeTokenAccessor(cleanupCtx, tokens, accessor); revokeErr != nil {
+				err = errors.Join(err, fmt.Errorf("revoking token: %w", revokeErr))
+			}
+		}
+		if deleteErr := deleteSecret(cleanupCtx, store, paths, secretID); deleteErr != nil {
+			err = errors.Join(err, fmt.Errorf("deleting secret: %w", deleteErr))
+		}
+	}()
+
+	if err := storeSecret(ctx, store, paths, secretID, secretPayload{Text: value}, cipher); err != nil {
+		return fmt.Errorf("writing secret: %w", err)
+	}
+	now := time.Now()
+	meta := secretMetadata{SharedBy: selfTestUser, CreatedAt: now, ExpiresAt: now.Add(selfTestTTL), UsesRemaining: 1}
+	if err := writeSecretMetadata(ctx, store, paths, secretID, meta); err != nil {
+		return fmt.Errorf("writing secret metadata: %w", err)
+	}
+	if err := writeSecretPolicy(ctx, store, paths, secretID); err != nil {
+		return fmt.Errorf("writing secret policy: %w", err)
+	}
+	var token string
+	token, accessor, err = createVaultToken(ctx, tokens, cfg, append([]string{paths.policyName(secretID)}, cfg.TokenPolicies...), secretID, selfTestUser, selfTestUser, selfTestTTL, 1)
+	if err != nil {
+		return fmt.Errorf("creating token: %w", err)
+	}
+
+	reader, err := recipient(token)
+	if err != nil {
+		return fmt.Errorf("creating recipient client: %w", err)
+	}
+	got, err := readSecret(ctx, reader, paths, secretID, cipher)
+	if err != nil {
+		return fmt.Errorf("reading secret with its token: %w", err)
+	}
+	if got.Text != value {
+		return errors.New("reading secret with its token returned a different value")
+	}
+
+	if err := revokeTokenAccessor(ctx, tokens, accessor); err != nil {
+		return fmt.Errorf("revoking token: %w", err)
+	}
+	accessor = ""
+	if err := deleteSecret(ctx, store, paths, secretID); err != nil {
+		return fmt.Errorf("deleting secret: %w", err)
+	}
+	return nil
+}
+
+// selfTest runs runSelfTest with vaultClient, reading the secret back with
+// a clone of it that carries the recipient's token.
+func selfTest(ctx context.Context, vaultClient *api.Client, cfg *Config) error {
+	start := time.Now()
+	err := runSelfTest(ctx, cfg, vaultStore(vaultClient, cfg), vaultTokens(vaultClient, cfg), func(token string) (SecretStore, error) {
+		client, err := vaultClient.Clone()
+		if err != nil {
+			return nil, err
+		}
+		client.SetToken(token)
+		return vaultStore(client, cfg), nil
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info("Self-test passed", "event", "self_test", "duration", time.Since(start))
+	return nil
+}
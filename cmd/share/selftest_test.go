@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	cfg := &Config{VaultSecretsMount: defaultSecretsMount, VaultKVVersion: defaultKVVersion, VaultTimeout: defaultVaultTimeout}
+
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	if err := runSelfTest(context.Background(), cfg, store, tokens, func(string) (SecretStore, error) { return store, nil }); err != nil {
+		t.Fatalf("runSelfTest() error = %v", err)
+	}
+	if len(tokens.created) != 1 || tokens.created[0].NumUses != 1 || tokens.created[0].Metadata["shared_by"] != selfTestUser {
+		t.Errorf("tokens created = %+v, want one single-use token for the self-test", tokens.created)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-1"}) {
+		t.Errorf("revoked = %q, want the self-test token", tokens.revoked)
+	}
+	if ids, err := listSecretIDs(context.Background(), store, cfg.kvPaths("")); err != nil || len(ids) != 0 {
+		t.Errorf("secrets left = %q, %v, want the throwaway secret destroyed", ids, err)
+	}
+
+	// A recipient token that cannot read the secret fails the test, and
+	// the secret and token are still cleaned up.
+	store = newFakeSecretStore()
+	tokens = &fakeTokenCreator{}
+	err := runSelfTest(context.Background(), cfg, store, tokens, func(string) (SecretStore, error) { return newFakeSecretStore(), nil })
+	if err == nil || !strings.Contains(err.Error(), "reading secret with its token") {
+		t.Errorf("runSelfTest() with an unreadable secret error = %v", err)
+	}
+	if !slices.Equal(tokens.revoked, []string{"accessor-1"}) {
+		t.Errorf("revoked = %q, want the token revoked after a failure", tokens.revoked)
+	}
+	if ids, _ := listSecretIDs(context.Background(), store, cfg.kvPaths("")); len(ids) != 0 {
+		t.Errorf("secrets left after a failure = %q", ids)
+	}
+
+	store = newFakeSecretStore()
+	store.failWrites = "sys/policies/"
+	tokens = &fakeTokenCreator{}
+	err = runSelfTest(context.Background(), cfg, store, tokens, func(string) (SecretStore, error) { return store, nil })
+	if err == nil || !strings.Contains(err.Error(), "writing secret policy") || len(tokens.created) != 0 {
+		t.Errorf("runSelfTest() without policy permissions error = %v, tokens = %d", err, len(tokens.created))
+	}
+}
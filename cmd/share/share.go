@@ -1,168 +1,1206 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/gorilla/websocket"
 	"github.com/hashicorp/vault/api"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	vaultSecretsPath = "secrets/data/shared"
-	tokenTTL         = "1h"
-	tokenUses        = 2
+	defaultTokenTTL  = time.Hour
+	defaultMaxTTL    = 24 * time.Hour
+	defaultTokenUses = 1
+	defaultMaxUses   = 10
+	shutdownTimeout  = 10 * time.Second
+
+	defaultRetrievalAddr  = ":8080"
+	defaultMaxFileBytes   = 1 << 20
+	defaultMaxSecretBytes = 64 << 10
+
+	defaultShareRateLimit = 10
 )
 
 func main() {
+	configFile := flag.String("config", "", "YAML file of configuration `variables`; the environment overrides it")
+	flag.Parse()
+	// The create subcommand shares one secret from a script and exits.
+	create := flag.Arg(0) == "create"
+
 	// Load configuration
-	slackAppToken := os.Getenv("SLACK_APP_TOKEN")
-	slackBotToken := os.Getenv("SLACK_BOT_TOKEN")
-	vaultAddr := os.Getenv("VAULT_ADDR")
-	vaultToken := os.Getenv("VAULT_TOKEN")
+	if *configFile != "" {
+		if err := loadConfigFile(*configFile); err != nil {
+			fatal("Invalid configuration file", "error", err)
+		}
+	}
+	load := LoadConfig
+	if create {
+		load = loadCLIConfig
+	}
+	cfg, err := load()
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+
+	// With create, stdout carries only its result.
+	logOutput := os.Stdout
+	if create {
+		logOutput = os.Stderr
+	}
+	handler := slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: cfg.LogLevel})
+	slog.SetDefault(slog.New(handler))
 
-	if slackAppToken == "" || slackBotToken == "" || vaultAddr == "" || vaultToken == "" {
-		log.Fatalf("Missing required environment variables: SLACK_APP_TOKEN, SLACK_BOT_TOKEN, VAULT_ADDR, VAULT_TOKEN")
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracingEnabled() {
+		if shutdownTracing, err = setupTracing(context.Background()); err != nil {
+			fatal("Failed to set up tracing", "error", err)
+		}
+		slog.Info("Exporting traces over OTLP")
 	}
 
 	// Initialize clients
+	slackDebug := cfg.SlackDebug
+	slackLogger := newSlackLogger(handler)
 	slackClient := slack.New(
-		slackBotToken,
-		slack.OptionDebug(true),
-		slack.OptionLog(log.New(os.Stdout, "slack: ", log.Lshortfile)),
-		slack.OptionAppLevelToken(slackAppToken),
+		cfg.SlackBotToken,
+		slack.OptionDebug(slackDebug),
+		slack.OptionLog(slackLogger),
+		slack.OptionAppLevelToken(cfg.SlackAppToken),
+	)
+	// The client reconnects by itself when the connection drops.
+	socketClient := socketmode.New(
+		slackClient,
+		socketmode.OptionDebug(slackDebug),
+		socketmode.OptionLog(slackLogger),
+		socketmode.OptionPingInterval(cfg.SlackPingTimeout),
+		socketmode.OptionDialer(&websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: cfg.SlackConnectTimeout}),
 	)
-	socketClient := socketmode.New(slackClient)
+	var socket *socketStatus
+	if cfg.Mode != modeHTTP {
+		socket = newSocketStatus(cfg.SlackPingTimeout)
+	}
+	ws := newWorkspaces(cfg, slackClient, slack.OptionDebug(slackDebug), slack.OptionLog(slackLogger))
+	if cfg.multiWorkspace() {
+		slog.Info("Serving several Slack workspaces", "team_ids", cfg.teamIDs())
+	}
+
+	vaultClient, err := newVaultClient(cfg)
+	if err != nil {
+		fatal("Failed to create Vault client", "error", err)
+	}
+	// A single create has no use for a degraded start.
+	vaultLogin, degraded, err := connectVault(context.Background(), vaultClient, cfg, cfg.VaultStartup == startupDegrade && !create)
+	if err != nil {
+		fatal("Failed to log in to Vault", "error", err)
+	}
+	if vaultLogin != nil {
+		slog.Info("Logged in to Vault with AppRole", "lease_duration", vaultLogin.Auth.LeaseDuration, "renewable", vaultLogin.Auth.Renewable)
+	}
+
+	if cfg.VaultSkipVerify {
+		slog.Warn("Vault TLS certificate verification is disabled")
+	}
+	if cfg.EncryptionKey != nil {
+		slog.Info("Client-side encryption is enabled")
+	}
+	if cfg.TransitKey != "" {
+		slog.Info("Transit encryption is enabled", "mount", cfg.TransitMount, "key", cfg.TransitKey)
+	}
+
+	if cfg.LinkSigningKey == nil {
+		cfg.LinkSigningKey = make([]byte, 32)
+		if _, err := rand.Read(cfg.LinkSigningKey); err != nil {
+			fatal("Failed to generate link signing key", "error", err)
+		}
+		slog.Warn("LINK_SIGNING_KEY is not set; links sent with --to stop working when the bot restarts")
+	}
+	if cfg.DryRun {
+		slog.Warn("Dry run is active: secrets are not written to Vault and retrieval links will not work")
+	}
 
-	vaultClient, err := newVaultClient(vaultAddr, vaultToken)
+	auditLogger, err := newAuditLogger(cfg, vaultClient)
 	if err != nil {
-		log.Fatalf("Failed to create Vault client: %v", err)
+		fatal("Failed to create audit logger", "error", err)
 	}
 
-	// Start event listener
-	go handleSocketMode(socketClient, vaultClient)
-	log.Println("Slack Bot and Vault integration is running...")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if create {
+		code := runCreateCommand(ctx, vaultClient, cfg, auditLogger, flag.Args()[1:])
+		stop()
+		shutdownTracing(context.Background())
+		os.Exit(code)
+	}
 
-	socketClient.Run()
+	// Prove that the bot can share and revoke before it accepts commands.
+	// A degraded bot does so once Vault can be reached.
+	if cfg.SelfTest && cfg.DryRun {
+		slog.Warn("SELF_TEST is ignored in a dry run")
+	} else if cfg.SelfTest && !degraded {
+		if err := selfTest(ctx, vaultClient, cfg); err != nil {
+			fatal("Self-test failed", "error", err)
+		}
+	}
+
+	// Start the retrieval server
+	state := newStateStore(cfg)
+	retrieval := newRetrievalServer(vaultClient, ws, cfg, auditLogger, state)
+	go func() {
+		if err := retrieval.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Retrieval server failed", "error", err)
+		}
+	}()
+
+	var metrics *http.Server
+	if cfg.MetricsAddr != "" {
+		metrics = newMetricsServer(cfg)
+		go func() {
+			if err := metrics.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("Metrics server failed", "error", err)
+			}
+		}()
+	}
+
+	var health *http.Server
+	if cfg.HealthAddr != "" {
+		health = newHealthServer(vaultClient, ws, cfg, socket)
+		go func() {
+			if err := health.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal("Health server failed", "error", err)
+			}
+		}()
+	}
+
+	// Start receiving from Slack. The socket mode connection is given its
+	// own context so that it stays open while in-flight commands finish
+	// during shutdown.
+	b := newBot(socketClient, ws, vaultClient, cfg, auditLogger, state)
+	b.socket = socket
+	runCtx, cancelRun := context.WithCancel(context.Background())
+	defer cancelRun()
+	runErr := make(chan error, 1)
+	listenerDone := make(chan struct{})
+	var slackHTTP *http.Server
+	if cfg.Mode == modeHTTP {
+		close(listenerDone)
+		slackHTTP = newSlackHTTPServer(b)
+		go func() {
+			runErr <- slackHTTP.ListenAndServe()
+		}()
+	} else {
+		go func() {
+			runErr <- socketClient.RunContext(runCtx)
+		}()
+		go func() {
+			defer close(listenerDone)
+			b.handleSocketMode(ctx)
+		}()
+	}
+
+	// Once Vault can be reached, keep the bot's own Vault token alive,
+	// delete secrets that were never retrieved once they expire, and clean
+	// up after shares that failed part-way
+	vaultReady := func(login *api.Secret) {
+		if !cfg.DryRun {
+			go renewVaultToken(ctx, vaultClient, cfg, login)
+			go newSweeper(b).run(ctx)
+			go newReconciler(b).run(ctx)
+		}
+	}
+	if degraded {
+		go func() {
+			login, err := leaveDegraded(ctx, vaultClient, cfg)
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			if err != nil {
+				fatal("Self-test failed", "error", err)
+			}
+			vaultReady(login)
+		}()
+	} else {
+		vaultReady(vaultLogin)
+	}
+	slog.Info("Slack Bot and Vault integration is running...", "mode", cfg.Mode)
+
+	select {
+	case <-ctx.Done():
+	case err := <-runErr:
+		slog.Error("Stopped receiving from Slack", "mode", cfg.Mode, "error", err)
+		stop()
+	}
 
 	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down...")
+	slog.Info("Shutting down...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
+	<-listenerDone
+	if slackHTTP != nil {
+		if err := slackHTTP.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down Slack HTTP server", "error", err)
+		}
+	}
+	if !waitTimeout(&b.inflight, shutdownTimeout) {
+		slog.Warn("Timed out waiting for in-flight commands", "timeout", shutdownTimeout)
+	}
+	if cfg.RevokeOnShutdown {
+		b.revokeIssuedTokens()
+	}
+	if err := retrieval.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down retrieval server", "error", err)
+	}
+	if metrics != nil {
+		if err := metrics.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down metrics server", "error", err)
+		}
+	}
+	if health != nil {
+		if err := health.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Failed to shut down health server", "error", err)
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
+	}
+	cancelRun()
+}
+
+// fatal logs msg at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
-func newVaultClient(addr, token string) (*api.Client, error) {
-	config := api.DefaultConfig()
-	config.Address = addr
+// waitTimeout waits for wg and reports whether it finished before timeout.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
 
-	client, err := api.NewClient(config)
-	if err != nil {
-		return nil, err
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
 	}
-	client.SetToken(token)
-	return client, nil
 }
 
-func handleSocketMode(client *socketmode.Client, vaultClient *api.Client) {
-	for evt := range client.Events {
+func (b *bot) handleSocketMode(ctx context.Context) {
+	for {
+		var evt socketmode.Event
+		select {
+		case <-ctx.Done():
+			return
+		case evt = <-b.slack.Events:
+		}
+
 		switch evt.Type {
 		case socketmode.EventTypeSlashCommand:
 			cmd, ok := evt.Data.(slack.SlashCommand)
 			if !ok {
-				log.Println("Ignored unsupported slash command")
+				slog.Warn("Ignored unsupported slash command", "event_type", evt.Type)
 				continue
 			}
 
-			client.Ack(*evt.Request)
-			log.Printf("Event received: %s, Data: %+v", evt.Type, evt.Data)
+			b.slack.Ack(*evt.Request)
+			b.dispatchCommand(cmd)
+		case socketmode.EventTypeInteractive:
+			callback, ok := evt.Data.(slack.InteractionCallback)
+			if !ok {
+				slog.Warn("Ignored unsupported interactive payload", "event_type", evt.Type)
+				continue
+			}
 
-			switch cmd.Command {
-			case "/share":
-				handleShareCommand(client, vaultClient, cmd)
-			default:
-				log.Printf("Unsupported command: %s", cmd.Command)
+			b.handleInteraction(b.socketAck(evt.Request), callback)
+		case socketmode.EventTypeEventsAPI:
+			event, ok := evt.Data.(slackevents.EventsAPIEvent)
+			if !ok {
+				slog.Warn("Ignored unsupported event", "event_type", evt.Type)
+				continue
 			}
+
+			b.slack.Ack(*evt.Request)
+			b.handleEventsAPI(event)
 		default:
-			log.Printf("Ignored unsupported event type: %s", evt.Type)
+			if b.socket == nil || !b.socket.observe(evt) {
+				slog.Debug("Ignored unsupported event type", "event_type", evt.Type)
+			}
 		}
 	}
 }
 
-func handleShareCommand(client *socketmode.Client, vaultClient *api.Client, cmd slack.SlashCommand) {
-	secret := cmd.Text
-	if secret == "" {
-		sendSlackResponse(client, cmd.ResponseURL, "Please provide a secret to share. Usage: `/share <secret>`")
+// dispatchCommand runs the handler for an acknowledged slash command in the
+// background. A command Slack delivers again, recognised by its trigger ID,
+// is dropped: the first delivery already replied, and running it twice would
+// share the secret twice.
+func (b *bot) dispatchCommand(cmd slack.SlashCommand) {
+	slog.Info("Event received", "event_type", socketmode.EventTypeSlashCommand, "command", sanitizedCommand(cmd))
+	if cmd.TriggerID != "" && !b.commandsSeen.First(cmd.TriggerID) {
+		slog.Warn("Ignored redelivered slash command", "command", cmd.Command, "user_id", cmd.UserID, "trigger_id", cmd.TriggerID)
+		return
+	}
+	cmd.TeamID = b.workspaces.installation(cmd.EnterpriseID, cmd.TeamID)
+	if _, ok := b.workspaces.client(cmd.TeamID); !ok {
+		slog.Warn("Ignored command from unknown workspace", "command", cmd.Command, "user_id", cmd.UserID, "team_id", cmd.TeamID)
+		sendSlackResponse(b.slack, cmd.ResponseURL, unknownWorkspaceMessage)
 		return
 	}
 
-	secretID := fmt.Sprintf("secret-%d", time.Now().UnixNano())
-	secretPath := fmt.Sprintf("%s/%s", vaultSecretsPath, secretID)
+	b.runHandler("command", cmd.UserID, cmd.ResponseURL, func(ctx context.Context) { b.router.Dispatch(ctx, cmd) })
+}
 
-	// Store secret in Vault
-	if err := storeSecret(vaultClient, secretPath, secret); err != nil {
-		log.Printf("Failed to store secret in Vault: %v", err)
-		sendSlackResponse(client, cmd.ResponseURL, "Failed to store the secret. Please try again.")
+func (b *bot) handleShareCommand(ctx context.Context, cmd slack.SlashCommand) {
+	if !b.allowShareChannel(cmd) {
 		return
 	}
+	// Without arguments, collect the secret in a modal so that it never
+	// appears in the message composer or history.
+	if strings.TrimSpace(cmd.Text) == "" {
+		b.openShareModal(cmd)
+		return
+	}
+	b.shareFromCommand(ctx, cmd, false)
+}
 
-	// Create short-lived token
-	token, err := createVaultToken(vaultClient, secretID)
+// handleShareChannelCommand shares a secret like /share, but posts its link
+// for everyone in the channel to see.
+func (b *bot) handleShareChannelCommand(ctx context.Context, cmd slack.SlashCommand) {
+	if !b.allowShareChannel(cmd) {
+		return
+	}
+	b.shareFromCommand(ctx, cmd, true)
+}
+
+// shareFromCommand shares the secret given in the text of cmd, posting its
+// link in the channel when inChannel is set.
+func (b *bot) shareFromCommand(ctx context.Context, cmd slack.SlashCommand, inChannel bool) {
+	if !b.shareLimiter.Allow(cmd.UserID) {
+		slog.Warn("Share rate limit exceeded", "event", "share", "user_id", cmd.UserID)
+		sharesTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, rateLimitedMessage)
+		return
+	}
+
+	args, err := parseShareArgs(cmd.Text, b.cfg)
 	if err != nil {
-		log.Printf("Failed to create short-lived token: %v", err)
-		sendSlackResponse(client, cmd.ResponseURL, "Failed to create a secure access token. Please try again.")
+		b.reportFailure(cmd.ResponseURL, "Rejected share command", invalid(err), "event", "share", "user_id", cmd.UserID)
+		return
+	}
+
+	// A secret of only spaces or newlines is almost certainly a mistake, but
+	// one with content keeps its surrounding whitespace as typed.
+	if strings.TrimSpace(args.secret) == "" {
+		problem := "Please provide a secret to share."
+		if args.secret != "" {
+			problem = "The secret is only whitespace, so there is nothing to share."
+		}
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("%s Usage: `%s [--ttl 30m] [--uses 1] [--to @user] [--no-notify] [--burn] <secret>`. Run `/help` for all options.", problem, cmd.Command))
+		return
+	}
+	if inChannel && args.code {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "`--code` is for reading a secret out to someone, so it cannot be combined with `/share-channel`. Use `/share --code` instead.")
+		return
+	}
+	if inChannel && len(args.to) > 0 {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "`--to` sends personal links by DM, so it cannot be combined with `/share-channel`. Use `/share --to` instead.")
+		return
+	}
+	if inChannel && len(args.groups) > 0 {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "`--group` sends personal links by DM, so it cannot be combined with `/share-channel`. Use `/share --group` instead.")
+		return
+	}
+	if inChannel && args.email != "" {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "`--email` sends the link by email, so it cannot be combined with `/share-channel`. Use `/share --email` instead.")
+		return
+	}
+	if args.email != "" && !b.emailLimiter.Allow("email:"+cmd.UserID) {
+		slog.Warn("Email rate limit exceeded", "event", "share", "user_id", cmd.UserID)
+		emailsTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("You can send at most %d %s by email an hour. Please try again later.", b.cfg.EmailRateLimit, plural(b.cfg.EmailRateLimit, "secret", "secrets")))
+		return
+	}
+	if args.fields, err = parseSecretFields(args.secret); err != nil {
+		b.reportFailure(cmd.ResponseURL, "Rejected share command", invalid(err), "event", "share", "user_id", cmd.UserID)
+		return
+	}
+
+	req := shareRequest{
+		shareArgs:   args,
+		inChannel:   inChannel,
+		teamID:      cmd.TeamID,
+		channelID:   cmd.ChannelID,
+		userID:      cmd.UserID,
+		userName:    cmd.UserName,
+		responseURL: cmd.ResponseURL,
+	}
+	if needsConfirmation(args.secret, b.cfg) {
+		b.confirmShare(req)
 		return
 	}
+	b.shareSecret(ctx, req)
+}
+
+// burnNote is added to the reply to a share made with --burn.
+const burnNote = "It will be destroyed as soon as it is viewed. The retrieval page asks for confirmation first, so link previews cannot use it up."
 
-	// Generate Vault URL
-	vaultURL := fmt.Sprintf("%s/v1/%s/%s?token=%s", vaultClient.Address(), vaultSecretsPath, secretID, token)
-	response := fmt.Sprintf("Your secret has been securely shared and is valid for 1 hour: \n\n```curl --header \"X-Vault-Token: %s\" --request GET %s```", token, vaultURL)
-	sendSlackResponse(client, cmd.ResponseURL, response)
+// usesNote explains to the sharer what counts against the uses a secret
+// has.
+func usesNote(uses int) string {
+	return fmt.Sprintf("It has %s. Only revealing it on the retrieval page or reading it with its Vault token spends one; opening the link or a link preview does not.", formatUsesLeft(uses))
 }
 
-func storeSecret(client *api.Client, path, secret string) error {
-	data := map[string]interface{}{
-		"data": map[string]string{
-			"secret": secret,
-		},
+// rateLimitedMessage is the reply to a user who is sharing too quickly.
+const rateLimitedMessage = "You're sharing secrets too quickly. Please slow down and try again in a minute."
+
+// shareRequest describes a secret to share and who is sharing it.
+type shareRequest struct {
+	shareArgs
+	// file, when set, is shared instead of shareArgs.secret.
+	file *secretFile
+	// description, when set, replaces "Your secret has" in the reply.
+	description string
+	// inChannel posts the link for everyone in the channel to see instead of
+	// only to the sharer.
+	inChannel bool
+	// warning, when set, is added to the sharer's reply, such as when a
+	// credential detector restricted the share.
+	warning string
+	// replaces, when set, is the ID of the secret this one rotates, so that
+	// recipients are told its value changed.
+	replaces string
+	// teamID is the Slack workspace the secret is shared in, and channelID,
+	// when known, the channel.
+	teamID      string
+	channelID   string
+	userID      string
+	userName    string
+	responseURL string
+}
+
+// shareSecret stores the secret in req, issues a short-lived token for it and
+// replies to the sharer with the retrieval instructions. It returns the ID
+// of the new secret, or "" if it was not shared.
+func (b *bot) shareSecret(ctx context.Context, req shareRequest) string {
+	ctx, span := tracer.Start(ctx, "share", trace.WithAttributes(attrUserID.String(req.userID), attrTeamID.String(req.teamID)))
+	outcome := outcomeError
+	defer func() { endSpan(span, outcome) }()
+
+	users, err := b.resolveRecipients(req.teamID, req.to)
+	var groupIDs, members []string
+	if err == nil {
+		groupIDs, members, err = b.resolveGroups(req.teamID, req.groups)
+	}
+	if err != nil {
+		outcome = outcomeDenied
+		sendSlackResponse(b.slack, req.responseURL, err.Error())
+		return ""
+	}
+	recipients := appendNew(users, members...)
+	secretID, meta, token, err := b.createShare(ctx, &req, recipients)
+	if err != nil {
+		if errors.Is(err, errValidation) {
+			outcome = outcomeDenied
+		}
+		b.reportFailure(req.responseURL, "Failed to share secret", err, "event", "share", "secret_id", secretID, "user_id", req.userID)
+		return ""
+	}
+	span.SetAttributes(attrSecretID.String(secretID))
+	outcome = outcomeSuccess
+
+	vaultURL := b.secretAPIURL(req.teamID, secretID)
+	pageURL := secretPageURL(b.cfg, req.teamID, secretID)
+	what := "Your secret has"
+	switch {
+	case req.description != "":
+		what = req.description
+	case req.file != nil:
+		what = fmt.Sprintf("Your file `%s` has", req.file.Name)
+	}
+	// Everything from here on replies to Slack.
+	_, respond := tracer.Start(ctx, "slack.respond")
+	defer respond.End()
+	if len(recipients) > 0 {
+		b.sendRecipientLinks(req, secretID, recipients, describeRecipients(users, groupIDs, recipients), what)
+		return secretID
+	}
+	if req.email != "" {
+		if !b.sendEmailLink(ctx, req, secretID, meta, pageURL, what) {
+			return ""
+		}
+		return secretID
+	}
+	if req.inChannel {
+		b.sendChannelLink(req, secretID, pageURL)
+		return secretID
 	}
-	_, err := client.Logical().Write(path, data)
-	return err
+
+	if req.passphraseHash != "" {
+		// Reading the secret with its token would skip the passphrase.
+		token, vaultURL = "", ""
+	}
+	data := shareMessageData{
+		Subject:  what,
+		SecretID: secretID,
+		URL:      pageURL,
+		TTL:      formatDuration(req.ttl),
+		Uses:     formatUses(req.uses),
+		Token:    token,
+		VaultURL: vaultURL,
+
+		Instructions: b.retrievalInstructions(req.format, req.teamID, secretID, token),
+	}
+	response, err := shareMessage(b.cfg, data)
+	if err != nil {
+		slog.Error("Failed to render share message template, using the default", "event", "share", "secret_id", secretID, "error", err)
+		response, _ = shareMessage(&Config{}, data)
+	}
+	if req.code {
+		response += "\n" + b.shortCodeNote(req, secretID)
+	}
+	if !req.expiresAt.IsZero() {
+		response += "\n" + expiryNote(req.expiresAt)
+	}
+	if req.burn {
+		response += "\n" + burnNote
+	} else if req.uses > 0 {
+		response += "\n" + usesNote(req.uses)
+	}
+	if req.passphraseHash != "" {
+		response += "\n" + passphraseNote
+	}
+	if req.warning != "" {
+		response += "\n" + req.warning
+	}
+	if req.file != nil {
+		response += "\nSlack keeps a copy of files uploaded through the form, so delete it from your Slack files once it has been retrieved."
+	}
+	if b.pointsAtVault(response) {
+		slog.Error("Share message points at Vault for an encrypted secret, sending only the link", "event", "share", "secret_id", secretID)
+		response = fmt.Sprintf("%s been securely shared, is valid for %s and can be retrieved %s. Open this link to view it:\n%s\nTo destroy it early, run `/revoke %s`.", what, data.TTL, data.Uses, pageURL, secretID)
+	}
+	sendSlackResponse(b.slack, req.responseURL, response)
+	return secretID
+}
+
+// pointsAtVault reports whether response, a reply about a secret, sends the
+// recipient straight to Vault although secrets are encrypted, so that they
+// would only get ciphertext back. Encrypted secrets must be read through
+// the retrieval server, which decrypts them.
+func (b *bot) pointsAtVault(response string) bool {
+	return b.cfg.encrypted() && strings.Contains(response, strings.TrimSuffix(b.vault.Address(), "/")+"/v1/")
+}
+
+// secretAPIURL returns the URL that reads secretID with its Vault token. It
+// always goes through the retrieval server: a read straight from Vault would
+// spend none of the uses in the secret's metadata and would never delete a
+// --burn secret, so the retrieval page could reveal it again.
+func (b *bot) secretAPIURL(teamID, secretID string) string {
+	return fmt.Sprintf("%s/v1/secrets/%s", retrievalBaseURL(b.cfg), secretURLPath(b.cfg, teamID, secretID))
 }
 
-func createVaultToken(client *api.Client, secretID string) (string, error) {
-	var notRenewable bool
-	tokenRequest := &api.TokenCreateRequest{
-		DisplayName: "Secret Share",
-		Policies:    []string{"shared-secrets"},
-		Metadata: map[string]string{
-			"secret_id": secretID,
-		},
-		TTL:       tokenTTL,
-		NumUses:   tokenUses,
-		Renewable: &notRenewable,
-		NoParent:  true,
+// sendRecipientLinks DMs each recipient of a restricted secret their
+// personal link and tells the sharer it went to who. The shared token is not
+// shown, since it would bypass the restriction. Links beyond the first
+// groupDMBurst are sent by sendPacedLinks once the sharer has had this
+// reply.
+func (b *bot) sendRecipientLinks(req shareRequest, secretID string, recipients []string, who, what string) {
+	first, rest := recipients[:min(len(recipients), groupDMBurst)], recipients[min(len(recipients), groupDMBurst):]
+	var failed []string
+	for _, id := range first {
+		if !b.sendRecipientLink(req, secretID, id) {
+			failed = append(failed, id)
+		}
+	}
+
+	response := fmt.Sprintf("%s been securely shared with %s, is valid for %s and can be retrieved %s. Each recipient has been sent a personal link by DM that only works for them.\nTo destroy it early, run `/revoke %s`.", what, who, formatDuration(req.ttl), formatUses(req.uses), secretID)
+	if !req.expiresAt.IsZero() {
+		response += "\n" + expiryNote(req.expiresAt)
+	}
+	if req.burn {
+		response += "\n" + burnNote
+	} else if req.uses > 0 {
+		response += "\n" + usesNote(req.uses)
+	}
+	if req.passphraseHash != "" {
+		response += "\n" + passphraseNote
+	}
+	if req.warning != "" {
+		response += "\n" + req.warning
+	}
+	switch {
+	case len(rest) > 0:
+		response += fmt.Sprintf("\n%d of the first %d personal links were delivered. The other %d are being sent now, and you will be told once they have gone out.", len(first)-len(failed), len(first), len(rest))
+	case len(req.groups) > 0:
+		response += fmt.Sprintf("\n%d of %d personal links were delivered.", len(recipients)-len(failed), len(recipients))
+	}
+	if len(failed) > 0 {
+		response += fmt.Sprintf("\nThe link could not be sent to %s. Revoke the secret and share it again.", formatMentions(failed))
+	}
+	sendSlackResponse(b.slack, req.responseURL, response)
+
+	if len(rest) > 0 {
+		// The command's own deadline would cut a large group short.
+		b.inflight.Add(1)
+		go func() {
+			defer b.inflight.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(len(rest))*b.dmInterval+defaultCommandTimeout)
+			defer cancel()
+			b.sendPacedLinks(ctx, req, secretID, rest)
+		}()
+	}
+}
+
+// sendPacedLinks DMs recipients their personal links one every
+// b.dmInterval, stopping when ctx is done, and then tells the sharer how
+// many were delivered.
+func (b *bot) sendPacedLinks(ctx context.Context, req shareRequest, secretID string, recipients []string) {
+	ticker := time.NewTicker(b.dmInterval)
+	defer ticker.Stop()
+	var failed []string
+	sent := 0
+send:
+	for _, id := range recipients {
+		select {
+		case <-ctx.Done():
+			break send
+		case <-ticker.C:
+		}
+		sent++
+		if !b.sendRecipientLink(req, secretID, id) {
+			failed = append(failed, id)
+		}
+	}
+	if sent < len(recipients) {
+		slog.Error("Stopped sending retrieval links to recipients", "event", "share", "secret_id", secretID, "user_id", req.userID, "unsent", len(recipients)-sent, "error", ctx.Err())
+		failed = append(failed, recipients[sent:]...)
+	}
+
+	response := fmt.Sprintf("%d of the remaining %d personal links for secret `%s` were delivered.", len(recipients)-len(failed), len(recipients), secretID)
+	if len(failed) > 0 {
+		response += fmt.Sprintf("\nThe link could not be sent to %s. Revoke the secret and share it again.", formatMentions(failed))
+	}
+	sendSlackResponse(b.slack, req.responseURL, response)
+}
+
+// sendRecipientLink DMs recipient their personal link to secretID, and
+// reports whether it was delivered.
+func (b *bot) sendRecipientLink(req shareRequest, secretID, recipient string) bool {
+	text := fmt.Sprintf("<@%s> shared a secret with you. It is valid for %s and can be retrieved %s. This link only works for you:\n%s", req.userID, formatDuration(req.ttl), formatUses(req.uses), recipientURL(b.cfg, req.teamID, secretID, recipient))
+	if req.replaces != "" {
+		text = fmt.Sprintf("<@%s> changed the value of a secret they shared with you, and the old link no longer works. The new value is valid for %s and can be retrieved %s. This link only works for you:\n%s", req.userID, formatDuration(req.ttl), formatUses(req.uses), recipientURL(b.cfg, req.teamID, secretID, recipient))
+	}
+	if err := postSlack(b.api(req.teamID), "recipient_link", recipient, slack.MsgOptionText(text, false)); err != nil {
+		slog.Error("Failed to send retrieval link to recipient", "event", "share", "secret_id", secretID, "user_id", req.userID, "recipient_id", recipient, "error", err)
+		return false
+	}
+	return true
+}
+
+// appendRecipients adds the comma-separated references in value to to.
+func appendRecipients(to []string, value string) ([]string, error) {
+	added := false
+	for _, ref := range strings.Split(value, ",") {
+		if ref = strings.TrimSpace(ref); ref != "" {
+			to = append(to, ref)
+			added = true
+		}
+	}
+	if !added {
+		return to, errors.New("Please name who the secret is for, e.g. `--to @alice`.")
+	}
+	return to, nil
+}
+
+// createShare stores the secret in req, restricted to recipients if any,
+// and issues its short-lived token, without replying to anyone, so that it
+// can be shared from outside Slack too. req is updated with any restriction
+// a credential detector placed on it. It returns the new secret's ID, its
+// metadata and token. Failures are categorized as for reportFailure.
+func (b *bot) createShare(ctx context.Context, req *shareRequest, recipients []string) (secretID string, meta secretMetadata, token string, err error) {
+	if !req.expiresAt.IsZero() {
+		// A share that waited for confirmation still expires when asked.
+		if req.ttl = time.Until(req.expiresAt).Round(time.Second); req.ttl <= 0 {
+			return "", meta, "", invalid(errors.New("The expiry time you gave has passed, so the secret was not shared."))
+		}
+	}
+	b.restrictDetectedCredential(req)
+	if err := b.cfg.Validate(*req); err != nil {
+		sharesTotal.WithLabelValues(outcomeError).Inc()
+		return "", meta, "", invalid(err)
+	}
+	if err := b.checkQuota(ctx, req); err != nil {
+		sharesTotal.WithLabelValues(outcomeDenied).Inc()
+		return "", meta, "", err
+	}
+
+	if secretID, err = newSecretID(); err != nil {
+		sharesTotal.WithLabelValues(outcomeError).Inc()
+		return "", meta, "", storeFailure("store the secret", fmt.Errorf("generating secret ID: %w", err))
+	}
+	if b.cfg.SecretLayout == layoutChannel && channelIDPattern.MatchString(req.channelID) {
+		secretID = channelSecretID(req.channelID, secretID)
+	}
+
+	// Record the owner, expiry and remaining uses so that the secret can be
+	// revoked and the retrieval page can enforce its limits
+	now := time.Now()
+	meta = secretMetadata{
+		SharedBy:       req.userID,
+		SharedByName:   req.userName,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(req.ttl),
+		UsesRemaining:  req.uses,
+		Notify:         req.notify,
+		AllowedUsers:   recipients,
+		Burn:           req.burn,
+		Label:          req.label,
+		PassphraseHash: req.passphraseHash,
+	}
+	if !req.expiresAt.IsZero() {
+		if meta.ExpiresAt.Before(req.expiresAt.Add(-time.Second)) {
+			// A credential detector brought it forward.
+			req.expiresAt = meta.ExpiresAt
+		} else {
+			meta.ExpiresAt = req.expiresAt
+		}
+	}
+
+	if b.cfg.DryRun {
+		secretID, token = dryRunCredentials()
+		slog.Info("Dry run: skipped writing secret to Vault", "event", "share", "secret_id", secretID, "user_id", req.userID)
+	} else if token, err = b.storeShare(ctx, secretID, *req, meta); err != nil {
+		sharesTotal.WithLabelValues(outcomeError).Inc()
+		return secretID, meta, "", err
+	}
+
+	sharesTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret shared", "event", "share", "secret_id", secretID, "user_id", req.userID, "user_name", req.userName, "ttl", req.ttl, "uses", req.uses)
+	audit(b.audit, AuditEvent{
+		Action:        auditShare,
+		SecretID:      secretID,
+		SharedBy:      req.userID,
+		Actor:         req.userID,
+		TTL:           req.ttl.String(),
+		ExpiresAt:     meta.ExpiresAt.UTC().Format(time.RFC3339),
+		UsesRemaining: usesRemaining(req.uses, req.uses == 0),
+	})
+	return secretID, meta, token, nil
+}
+
+// storeShare writes the secret in req to Vault, issues its short-lived
+// token and records meta along with the token's accessor, returning the
+// token.
+func (b *bot) storeShare(ctx context.Context, secretID string, req shareRequest, meta secretMetadata) (string, error) {
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+
+	// Store secret in Vault
+	paths := b.cfg.kvPaths(req.teamID)
+	payload := secretPayload{Text: req.secret, File: req.file, Fields: req.fields}
+	if err := storeSecret(ctx, b.secrets, paths, secretID, payload, newSecretCipher(b.cfg, b.secrets)); err != nil {
+		return "", storeFailure("store the secret", fmt.Errorf("writing secret: %w", err))
+	}
+
+	if err := writeSecretPolicy(ctx, b.secrets, paths, secretID); err != nil {
+		return "", tokenFailure(fmt.Errorf("writing secret policy: %w", err))
+	}
+
+	// Create short-lived token
+	token, accessor, err := createVaultToken(ctx, b.tokens, b.cfg, append([]string{paths.policyName(secretID)}, b.cfg.TokenPolicies...), secretID, req.userID, req.userName, req.ttl, req.uses)
+	if err != nil {
+		return "", tokenFailure(err)
+	}
+
+	meta.TokenAccessor = accessor
+	b.issued.add(accessor, req.teamID, secretID, req.ttl)
+	if err := writeSecretMetadata(ctx, b.secrets, paths, secretID, meta); err != nil {
+		return "", storeFailure("store the secret", fmt.Errorf("writing secret metadata: %w", err))
+	}
+
+	// The index only powers /list, so a failure here does not fail the share
+	if err := b.updateIndex(ctx, req.teamID, req.userID, func(ids []string) []string { return append(ids, secretID) }); err != nil {
+		slog.Error("Failed to add secret to index", "event", "share", "secret_id", secretID, "user_id", req.userID, "error", err)
+	}
+	return token, nil
+}
+
+// shareArgs holds the options parsed from the text of a /share command.
+type shareArgs struct {
+	ttl time.Duration
+	// expiresAt, when set, is the --expires-at time that ttl was computed
+	// to end at.
+	expiresAt time.Time
+	uses      int
+	// notify sends the sharer a DM when the secret is first retrieved.
+	notify bool
+	// burn destroys the secret as soon as it has been read once.
+	burn bool
+	// code issues a short code that can be typed on the retrieval page in
+	// place of the link.
+	code bool
+	// to restricts retrieval to these users, given as --to references.
+	to []string
+	// groups restricts retrieval to the members of these user groups,
+	// given as --group references, along with any users in to.
+	groups []string
+	// label is a description of the secret for the sharer's own reference,
+	// shown wherever it is listed.
+	label string
+	// format is how the reply tells the recipient to read the secret from
+	// a terminal, one of retrievalFormats.
+	format string
+	// email, when set, is the address the retrieval link is emailed to
+	// instead of being given to the sharer.
+	email string
+	// passphraseHash, when set, is the bcrypt hash of the --passphrase the
+	// retrieval page asks for. The secret's token is then never shown, since
+	// it would get around the passphrase.
+	passphraseHash string
+	secret         string
+	// fields, when set, are stored in place of secret. They are parsed
+	// from secrets typed as name=value pairs.
+	fields []secretField
+}
+
+// shareFlags are the flags of /share and /share-channel, mapped to whether
+// each takes a value.
+var shareFlags = map[string]bool{
+	"--ttl":        true,
+	"--expires-at": true,
+	"--uses":       true,
+	"--to":         true,
+	"--group":      true,
+	"--no-notify":  false,
+	"--burn":       false,
+	"--code":       false,
+	"--label":      true,
+	"--format":     true,
+	"--email":      true,
+	"--passphrase": true,
+}
+
+// parseShareArgs consumes leading --flag options from text and returns the
+// remainder as the secret, as described by splitFlags.
+func parseShareArgs(text string, cfg *Config) (shareArgs, error) {
+	flags, rest, err := splitFlags(text, shareFlags)
+	if err != nil {
+		args := cfg.defaultArgs()
+		args.format = formatCurl
+		return args, err
+	}
+	return newShareArgs(flags, rest, cfg)
+}
+
+// newShareArgs returns the options given by flags, already split from the
+// secret by splitFlags or in some other way, for sharing secret.
+func newShareArgs(flags []commandFlag, secret string, cfg *Config) (shareArgs, error) {
+	args := cfg.defaultArgs()
+	args.format = formatCurl
+	var err error
+	ttlSet, usesSet, formatSet := false, false, false
+	for _, f := range flags {
+		switch f.name {
+		case "--no-notify":
+			args.notify = false
+		case "--burn":
+			args.burn = true
+		case "--code":
+			args.code = true
+		case "--to":
+			args.to, err = appendRecipients(args.to, f.value)
+		case "--group":
+			args.groups, err = appendGroups(args.groups, f.value)
+		case "--ttl":
+			args.ttl, err = parseTTL(f.value, cfg)
+			ttlSet = true
+		case "--expires-at":
+			args.expiresAt, args.ttl, err = parseExpiresAt(f.value, cfg, time.Now())
+		case "--uses":
+			args.uses, err = parseUses(f.value, cfg)
+			usesSet = true
+		case "--label":
+			args.label, err = parseLabel(f.value)
+		case "--format":
+			args.format, err = parseFormat(f.value)
+			formatSet = true
+		case "--email":
+			if cfg.SMTPAddr == "" {
+				return args, errors.New("Sharing by email is not set up. Please ask an admin to configure an SMTP relay.")
+			}
+			args.email, err = parseEmail(f.value)
+		case "--passphrase":
+			args.passphraseHash, err = parsePassphrase(f.value)
+		}
+		if err != nil {
+			return args, err
+		}
+	}
+
+	if ttlSet && !args.expiresAt.IsZero() {
+		return args, errTTLAndExpiresAt
+	}
+	if args.code && len(args.to) > 0 {
+		return args, errors.New("`--to` secrets can only be opened through personal links, so it cannot be combined with `--code`.")
+	}
+	if args.code && len(args.groups) > 0 {
+		return args, errors.New("`--group` secrets can only be opened through personal links, so it cannot be combined with `--code`.")
+	}
+	if args.burn {
+		if usesSet && args.uses != 1 {
+			return args, errBurnUses
+		}
+		args.uses = 1
+	}
+	if args.email != "" {
+		switch {
+		case len(args.to) > 0:
+			return args, errors.New("`--to` sends personal links in Slack, so it cannot be combined with `--email`.")
+		case len(args.groups) > 0:
+			return args, errors.New("`--group` sends personal links in Slack, so it cannot be combined with `--email`.")
+		case args.code:
+			return args, errors.New("`--email` sends only the link, so it cannot be combined with `--code`.")
+		case usesSet && args.uses != 1:
+			return args, errors.New("Links sent by email can only be retrieved once, so `--email` cannot be combined with `--uses`.")
+		}
+		args.uses = 1
+	}
+	if args.passphraseHash != "" {
+		if formatSet && args.format != formatURL {
+			return args, errors.New("`--passphrase` secrets can only be opened on the retrieval page, so it cannot be combined with `--format curl` or `--format vault`.")
+		}
+		args.format = formatURL
+	}
+	if err := checkSecretSize(secret, cfg); err != nil {
+		return args, err
+	}
+	args.secret = secret
+	return args, nil
+}
+
+// checkSecretSize rejects a secret longer than cfg allows, before anything
+// is written to Vault.
+func checkSecretSize(secret string, cfg *Config) error {
+	return cfg.checkSecretSize(secret)
+}
+
+// parseTTL parses and bounds a user-supplied TTL.
+func parseTTL(value string, cfg *Config) (time.Duration, error) {
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("Invalid TTL %q. Use a duration such as `30m` or `2h` (maximum %s).", value, formatDuration(cfg.MaxTTL))
+	}
+	if err := cfg.checkTTL(ttl); err != nil {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+var errTTLAndExpiresAt = errors.New("`--ttl` and `--expires-at` both set when the secret expires, so use only one of them.")
+
+// parseExpiresAt parses a user-supplied RFC 3339 expiry time and returns it
+// with the TTL from now until then, which is bounded like --ttl.
+func parseExpiresAt(value string, cfg *Config, now time.Time) (time.Time, time.Duration, error) {
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("Invalid expiry time %q. Use an RFC 3339 timestamp such as `%s`.", value, now.Add(time.Hour).UTC().Truncate(time.Minute).Format(time.RFC3339))
+	}
+	ttl := at.Sub(now).Round(time.Second)
+	if ttl <= 0 {
+		return time.Time{}, 0, fmt.Errorf("The expiry time %s has already passed.", value)
+	}
+	if ttl > cfg.MaxTTL {
+		return time.Time{}, 0, fmt.Errorf("The expiry time %s is more than the maximum of %s from now.", value, formatDuration(cfg.MaxTTL))
+	}
+	return at, ttl, nil
+}
+
+// expiryNote is added to the reply to a share made with --expires-at.
+func expiryNote(at time.Time) string {
+	return "It expires at " + formatSlackDate(at) + "."
+}
+
+// formatSlackDate renders at for a Slack message, which shows it in the
+// reader's own time zone.
+func formatSlackDate(at time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", at.Unix(), at.UTC().Format(time.RFC1123))
+}
+
+// parseUses parses and bounds a user-supplied number of uses.
+func parseUses(value string, cfg *Config) (int, error) {
+	uses, err := strconv.Atoi(value)
+	if err != nil || uses < 0 {
+		return 0, fmt.Errorf("Invalid uses %q. Use a whole number between 1 and %d.", value, cfg.MaxUses)
+	}
+	if err := cfg.checkUses(uses); err != nil {
+		return 0, err
+	}
+	return uses, nil
+}
+
+// The retrieval formats a sharer can pick with --format.
+const (
+	formatCurl  = "curl"
+	formatVault = "vault"
+	formatURL   = "url"
+)
+
+var retrievalFormats = []string{formatCurl, formatVault, formatURL}
+
+// parseFormat checks a user-supplied --format.
+func parseFormat(value string) (string, error) {
+	format := strings.ToLower(value)
+	if !slices.Contains(retrievalFormats, format) {
+		return "", fmt.Errorf("Invalid format %q. Use one of `%s`.", value, strings.Join(retrievalFormats, "`, `"))
+	}
+	return format, nil
+}
+
+// retrievalInstructions tells the recipient how to read secretID with token
+// from a terminal in format. Both commands go through the retrieval server's
+// /v1/secrets API, as secretAPIURL explains, which answers the Vault CLI the
+// way Vault would. The Vault CLI is told to `vault read`, since `vault kv
+// get` first looks up the mount, which the retrieval server does not serve.
+func (b *bot) retrievalInstructions(format, teamID, secretID, token string) string {
+	switch format {
+	case formatURL:
+		return ""
+	case formatVault:
+		return fmt.Sprintf("Or with the Vault CLI: \n```VAULT_ADDR=%s VAULT_TOKEN=%s vault read secrets/%s```", retrievalBaseURL(b.cfg), token, secretURLPath(b.cfg, teamID, secretID))
+	default:
+		return fmt.Sprintf("Or from a terminal: \n```curl --header \"X-Vault-Token: %s\" --request GET %s```", token, b.secretAPIURL(teamID, secretID))
 	}
+}
+
+// maxLabelLength is the most characters a --label may have.
+const maxLabelLength = 80
 
-	token, err := client.Auth().Token().Create(tokenRequest)
+// parseLabel cleans up a user-supplied label: control characters such as
+// newlines become spaces, invisible formatting characters such as direction
+// overrides are dropped, and runs of spaces are collapsed.
+func parseLabel(value string) (string, error) {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return ' '
+		case unicode.Is(unicode.Cf, r):
+			return -1
+		}
+		return r
+	}, value)
+	label = strings.Join(strings.Fields(label), " ")
+	if label == "" {
+		return "", errors.New("Please give `--label` some text, e.g. `--label \"prod db password\"`.")
+	}
+	if n := utf8.RuneCountInString(label); n > maxLabelLength {
+		return "", fmt.Errorf("The label is %d characters long, more than the maximum of %d.", n, maxLabelLength)
+	}
+	return label, nil
+}
+
+// formatUses describes how many times a secret can be retrieved, where zero
+// means unlimited.
+func formatUses(uses int) string {
+	switch uses {
+	case 0:
+		return "an unlimited number of times"
+	case 1:
+		return "once"
+	default:
+		return fmt.Sprintf("%d times", uses)
+	}
+}
+
+// formatDuration renders d without the zero-valued trailing units that
+// time.Duration.String adds, e.g. "1h" rather than "1h0m0s".
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// sendChannelLink posts the retrieval link of a /share-channel secret to the
+// channel, naming the sharer, and tells the sharer how to revoke it. The
+// Vault token is left out, since anyone in the channel could use it.
+func (b *bot) sendChannelLink(req shareRequest, secretID, pageURL string) {
+	text := fmt.Sprintf("<@%s> shared a secret with this channel. It is valid for %s and can be retrieved %s:\n%s", req.userID, formatDuration(req.ttl), formatUses(req.uses), pageURL)
+	err := postSlack(
+		&b.slack.Client, "channel_link", "",
+		slack.MsgOptionResponseURL(req.responseURL, slack.ResponseTypeInChannel),
+		slack.MsgOptionText(text, false),
+	)
 	if err != nil {
-		return "", err
+		slog.Error("Failed to post retrieval link to channel", "event", "share", "secret_id", secretID, "user_id", req.userID, "error", err)
+		sendSlackResponse(b.slack, req.responseURL, fmt.Sprintf("Your secret was stored but the link could not be posted to the channel. Run `/revoke %s` and try again.", secretID))
+		return
+	}
+	response := fmt.Sprintf("Your secret's link has been posted to the channel. To destroy it early, run `/revoke %s`.", secretID)
+	if req.passphraseHash != "" {
+		response += "\n" + passphraseNote
+	}
+	if req.warning != "" {
+		response += "\n" + req.warning
 	}
-	return token.Auth.ClientToken, nil
+	sendSlackResponse(b.slack, req.responseURL, response)
 }
 
+// replaceSlackResponse replaces the message that an interaction came from.
+func replaceSlackResponse(client *socketmode.Client, responseURL, message string) {
+	err := postSlack(
+		&client.Client, "replace_response", "",
+		slack.MsgOptionReplaceOriginal(responseURL),
+		slack.MsgOptionText(message, false),
+	)
+	if err != nil {
+		slog.Error("Failed to send response to Slack", "error", err)
+	}
+}
+
+// sendSlackResponse replies ephemerally through a command's response URL.
+// Slack posts such replies wherever the command was run, so a command run in
+// a thread is answered in that thread without a thread timestamp, which
+// slash command payloads do not carry. Any reply that carries a secret, a
+// token or a link to one must be sent this way.
 func sendSlackResponse(client *socketmode.Client, responseURL, message string) {
-	_, _, err := client.Client.PostMessage(
-		"",
-		slack.MsgOptionResponseURL(responseURL, slack.ResponseTypeEphemeral),
+	sendSlackResponseType(client, responseURL, slack.ResponseTypeEphemeral, message)
+}
+
+// sendSlackResponseType replies through a command's response URL with
+// responseType, slack.ResponseTypeEphemeral or slack.ResponseTypeInChannel.
+func sendSlackResponseType(client *socketmode.Client, responseURL, responseType, message string) {
+	err := postSlack(
+		&client.Client, "response", "",
+		slack.MsgOptionResponseURL(responseURL, responseType),
 		slack.MsgOptionText(message, false),
 	)
 	if err != nil {
-		log.Printf("Failed to send response to Slack: %v", err)
+		slog.Error("Failed to send response to Slack", "error", err)
 	}
 }
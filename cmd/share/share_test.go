@@ -0,0 +1,492 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// fakeSecretStore records writes in memory and fails writes to paths
+// starting with failWrites. Data is round-tripped through JSON, as it is
+// when it goes through Vault.
+type fakeSecretStore struct {
+	mu         sync.Mutex
+	data       map[string]map[string]interface{}
+	failWrites string
+}
+
+func newFakeSecretStore() *fakeSecretStore {
+	return &fakeSecretStore{data: map[string]map[string]interface{}{}}
+}
+
+func (s *fakeSecretStore) ReadWithContext(_ context.Context, path string) (*api.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[path]
+	if !ok {
+		return nil, nil
+	}
+	return &api.Secret{Data: data}, nil
+}
+
+func (s *fakeSecretStore) WriteWithContext(_ context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failWrites != "" && strings.HasPrefix(path, s.failWrites) {
+		return nil, errors.New("vault unavailable")
+	}
+	if custom, ok := data["custom_metadata"].(map[string]string); ok {
+		for key, value := range custom {
+			if len(value) > maxMetadataValue {
+				return nil, fmt.Errorf("custom_metadata value of key %q is too long", key)
+			}
+		}
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var stored map[string]interface{}
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	s.data[path] = stored
+	return nil, nil
+}
+
+func (s *fakeSecretStore) DeleteWithContext(_ context.Context, path string) (*api.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, path)
+	return nil, nil
+}
+
+// ListWithContext returns the names of the paths directly under path, as Vault lists
+// the keys of a KV directory.
+func (s *fakeSecretStore) ListWithContext(_ context.Context, path string) (*api.Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// As Vault does, subdirectories are listed once each, with a trailing
+	// slash.
+	var keys []interface{}
+	for p := range s.data {
+		name, ok := strings.CutPrefix(p, path+"/")
+		if dir, _, nested := strings.Cut(name, "/"); nested {
+			name = dir + "/"
+		}
+		if ok && !slices.Contains(keys, interface{}(name)) {
+			keys = append(keys, name)
+		}
+	}
+	if keys == nil {
+		return nil, nil
+	}
+	return &api.Secret{Data: map[string]interface{}{"keys": keys}}, nil
+}
+
+type fakeTokenCreator struct {
+	createErr error
+	created   []*api.TokenCreateRequest
+	revoked   []string
+}
+
+func (f *fakeTokenCreator) CreateWithContext(_ context.Context, opts *api.TokenCreateRequest) (*api.Secret, error) {
+	if f.createErr != nil {
+		return nil, f.createErr
+	}
+	f.created = append(f.created, opts)
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: "hvs.recipient", Accessor: "accessor-1"}}, nil
+}
+
+func (f *fakeTokenCreator) RevokeAccessorWithContext(_ context.Context, accessor string) error {
+	f.revoked = append(f.revoked, accessor)
+	return nil
+}
+
+// newTestBot returns a bot backed by fakes, the response URL to give its
+// commands and a function returning the messages posted to it.
+func newTestBot(t *testing.T, store SecretStore, tokens TokenCreator) (*bot, string, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var messages []string
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			Text string `json:"text"`
+		}
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		messages = append(messages, msg.Text)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(slackAPI.Close)
+
+	vaultClient, err := api.NewClient(&api.Config{Address: "http://127.0.0.1:8200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		SharePolicy:       SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses},
+		ShareRateLimit:    defaultShareRateLimit,
+		ConfirmLength:     defaultConfirmLength,
+		RetrievalAddr:     defaultRetrievalAddr,
+		VaultSecretsMount: defaultSecretsMount,
+		VaultKVVersion:    defaultKVVersion,
+		VaultTimeout:      defaultVaultTimeout,
+	}
+	client := socketmode.New(slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+	b := newBot(client, newWorkspaces(cfg, &client.Client), vaultClient, cfg, multiAuditLogger(nil), newMemoryState())
+	b.secrets = store
+	b.tokens = tokens
+
+	return b, slackAPI.URL + "/response", func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), messages...)
+	}
+}
+
+func TestHandleShareCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		failWrites string
+		createErr  error
+		wantReply  string
+		wantToken  bool
+		wantStored bool
+	}{
+		{
+			name:       "success",
+			wantReply:  "been securely shared",
+			wantToken:  true,
+			wantStored: true,
+		},
+		{
+			name:       "vault write error",
+			failWrites: "secrets/data/",
+			wantReply:  "Failed to store the secret",
+		},
+		{
+			name:       "policy write error",
+			failWrites: "sys/policies/",
+			wantReply:  "Failed to create a secure access token",
+		},
+		{
+			name:      "token creation error",
+			createErr: errors.New("permission denied"),
+			wantReply: "Failed to create a secure access token",
+		},
+		{
+			name:       "metadata write error",
+			failWrites: "secrets/metadata/",
+			wantReply:  "Failed to store the secret",
+			wantToken:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeSecretStore()
+			store.failWrites = tt.failWrites
+			tokens := &fakeTokenCreator{createErr: tt.createErr}
+			b, responseURL, replies := newTestBot(t, store, tokens)
+
+			b.handleShareCommand(context.Background(), slack.SlashCommand{
+				Command:     "/share",
+				Text:        "--uses 2 hunter2",
+				UserID:      "U123",
+				UserName:    "alice",
+				ResponseURL: responseURL,
+			})
+
+			got := replies()
+			if len(got) != 1 || !strings.Contains(got[0], tt.wantReply) {
+				t.Fatalf("replies = %q, want one containing %q", got, tt.wantReply)
+			}
+			if strings.Contains(got[0], "hunter2") {
+				t.Errorf("reply contains the secret: %q", got[0])
+			}
+			if gotToken := len(tokens.created) == 1; gotToken != tt.wantToken {
+				t.Errorf("token created = %v, want %v", gotToken, tt.wantToken)
+			} else if gotToken && tokens.created[0].NumUses != 2 {
+				t.Errorf("token NumUses = %d, want 2", tokens.created[0].NumUses)
+			} else if gotToken {
+				id := tokens.created[0].Metadata["secret_id"]
+				if want := []string{"hush-" + id}; !slices.Equal(tokens.created[0].Policies, want) {
+					t.Errorf("token Policies = %q, want %q", tokens.created[0].Policies, want)
+				}
+				policy, _ := store.data["sys/policies/acl/hush-"+id]["policy"].(string)
+				if !strings.Contains(policy, `path "secrets/data/shared/`+id+`"`) || strings.Contains(policy, "*") {
+					t.Errorf("secret policy = %q, want read on only %s", policy, id)
+				}
+			}
+
+			stored := false
+			for path, data := range store.data {
+				if m, ok := data["custom_metadata"].(map[string]interface{}); ok && strings.HasPrefix(path, "secrets/metadata/shared/") {
+					stored = m["token_accessor"] == "accessor-1" && m["uses_remaining"] == "2"
+				}
+			}
+			if stored != tt.wantStored {
+				t.Errorf("metadata stored = %v, want %v (store: %v)", stored, tt.wantStored, store.data)
+			}
+			if tt.wantStored && !strings.Contains(got[0], "hvs.recipient") {
+				t.Errorf("reply does not include the recipient token: %q", got[0])
+			}
+		})
+	}
+}
+
+// hangingStore is a SecretStore whose writes never complete until their
+// context is done, like a Vault that has stopped responding.
+type hangingStore struct {
+	*fakeSecretStore
+}
+
+func (s hangingStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestShareVaultTimeout(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, hangingStore{newFakeSecretStore()}, &fakeTokenCreator{})
+	b.cfg.VaultTimeout = 10 * time.Millisecond
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || got[0] != vaultTimeoutMessage {
+		t.Errorf("replies = %q, want the Vault timeout message", got)
+	}
+}
+
+// sealedStore is a SecretStore whose writes fail the way a sealed Vault
+// fails them.
+type sealedStore struct {
+	*fakeSecretStore
+}
+
+func (s sealedStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	return nil, &api.ResponseError{StatusCode: http.StatusServiceUnavailable, Errors: []string{"Vault is sealed"}}
+}
+
+func TestShareVaultSealed(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, sealedStore{newFakeSecretStore()}, &fakeTokenCreator{})
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || got[0] != vaultSealedMessage {
+		t.Errorf("replies = %q, want the sealed message", got)
+	}
+}
+
+// deniedStore is a SecretStore whose writes fail the way Vault fails them
+// when the bot's policy does not allow them.
+type deniedStore struct {
+	*fakeSecretStore
+}
+
+func (s deniedStore) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	return nil, &api.ResponseError{StatusCode: http.StatusForbidden, Errors: []string{"1 error occurred:\n\t* permission denied\n\n"}}
+}
+
+func TestShareVaultPermissionDenied(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, deniedStore{newFakeSecretStore()}, &fakeTokenCreator{})
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || got[0] != vaultPermissionMessage {
+		t.Errorf("replies = %q, want the permission message", got)
+	}
+}
+
+func TestShareChannelCommand(t *testing.T) {
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+
+	b.handleShareChannelCommand(context.Background(), slack.SlashCommand{Command: "/share-channel", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	got := replies()
+	if len(got) != 2 {
+		t.Fatalf("replies = %q, want the channel post and a note to the sharer", got)
+	}
+	if !strings.Contains(got[0], "<@U1> shared a secret with this channel") || !strings.Contains(got[0], "/s/secret-") {
+		t.Errorf("channel post = %q, want the sharer and the link", got[0])
+	}
+	for _, reply := range got {
+		if strings.Contains(reply, "hunter2") || strings.Contains(reply, "hvs.") {
+			t.Errorf("reply exposes the secret or its token: %q", reply)
+		}
+	}
+	if !strings.Contains(got[1], "/revoke secret-") {
+		t.Errorf("note to sharer = %q, want revoke instructions", got[1])
+	}
+
+	b.handleShareChannelCommand(context.Background(), slack.SlashCommand{Command: "/share-channel", Text: "--to @alice hunter2", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], "cannot be combined") {
+		t.Errorf("reply to --to = %q, want it rejected", got[len(got)-1])
+	}
+}
+
+func TestShareChannelLayout(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.SecretLayout = layoutChannel
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", TeamID: "T1", ChannelID: "C1", UserID: "U1", ResponseURL: responseURL})
+	id := tokens.created[0].Metadata["secret_id"]
+	if !strings.HasPrefix(id, "C1_") {
+		t.Fatalf("secret ID = %q, want it to start with the channel", id)
+	}
+	if _, ok := store.data["secrets/data/shared/T1/C1/"+strings.TrimPrefix(id, "C1_")]; !ok {
+		t.Errorf("store = %v, want the secret under the team and channel", store.data)
+	}
+	if got := replies(); !strings.Contains(got[0], "/s/T1/"+id) {
+		t.Errorf("reply = %q, want a link naming the team", got[0])
+	}
+
+	// Without a channel, the secret is kept in the team's directory.
+	var out strings.Builder
+	if err := runCreate(context.Background(), b, []string{"--team", "T1"}, strings.NewReader("hunter3"), &out); err != nil {
+		t.Fatal(err)
+	}
+	other := tokens.created[1].Metadata["secret_id"]
+	if strings.Contains(other, "_") {
+		t.Errorf("secret ID = %q, want no channel", other)
+	}
+
+	ids, err := listSecretIDs(context.Background(), store, b.cfg.kvPaths("T1"))
+	slices.Sort(ids)
+	if want := []string{id, other}; err != nil || !slices.Equal(ids, want) {
+		t.Errorf("listSecretIDs() = %q, %v, want %q", ids, err, want)
+	}
+	if teams, err := b.storedTeams(context.Background()); err != nil || !slices.Equal(teams, []string{"T1"}) {
+		t.Errorf("storedTeams() = %q, %v, want T1", teams, err)
+	}
+	if err := runCreate(context.Background(), b, nil, strings.NewReader("hunter4"), &out); err == nil || !strings.Contains(err.Error(), "--team") {
+		t.Errorf("runCreate() without --team error = %v, want --team required", err)
+	}
+}
+
+func TestShareMaxSecretBytes(t *testing.T) {
+	store := newFakeSecretStore()
+	b, responseURL, replies := newTestBot(t, store, &fakeTokenCreator{})
+	b.cfg.MaxSecretBytes = 8
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--ttl 5m password123", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); len(got) != 1 || !strings.Contains(got[0], "The secret is 11 bytes, more than the maximum of 8 bytes") {
+		t.Errorf("replies = %q, want the secret refused for its size", got)
+	}
+	if len(store.data) != 0 {
+		t.Errorf("store = %v, want nothing written to Vault", store.data)
+	}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", ResponseURL: responseURL})
+	if len(store.data) == 0 {
+		t.Error("a secret within the limit was not stored")
+	}
+}
+
+func TestShareWhitespaceSecret(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	for _, text := range []string{"--ttl 5m \n", `--ttl 5m "   "`, "-- \t "} {
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", ResponseURL: responseURL})
+		if got := replies(); !strings.Contains(got[len(got)-1], "Usage: `/share") {
+			t.Errorf("reply to %q = %q, want the usage", text, got[len(got)-1])
+		}
+	}
+	if got := replies(); !strings.Contains(got[1], "only whitespace") {
+		t.Errorf("reply to a quoted blank secret = %q, want it called whitespace", got[1])
+	}
+	if len(store.data) != 0 {
+		t.Fatalf("store = %v, want nothing written to Vault", store.data)
+	}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: `--no-notify " hunter2 "`, UserID: "U1", ResponseURL: responseURL})
+	id := tokens.created[0].Metadata["secret_id"]
+	if stored, err := readSecret(context.Background(), store, b.cfg.kvPaths(""), id, nil); err != nil || stored.Text != " hunter2 " {
+		t.Errorf("stored secret = %q, %v, want its whitespace kept", stored.Text, err)
+	}
+}
+
+func TestShareFormat(t *testing.T) {
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, newFakeSecretStore(), tokens)
+	share := func(text string) string {
+		t.Helper()
+		b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: text, UserID: "U1", ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	if got := share("hunter2"); !strings.Contains(got, `curl --header "X-Vault-Token: hvs.recipient" --request GET `+retrievalBaseURL(b.cfg)+"/v1/secrets/secret-") {
+		t.Errorf("default reply = %q, want the curl command through the retrieval server", got)
+	}
+	got := share("--format vault hunter2")
+	id := tokens.created[len(tokens.created)-1].Metadata["secret_id"]
+	if want := "VAULT_ADDR=" + retrievalBaseURL(b.cfg) + " VAULT_TOKEN=hvs.recipient vault read secrets/" + id; !strings.Contains(got, want) || strings.Contains(got, "curl") || strings.Contains(got, "127.0.0.1:8200") {
+		t.Errorf("--format vault reply = %q, want %q", got, want)
+	}
+	if got := share("--format url hunter2"); strings.Contains(got, "hvs.recipient") || !strings.Contains(got, "/s/secret-") || !strings.Contains(got, "/revoke secret-") {
+		t.Errorf("--format url reply = %q, want only the link", got)
+	}
+	if got := share("--format wget hunter2"); !strings.Contains(got, "Invalid format") {
+		t.Errorf("reply to an unknown format = %q, want it rejected", got)
+	}
+}
+
+func TestParseExpiresAt(t *testing.T) {
+	cfg := &Config{SharePolicy: SharePolicy{MaxTTL: 24 * time.Hour}}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	at, ttl, err := parseExpiresAt("2025-06-01T16:30:00+02:00", cfg, now)
+	if err != nil || ttl != 2*time.Hour+30*time.Minute || !at.Equal(now.Add(ttl)) {
+		t.Errorf("parseExpiresAt() = %s, %s, %v, want 2h30m from now", at, ttl, err)
+	}
+	for value, want := range map[string]string{
+		"tomorrow":             "RFC 3339",
+		"2025-06-01T11:00:00Z": "already passed",
+		"2025-06-03T12:00:00Z": "maximum of 24h",
+	} {
+		if _, _, err := parseExpiresAt(value, cfg, now); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseExpiresAt(%q) error = %v, want it to mention %q", value, err, want)
+		}
+	}
+
+	if _, err := parseShareArgs("--ttl 1h --expires-at "+time.Now().Add(time.Hour).Format(time.RFC3339)+" hunter2", &Config{SharePolicy: SharePolicy{MaxTTL: defaultMaxTTL, MaxUses: defaultMaxUses}}); err == nil {
+		t.Error("parseShareArgs() with --ttl and --expires-at succeeded")
+	}
+}
+
+func TestShareExpiresAt(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+
+	at := time.Now().Add(90 * time.Minute).Truncate(time.Second)
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify --expires-at " + at.Format(time.RFC3339) + " hunter2", UserID: "U1", ResponseURL: responseURL})
+	if len(tokens.created) != 1 {
+		t.Fatalf("replies = %q, want the secret shared", replies())
+	}
+	if ttl, _ := time.ParseDuration(tokens.created[0].TTL); ttl <= 89*time.Minute || ttl > 90*time.Minute {
+		t.Errorf("token TTL = %s, want about 90m", tokens.created[0].TTL)
+	}
+	meta, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), tokens.created[0].Metadata["secret_id"])
+	if err != nil || meta.ExpiresAt.Sub(at).Abs() > time.Second {
+		t.Errorf("metadata ExpiresAt = %s, %v, want %s", meta.ExpiresAt, err, at)
+	}
+	if got := replies(); !strings.Contains(got[0], fmt.Sprintf("It expires at <!date^%d^", at.Unix())) {
+		t.Errorf("reply = %q, want the absolute expiry", got[0])
+	}
+}
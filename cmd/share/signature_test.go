@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// signSlackRequest sets the headers Slack would send with body at ts.
+func signSlackRequest(header http.Header, secret, body string, ts time.Time) {
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + stamp + ":" + body))
+	header.Set("X-Slack-Request-Timestamp", stamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	const body = "command=%2Fshare&text=hunter2"
+	tests := []struct {
+		name    string
+		secret  string
+		body    string
+		ts      time.Time
+		wantErr bool
+	}{
+		{"valid", testSigningSecret, body, time.Now(), false},
+		{"wrong secret", "other-secret", body, time.Now(), true},
+		{"tampered body", testSigningSecret, body + "&user_id=U2", time.Now(), true},
+		{"stale", testSigningSecret, body, time.Now().Add(-6 * time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			signSlackRequest(header, tt.secret, body, tt.ts)
+			err := VerifySlackSignature(testSigningSecret, header, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifySlackSignature() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := VerifySlackSignature(testSigningSecret, http.Header{}, []byte(body)); err == nil {
+		t.Error("VerifySlackSignature() without signature headers succeeded")
+	}
+}
+
+func TestVerifySlackRequests(t *testing.T) {
+	const body = "command=%2Fshare&text=hunter2"
+	var got string
+	handler := verifySlackRequests(testSigningSecret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/slack/commands", strings.NewReader(body))
+	signSlackRequest(req.Header, testSigningSecret, body, time.Now())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || got != body {
+		t.Errorf("signed request: status %d, handler saw %q", rec.Code, got)
+	}
+
+	got = ""
+	req = httptest.NewRequest(http.MethodPost, "/slack/commands", strings.NewReader(body))
+	signSlackRequest(req.Header, "other-secret", body, time.Now())
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || got != "" {
+		t.Errorf("forged request: status %d, handler saw %q", rec.Code, got)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+const (
+	slackMaxAttempts = 4
+	// slackMaxRetryAfter is the longest Retry-After the bot waits out. Past
+	// it the message is dropped rather than holding up its handler.
+	slackMaxRetryAfter = 30 * time.Second
+)
+
+// slackRetryPolicy retries posts that Slack rejects with 429 Too Many
+// Requests after the Retry-After it gives. Posts are retried in the handler
+// that made them, so a burst of rate-limited replies keeps their handler
+// slots busy and new commands wait for one instead of piling up more posts.
+type slackRetryPolicy struct {
+	attempts int
+	maxWait  time.Duration
+	// sleep waits for the delay or until ctx is done. It is replaced in
+	// tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+var slackRetries = slackRetryPolicy{attempts: slackMaxAttempts, maxWait: slackMaxRetryAfter, sleep: sleepContext}
+
+// do calls post until it succeeds, fails for a reason other than a rate
+// limit, or has been tried p.attempts times.
+func (p slackRetryPolicy) do(ctx context.Context, operation string, post func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := post()
+		var limited *slack.RateLimitedError
+		if !errors.As(err, &limited) {
+			return err
+		}
+		wait := max(limited.RetryAfter, time.Second)
+		if attempt >= p.attempts || wait > p.maxWait {
+			slackRateLimitsTotal.WithLabelValues("dropped").Inc()
+			return err
+		}
+		slackRateLimitsTotal.WithLabelValues("retried").Inc()
+		slog.Warn("Slack rate limited a post, retrying", "operation", operation, "attempt", attempt, "retry_after", wait)
+		if err := p.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// postSlack sends a message with client, waiting out Slack's rate limits
+// as slackRetries allows.
+func postSlack(client *slack.Client, operation, channelID string, options ...slack.MsgOption) error {
+	return slackRetries.do(context.Background(), operation, func() error {
+		_, _, err := client.PostMessage(channelID, options...)
+		return err
+	})
+}
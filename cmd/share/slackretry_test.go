@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestPostSlackRateLimited(t *testing.T) {
+	tests := []struct {
+		name       string
+		limited    int
+		retryAfter int
+		wantPosts  int
+		wantErr    bool
+	}{
+		{name: "not limited", wantPosts: 1},
+		{name: "retried", limited: 2, retryAfter: 3, wantPosts: 3},
+		{name: "too many attempts", limited: slackMaxAttempts, retryAfter: 1, wantPosts: slackMaxAttempts, wantErr: true},
+		{name: "retry after too long", limited: 1, retryAfter: 120, wantPosts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(posts.Add(1)) <= tt.limited {
+					w.Header().Set("Retry-After", strconv.Itoa(tt.retryAfter))
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer srv.Close()
+
+			var waits []time.Duration
+			saved := slackRetries
+			slackRetries.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			t.Cleanup(func() { slackRetries = saved })
+
+			err := postSlack(slack.New("xoxb-test"), "response", "", slack.MsgOptionResponseURL(srv.URL, slack.ResponseTypeEphemeral), slack.MsgOptionText("hi", false))
+			var limited *slack.RateLimitedError
+			if tt.wantErr != errors.As(err, &limited) {
+				t.Errorf("postSlack() error = %v, want a rate limit error: %v", err, tt.wantErr)
+			}
+			if got := int(posts.Load()); got != tt.wantPosts {
+				t.Errorf("posts = %d, want %d", got, tt.wantPosts)
+			}
+			for _, d := range waits {
+				if d != time.Duration(tt.retryAfter)*time.Second {
+					t.Errorf("waited %s between attempts, want the Retry-After of %ds", d, tt.retryAfter)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+const (
+	// defaultSlackPingTimeout is how long the Socket Mode connection may go
+	// without a ping from Slack before it is treated as dead and reopened,
+	// as the socketmode client does by default.
+	defaultSlackPingTimeout = 30 * time.Second
+	// defaultSlackConnectTimeout bounds the WebSocket handshake of each
+	// connection attempt.
+	defaultSlackConnectTimeout = 30 * time.Second
+)
+
+// Socket Mode connection states, as reported by /readyz.
+const (
+	socketConnecting   = "connecting"
+	socketConnected    = "connected"
+	socketDisconnected = "disconnected"
+)
+
+// socketStatus tracks the state of the Socket Mode connection. The socketmode
+// client reconnects by itself, backing off exponentially up to five minutes
+// between attempts; socketStatus only records what it reports.
+type socketStatus struct {
+	// grace is how long the connection may be down, after it has been up,
+	// before the bot is reported as not ready, so that Slack's routine
+	// connection refreshes do not fail readiness.
+	grace time.Duration
+	now   func() time.Time
+
+	mu    sync.Mutex
+	state string
+	since time.Time
+	// everConnected is set once the first connection succeeds. Until then
+	// the bot is not ready, however recently it started.
+	everConnected bool
+}
+
+func newSocketStatus(grace time.Duration) *socketStatus {
+	return &socketStatus{grace: grace, now: time.Now, state: socketConnecting, since: time.Now()}
+}
+
+// observe records the connection state an event from the socketmode client
+// reports and logs each change. It reports whether evt was a connection
+// event.
+func (s *socketStatus) observe(evt socketmode.Event) bool {
+	switch evt.Type {
+	case socketmode.EventTypeConnecting:
+		s.set(socketConnecting)
+		var args []any
+		if e, ok := evt.Data.(*slack.ConnectingEvent); ok {
+			args = []any{"attempt", e.Attempt}
+		}
+		slog.Info("Connecting to Slack", append([]any{"event", "socket"}, args...)...)
+	case socketmode.EventTypeConnected:
+		s.set(socketConnected)
+		var args []any
+		if e, ok := evt.Data.(*socketmode.ConnectedEvent); ok {
+			args = []any{"connection_count", e.ConnectionCount}
+		}
+		slog.Info("Connected to Slack", append([]any{"event", "socket"}, args...)...)
+	case socketmode.EventTypeConnectionError:
+		s.set(socketDisconnected)
+		var args []any
+		if e, ok := evt.Data.(*slack.ConnectionErrorEvent); ok {
+			args = []any{"attempt", e.Attempt, "retry_in", e.Backoff, "error", e.ErrorObj}
+		}
+		slog.Warn("Failed to connect to Slack; retrying", append([]any{"event", "socket"}, args...)...)
+	case socketmode.EventTypeDisconnect:
+		// Slack asks for this before it refreshes a connection, and the
+		// client reconnects straight away.
+		s.set(socketDisconnected)
+		slog.Info("Slack closed the connection; reconnecting", "event", "socket")
+	case socketmode.EventTypeInvalidAuth:
+		s.set(socketDisconnected)
+		slog.Error("Slack rejected the app token; not reconnecting", "event", "socket")
+	default:
+		return false
+	}
+	return true
+}
+
+// set records the state and, if it changed, when.
+func (s *socketStatus) set(state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state == socketConnected {
+		s.everConnected = true
+	}
+	if s.state != state {
+		s.state, s.since = state, s.now()
+	}
+}
+
+// check returns an error describing the connection unless it is up, or was
+// up until less than grace ago.
+func (s *socketStatus) check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == socketConnected {
+		return nil
+	}
+	down := s.now().Sub(s.since)
+	if s.everConnected && down < s.grace {
+		return nil
+	}
+	return fmt.Errorf("socket mode connection %s for %s", s.state, down.Round(time.Second))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/socketmode"
+)
+
+func TestSocketStatus(t *testing.T) {
+	now := time.Now()
+	s := newSocketStatus(30 * time.Second)
+	s.now = func() time.Time { return now }
+
+	if err := s.check(); err == nil || !strings.Contains(err.Error(), "connecting") {
+		t.Errorf("check() before connecting = %v, want not ready", err)
+	}
+	s.observe(socketmode.Event{Type: socketmode.EventTypeConnecting, Data: &slack.ConnectingEvent{Attempt: 1}})
+	if !s.observe(socketmode.Event{Type: socketmode.EventTypeConnected, Data: &socketmode.ConnectedEvent{}}) {
+		t.Error("observe(connected) = false, want a connection event")
+	}
+	if err := s.check(); err != nil {
+		t.Errorf("check() while connected = %v", err)
+	}
+
+	s.observe(socketmode.Event{Type: socketmode.EventTypeDisconnect})
+	now = now.Add(10 * time.Second)
+	if err := s.check(); err != nil {
+		t.Errorf("check() shortly after a disconnect = %v, want ready within the grace period", err)
+	}
+	s.observe(socketmode.Event{Type: socketmode.EventTypeConnectionError, Data: &slack.ConnectionErrorEvent{Attempt: 1}})
+	now = now.Add(30 * time.Second)
+	if err := s.check(); err == nil || !strings.Contains(err.Error(), "disconnected for 40s") {
+		t.Errorf("check() long after a disconnect = %v, want not ready", err)
+	}
+
+	if s.observe(socketmode.Event{Type: socketmode.EventTypeHello}) {
+		t.Error("observe(hello) = true, want it left to the caller")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// Values of VAULT_STARTUP.
+const (
+	// startupFail exits when the bot cannot log in to Vault at startup.
+	startupFail = "fail"
+	// startupDegrade starts the bot anyway, replying to commands that the
+	// secret store is unavailable until Vault can be reached.
+	startupDegrade = "degrade"
+)
+
+var vaultStartups = []string{startupFail, startupDegrade}
+
+const defaultStartupRetry = 30 * time.Second
+
+// connectVault logs client in to Vault at startup. With degrade it also
+// checks that Vault is reachable and unsealed and, if it is not, holds the
+// circuit breaker open, so that every Vault operation fails fast with
+// errVaultUnavailable, and reports that the bot is degraded instead of
+// returning the error.
+func connectVault(ctx context.Context, client *api.Client, cfg *Config, degrade bool) (login *api.Secret, degraded bool, err error) {
+	if !degrade {
+		login, err = loginVault(ctx, client, cfg)
+		return login, false, err
+	}
+	if login, err = reachVault(ctx, client, cfg); err != nil {
+		slog.Error("Vault cannot be reached; starting degraded until it can", "event", "vault_startup", "retry_interval", cfg.VaultStartupRetry, "error", err)
+		cfg.vaultBreaker.hold()
+		return nil, true, nil
+	}
+	return login, false, nil
+}
+
+// reachVault logs client in, if AppRole is configured, and checks that
+// Vault is unsealed and accepts the bot's token.
+func reachVault(ctx context.Context, client *api.Client, cfg *Config) (*api.Secret, error) {
+	login, err := loginVault(ctx, client, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVault(ctx, client); err != nil {
+		return nil, err
+	}
+	return login, nil
+}
+
+// leaveDegraded checks Vault every cfg.VaultStartupRetry until it can be
+// reached, then releases the circuit breaker so that commands reach Vault
+// again and runs the self-test if SELF_TEST asks for one; the self-test's
+// requests go through the breaker too. It returns the login, or an error if
+// the self-test fails or ctx is cancelled first.
+func leaveDegraded(ctx context.Context, client *api.Client, cfg *Config) (*api.Secret, error) {
+	ticker := time.NewTicker(cfg.VaultStartupRetry)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		login, err := reachVault(ctx, client, cfg)
+		if err != nil {
+			slog.Warn("Vault still cannot be reached", "event", "vault_startup", "retry_in", cfg.VaultStartupRetry, "error", err)
+			continue
+		}
+
+		cfg.vaultBreaker.release()
+		slog.Info("Vault can be reached; leaving degraded mode", "event", "vault_startup")
+		if cfg.SelfTest && !cfg.DryRun {
+			if err := selfTest(ctx, client, cfg); err != nil {
+				return nil, fmt.Errorf("self-test: %w", err)
+			}
+		}
+		return login, nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDegradedStartup(t *testing.T) {
+	var sealed atomic.Bool
+	sealed.Store(true)
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/sys/seal-status" {
+			fmt.Fprintf(w, `{"sealed":%t}`, sealed.Load())
+			return
+		}
+		w.Write([]byte(`{"data":{"id":"hvs.test"}}`))
+	}))
+	defer vault.Close()
+
+	cfg := &Config{VaultAddr: vault.URL, VaultToken: "hvs.test", VaultMaxAttempts: 1, VaultStartupRetry: 10 * time.Millisecond, vaultBreaker: newCircuitBreaker(5, time.Minute)}
+	client, err := newVaultClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	call := func() error {
+		called = false
+		return cfg.vaultBreaker.do("read", func() error { called = true; return nil })
+	}
+
+	// Failing fast is left to the first Vault request with a static token.
+	if _, degraded, err := connectVault(context.Background(), client, cfg, false); err != nil || degraded {
+		t.Fatalf("connectVault() without degrade = %t, %v, want it to start normally", degraded, err)
+	}
+
+	_, degraded, err := connectVault(context.Background(), client, cfg, true)
+	if err != nil || !degraded {
+		t.Fatalf("connectVault() with Vault sealed = %t, %v, want a degraded start", degraded, err)
+	}
+	if err := call(); !errors.Is(err, errVaultUnavailable) || called {
+		t.Errorf("Vault request while degraded = %v, called %t, want it turned away", err, called)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := leaveDegraded(cancelled, client, cfg); !errors.Is(err, context.Canceled) {
+		t.Errorf("leaveDegraded() once cancelled = %v, want context.Canceled", err)
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { sealed.Store(false) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := leaveDegraded(ctx, client, cfg); err != nil {
+		t.Fatalf("leaveDegraded() = %v, want it to return once Vault is unsealed", err)
+	}
+	if err := call(); err != nil || !called {
+		t.Errorf("Vault request after leaving degraded mode = %v, called %t, want it let through", err, called)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stateTimeout bounds each request to the state store.
+const stateTimeout = 2 * time.Second
+
+// StateStore holds the bot's short-lived state: redelivered commands, shares
+// awaiting confirmation and rate limits. Every entry expires after the TTL it
+// was written with. Keeping it in Redis lets several replicas share it and
+// keeps it across restarts.
+type StateStore interface {
+	// SetNX stores value under key unless key is already set, and reports
+	// whether it did.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Get returns the value under key, reporting false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Take removes and returns the value under key, reporting false if there
+	// is none. Only one of several concurrent callers gets the value.
+	Take(ctx context.Context, key string) ([]byte, bool, error)
+	// Incr adds one to the counter under key and returns its new value. A
+	// counter that does not exist starts at zero and expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// newStateStore returns the state store cfg configures.
+func newStateStore(cfg *Config) StateStore {
+	if cfg.RedisOptions != nil {
+		return newRedisState(redis.NewClient(cfg.RedisOptions))
+	}
+	return newMemoryState()
+}
+
+// stateBackendEnv reads STATE_BACKEND and, for redis, REDIS_URL, returning the
+// options to connect to Redis with or nil to keep state in memory.
+func stateBackendEnv(errs *[]error) *redis.Options {
+	switch backend := stringEnv("STATE_BACKEND", "memory"); backend {
+	case "memory":
+		return nil
+	case "redis":
+		v := os.Getenv("REDIS_URL")
+		if v == "" {
+			*errs = append(*errs, fmt.Errorf("REDIS_URL is required when STATE_BACKEND is redis"))
+			return nil
+		}
+		opts, err := redis.ParseURL(v)
+		if err != nil {
+			*errs = append(*errs, fmt.Errorf("REDIS_URL %w", err))
+			return nil
+		}
+		return opts
+	default:
+		*errs = append(*errs, fmt.Errorf("STATE_BACKEND must be memory or redis, got %q", backend))
+		return nil
+	}
+}
+
+// memoryState is a StateStore in the bot's memory, so its state is lost when
+// the bot restarts and is not shared between replicas.
+type memoryState struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastPrune time.Time
+}
+
+type memoryEntry struct {
+	value   []byte
+	count   int64
+	expires time.Time
+}
+
+func newMemoryState() *memoryState {
+	return &memoryState{now: time.Now, entries: make(map[string]memoryEntry)}
+}
+
+// live returns the entry under key if it has not expired, dropping expired
+// entries once a minute so that the map does not grow without bound. It must
+// be called with s.mu held.
+func (s *memoryState) live(key string, now time.Time) (memoryEntry, bool) {
+	if now.Sub(s.lastPrune) >= time.Minute {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastPrune = now
+	}
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return memoryEntry{}, false
+	}
+	return e, true
+}
+
+func (s *memoryState) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if _, ok := s.live(key, now); ok {
+		return false, nil
+	}
+	s.entries[key] = memoryEntry{value: value, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *memoryState) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.live(key, s.now())
+	return e.value, ok, nil
+}
+
+func (s *memoryState) Take(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.live(key, s.now())
+	if !ok {
+		return nil, false, nil
+	}
+	delete(s.entries, key)
+	return e.value, true, nil
+}
+
+func (s *memoryState) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	e, ok := s.live(key, now)
+	if !ok {
+		e = memoryEntry{expires: now.Add(ttl)}
+	}
+	e.count++
+	s.entries[key] = e
+	return e.count, nil
+}
+
+// redisKeyPrefix namespaces the bot's keys in a Redis shared with other
+// applications.
+const redisKeyPrefix = "hush:"
+
+// redisIncr increments a counter and sets its expiry only when it is
+// created, so that a fixed window is not extended by every request in it.
+var redisIncr = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// redisState is a StateStore in Redis.
+type redisState struct {
+	client redis.UniversalClient
+}
+
+func newRedisState(client redis.UniversalClient) *redisState {
+	return &redisState{client: client}
+}
+
+func (s *redisState) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, redisKeyPrefix+key, value, ttl).Result()
+}
+
+func (s *redisState) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return redisValue(s.client.Get(ctx, redisKeyPrefix+key))
+}
+
+func (s *redisState) Take(ctx context.Context, key string) ([]byte, bool, error) {
+	return redisValue(s.client.GetDel(ctx, redisKeyPrefix+key))
+}
+
+// redisValue returns the value of a GET-like command, reporting false rather
+// than an error if the key was not set.
+func redisValue(cmd *redis.StringCmd) ([]byte, bool, error) {
+	value, err := cmd.Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *redisState) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return redisIncr.Run(ctx, s.client, []string{redisKeyPrefix + key}, ttl.Milliseconds()).Int64()
+}
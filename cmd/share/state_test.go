@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryState(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	s := newMemoryState()
+	s.now = func() time.Time { return now }
+
+	if ok, _ := s.SetNX(ctx, "a", []byte("1"), time.Minute); !ok {
+		t.Error("SetNX() on a new key = false")
+	}
+	if ok, _ := s.SetNX(ctx, "a", []byte("2"), time.Minute); ok {
+		t.Error("SetNX() on a set key = true")
+	}
+	if v, ok, _ := s.Take(ctx, "a"); !ok || string(v) != "1" {
+		t.Errorf("Take() = %q, %v, want 1", v, ok)
+	}
+	if _, ok, _ := s.Take(ctx, "a"); ok {
+		t.Error("second Take() = true")
+	}
+
+	for want := int64(1); want <= 3; want++ {
+		if n, _ := s.Incr(ctx, "n", time.Minute); n != want {
+			t.Errorf("Incr() = %d, want %d", n, want)
+		}
+	}
+
+	s.SetNX(ctx, "b", nil, time.Minute)
+	now = now.Add(time.Minute)
+	if _, ok, _ := s.Take(ctx, "b"); ok {
+		t.Error("Take() after the TTL = true")
+	}
+	if n, _ := s.Incr(ctx, "n", time.Minute); n != 1 {
+		t.Errorf("Incr() after the TTL = %d, want 1", n)
+	}
+	if len(s.entries) != 1 {
+		t.Errorf("entries after the TTL = %d, want expired ones pruned", len(s.entries))
+	}
+}
+
+func TestWindowLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	state := newMemoryState()
+	state.now = func() time.Time { return now }
+	l := newWindowLimiter(state, 2, time.Minute)
+	l.now = state.now
+
+	if !l.Allow("U1") || !l.Allow("U1") {
+		t.Fatal("Allow() within the limit = false")
+	}
+	if l.Allow("U1") {
+		t.Error("Allow() over the limit = true")
+	}
+	if !l.Allow("U2") {
+		t.Error("Allow() for another key = false")
+	}
+	now = now.Add(time.Minute)
+	if !l.Allow("U1") {
+		t.Error("Allow() in the next window = false")
+	}
+}
+
+func TestPendingSharesRoundTrip(t *testing.T) {
+	state := newMemoryState()
+	p := newPendingShares(state, []byte("signing key"))
+	now := time.Now()
+	req := shareRequest{
+		shareArgs:   shareArgs{ttl: time.Hour, uses: 2, burn: true, to: []string{"<@U2>"}, secret: "line 1\nline 2"},
+		teamID:      "T1",
+		userID:      "U1",
+		userName:    "alice",
+		responseURL: "https://hooks.slack.com/1",
+	}
+	nonce, err := p.add(req, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range state.entries {
+		if strings.Contains(string(e.value), "line 1") {
+			t.Error("pending share is stored in plaintext")
+		}
+	}
+	if _, ok := p.take(nonce, "U2", now); ok {
+		t.Error("take() by another user succeeded")
+	}
+	got, ok := p.take(nonce, "U1", now)
+	if !ok {
+		t.Fatal("take() by the sharer failed")
+	}
+	if got.secret != req.secret || got.ttl != req.ttl || got.uses != 2 || !got.burn || got.to[0] != "<@U2>" || got.teamID != "T1" || got.responseURL != req.responseURL {
+		t.Errorf("take() = %+v, want %+v", got, req)
+	}
+}
+
+func TestLoadConfigStateBackend(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("STATE_BACKEND", "redis")
+	t.Setenv("REDIS_URL", "redis://localhost:6379/2")
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "LINK_SIGNING_KEY") {
+		t.Errorf("LoadConfig() without LINK_SIGNING_KEY error = %v, want it to name LINK_SIGNING_KEY", err)
+	}
+
+	t.Setenv("LINK_SIGNING_KEY", "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.RedisOptions == nil || cfg.RedisOptions.Addr != "localhost:6379" || cfg.RedisOptions.DB != 2 {
+		t.Errorf("RedisOptions = %+v, want localhost:6379 database 2", cfg.RedisOptions)
+	}
+
+	t.Setenv("REDIS_URL", "")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "REDIS_URL") {
+		t.Errorf("LoadConfig() without REDIS_URL error = %v", err)
+	}
+	t.Setenv("STATE_BACKEND", "etcd")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "STATE_BACKEND") {
+		t.Errorf("LoadConfig() with an unknown backend error = %v", err)
+	}
+}
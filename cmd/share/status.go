@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// secretStatus is the body of a status response. Only Available is set for
+// a secret that cannot be retrieved, and UsesRemaining is left out for one
+// with unlimited uses.
+type secretStatus struct {
+	Available     bool   `json:"available"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	TTLSeconds    int64  `json:"ttl_remaining_seconds,omitempty"`
+	UsesRemaining *int   `json:"uses_remaining,omitempty"`
+	Burn          bool   `json:"burn,omitempty"`
+}
+
+// handleStatus reports whether a secret can still be retrieved, and for how
+// much longer and how many more times, from its metadata alone, so checking
+// a link never spends one of its uses. A secret shared with --to only
+// reports its status to the personal link of one of its recipients.
+func (rs *retrievalServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	secretID := r.PathValue("secretID")
+	paths, ok := rs.paths(r)
+	if !ok || !validSecretID(secretID) {
+		statusChecksTotal.WithLabelValues(outcomeNotFound).Inc()
+		writeStatus(w, http.StatusNotFound, secretStatus{})
+		return
+	}
+
+	ctx, cancel := vaultContext(r.Context(), rs.cfg)
+	defer cancel()
+	meta, err := readSecretMetadata(ctx, rs.secrets, paths, secretID)
+	now := time.Now()
+	if errors.Is(err, errSecretNotFound) || err == nil && meta.expired(now) {
+		statusChecksTotal.WithLabelValues(outcomeNotFound).Inc()
+		writeStatus(w, http.StatusNotFound, secretStatus{})
+		return
+	}
+	if vaultSealed(err) {
+		slog.Error("Vault is sealed", "event", "status", "secret_id", secretID, "error", err)
+		statusChecksTotal.WithLabelValues(outcomeError).Inc()
+		http.Error(w, "Vault is sealed; contact an admin", http.StatusServiceUnavailable)
+		return
+	}
+	if errors.Is(err, errVaultUnavailable) {
+		statusChecksTotal.WithLabelValues(outcomeError).Inc()
+		http.Error(w, "the secret store is temporarily unavailable; try again in a minute", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to read secret metadata", "event", "status", "secret_id", secretID, "error", err)
+		statusChecksTotal.WithLabelValues(outcomeError).Inc()
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if len(meta.AllowedUsers) > 0 {
+		u := r.URL.Query().Get("u")
+		if !verifyLink(rs.cfg.LinkSigningKey, secretID, u, r.URL.Query().Get("sig")) || !slices.Contains(meta.AllowedUsers, u) {
+			statusChecksTotal.WithLabelValues(outcomeDenied).Inc()
+			http.Error(w, "this secret was shared with someone else", http.StatusForbidden)
+			return
+		}
+	}
+
+	statusChecksTotal.WithLabelValues(outcomeSuccess).Inc()
+	uses := meta.UsesRemaining
+	if meta.Burn {
+		uses = 1
+	}
+	writeStatus(w, http.StatusOK, secretStatus{
+		Available:     true,
+		ExpiresAt:     meta.ExpiresAt.UTC().Format(time.RFC3339),
+		TTLSeconds:    int64(meta.ExpiresAt.Sub(now).Seconds()),
+		UsesRemaining: usesRemaining(uses, uses == 0),
+		Burn:          meta.Burn,
+	})
+}
+
+func writeStatus(w http.ResponseWriter, code int, status secretStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSecretStatus(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cfg := &Config{LinkSigningKey: key, RetrievalAddr: defaultRetrievalAddr, VaultSecretsMount: defaultSecretsMount, VaultKVVersion: defaultKVVersion}
+	store := newFakeSecretStore()
+	paths := cfg.kvPaths("")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	storeSecret(context.Background(), store, paths, "secret-1", secretPayload{Text: "hunter2"}, nil)
+	writeSecretMetadata(context.Background(), store, paths, "secret-1", secretMetadata{ExpiresAt: expires, UsesRemaining: 2})
+	storeSecret(context.Background(), store, paths, "secret-2", secretPayload{Text: "hunter3"}, nil)
+	writeSecretMetadata(context.Background(), store, paths, "secret-2", secretMetadata{ExpiresAt: expires, AllowedUsers: []string{"U123"}})
+	rs := &retrievalServer{secrets: store, cfg: cfg, audit: multiAuditLogger(nil), state: newMemoryState()}
+
+	check := func(secretID string, query url.Values) (int, secretStatus) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/v1/status/"+secretID+"?"+query.Encode(), nil)
+		req.SetPathValue("secretID", secretID)
+		rec := httptest.NewRecorder()
+		rs.handleStatus(rec, req)
+		var status secretStatus
+		json.Unmarshal(rec.Body.Bytes(), &status)
+		return rec.Code, status
+	}
+
+	for i := 0; i < 3; i++ {
+		code, status := check("secret-1", nil)
+		if code != http.StatusOK || !status.Available || status.UsesRemaining == nil || *status.UsesRemaining != 2 || status.ExpiresAt != expires.UTC().Format(time.RFC3339) || status.TTLSeconds < 3500 {
+			t.Fatalf("status %d = %d %+v, want 2 uses and about an hour left", i+1, code, status)
+		}
+	}
+	if meta, err := readSecretMetadata(context.Background(), store, paths, "secret-1"); err != nil || meta.UsesRemaining != 2 {
+		t.Errorf("metadata after status checks = %+v, %v, want no use spent", meta, err)
+	}
+
+	if code, _ := check("secret-2", nil); code != http.StatusForbidden {
+		t.Errorf("restricted status without a link = %d, want 403", code)
+	}
+	code, status := check("secret-2", url.Values{"u": {"U123"}, "sig": {signLink(key, "secret-2", "U123")}})
+	if code != http.StatusOK || status.UsesRemaining != nil {
+		t.Errorf("restricted status for its recipient = %d %+v, want unlimited uses", code, status)
+	}
+
+	if code, status := check("secret-3", nil); code != http.StatusNotFound || status.Available {
+		t.Errorf("missing secret status = %d %+v, want unavailable", code, status)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const defaultSweepInterval = 15 * time.Minute
+
+// sweeper deletes shared secrets that have outlived their token. A token
+// expiring makes its secret unreadable but leaves the data in Vault, so
+// without the sweeper secrets that are never retrieved would pile up.
+type sweeper struct {
+	b *bot
+	// seen records when secrets without a creation time were first
+	// listed. Such secrets are either still being shared or were orphaned
+	// by a share that failed part-way, and are aged from that point.
+	seen map[string]time.Time
+}
+
+func newSweeper(b *bot) *sweeper {
+	return &sweeper{b: b, seen: map[string]time.Time{}}
+}
+
+// run sweeps every cfg.SweepInterval, starting straight away, until ctx is
+// cancelled.
+func (s *sweeper) run(ctx context.Context) {
+	ticker := time.NewTicker(s.b.cfg.SweepInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.sweep(ctx, time.Now()); err != nil {
+			slog.Error("Failed to list shared secrets in Vault", "event", "sweep", "error", err)
+		} else if n > 0 {
+			slog.Info("Swept expired secrets", "event", "sweep", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep deletes every secret, in every workspace, that has expired or is
+// older than cfg.SecretMaxAge, revoking its token first, and returns how
+// many it deleted. Secrets that cannot be swept are logged and retried on
+// the next sweep.
+func (s *sweeper) sweep(ctx context.Context, now time.Time) (int, error) {
+	var listed []string
+	var errs []error
+	swept := 0
+	teams, err := s.b.storedTeams(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, team := range teams {
+		paths := s.b.cfg.kvPaths(team)
+		listCtx, cancel := vaultContext(ctx, s.b.cfg)
+		ids, err := listSecretIDs(listCtx, s.b.secrets, paths)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		listed = append(listed, ids...)
+
+		for _, id := range ids {
+			if s.sweepSecret(ctx, paths, id, now) {
+				swept++
+			}
+		}
+	}
+
+	// Without a complete listing, secrets that were not listed may still
+	// exist, so keep their first sightings.
+	if len(errs) == 0 {
+		for id := range s.seen {
+			if !slices.Contains(listed, id) {
+				delete(s.seen, id)
+			}
+		}
+	}
+	return swept, errors.Join(errs...)
+}
+
+// sweepSecret deletes id from paths if it is due and reports whether it did.
+func (s *sweeper) sweepSecret(ctx context.Context, paths kvPaths, id string, now time.Time) bool {
+	ctx, cancel := vaultContext(ctx, s.b.cfg)
+	defer cancel()
+
+	meta, err := readSecretMetadata(ctx, s.b.secrets, paths, id)
+	if err != nil && !errors.Is(err, errSecretNotFound) {
+		slog.Error("Failed to read secret metadata from Vault", "event", "sweep", "secret_id", id, "error", err)
+		sweptTotal.WithLabelValues(outcomeError).Inc()
+		return false
+	}
+
+	created := meta.CreatedAt
+	if created.IsZero() {
+		if _, ok := s.seen[id]; !ok {
+			s.seen[id] = now
+		}
+		created = s.seen[id]
+	}
+	if !meta.expired(now) && now.Sub(created) < s.b.cfg.SecretMaxAge {
+		return false
+	}
+
+	if meta.TokenAccessor != "" {
+		if err := revokeTokenAccessor(ctx, s.b.tokens, meta.TokenAccessor); err != nil {
+			slog.Error("Failed to revoke token", "event", "sweep", "secret_id", id, "error", err)
+			sweptTotal.WithLabelValues(outcomeError).Inc()
+			return false
+		}
+		s.b.issued.remove(meta.TokenAccessor)
+	}
+	if err := deleteSecretLocked(ctx, s.b.state, s.b.secrets, paths, id); err != nil {
+		slog.Error("Failed to delete secret from Vault", "event", "sweep", "secret_id", id, "error", err)
+		sweptTotal.WithLabelValues(outcomeError).Inc()
+		return false
+	}
+	delete(s.seen, id)
+
+	if meta.SharedBy != "" {
+		err := s.b.updateIndex(ctx, paths.team, meta.SharedBy, func(ids []string) []string {
+			return slices.DeleteFunc(ids, func(indexed string) bool { return indexed == id })
+		})
+		if err != nil {
+			slog.Error("Failed to remove secret from index", "event", "sweep", "secret_id", id, "error", err)
+		}
+	}
+
+	sweptTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Debug("Secret swept", "event", "sweep", "secret_id", id, "created_at", created, "expires_at", meta.ExpiresAt)
+	audit(s.b.audit, AuditEvent{Action: auditExpire, SecretID: id, SharedBy: meta.SharedBy})
+	return true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, _, _ := newTestBot(t, store, tokens)
+	b.cfg.SecretMaxAge = 24 * time.Hour
+	paths := b.cfg.kvPaths("")
+	now := time.Now()
+
+	share := func(id string, meta secretMetadata) {
+		t.Helper()
+		if err := storeSecret(context.Background(), store, paths, id, secretPayload{Text: "hunter2"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeSecretMetadata(context.Background(), store, paths, id, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	share("fresh", secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-fresh", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)})
+	share("expired", secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-expired", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Hour)})
+	share("old", secretMetadata{SharedBy: "U1", TokenAccessor: "accessor-old", CreatedAt: now.Add(-25 * time.Hour)})
+	// A share that failed before its metadata was written leaves a secret
+	// with no creation time.
+	store.WriteWithContext(context.Background(), paths.metadata("orphan"), map[string]interface{}{})
+	writeSecretIndex(context.Background(), store, paths, "U1", []string{"fresh", "expired", "old"})
+
+	s := newSweeper(b)
+	n, err := s.sweep(context.Background(), now)
+	if err != nil {
+		t.Fatalf("sweep() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("sweep() = %d, want 2", n)
+	}
+	for _, id := range []string{"expired", "old"} {
+		if _, err := readSecretMetadata(context.Background(), store, paths, id); err != errSecretNotFound {
+			t.Errorf("%s metadata read error = %v, want it deleted", id, err)
+		}
+	}
+	for _, id := range []string{"fresh", "orphan"} {
+		if _, err := readSecretMetadata(context.Background(), store, paths, id); err != nil {
+			t.Errorf("%s was swept: %v", id, err)
+		}
+	}
+	slices.Sort(tokens.revoked)
+	if want := []string{"accessor-expired", "accessor-old"}; !slices.Equal(tokens.revoked, want) {
+		t.Errorf("revoked = %q, want %q", tokens.revoked, want)
+	}
+	if ids, _ := readSecretIndex(context.Background(), store, paths, "U1"); !slices.Equal(ids, []string{"fresh"}) {
+		t.Errorf("index = %q, want only fresh", ids)
+	}
+
+	// The orphan is aged from when it was first seen.
+	if n, _ := s.sweep(context.Background(), now.Add(23*time.Hour)); n != 1 {
+		t.Errorf("sweep() a day later = %d, want 1 for fresh", n)
+	}
+	if _, err := readSecretMetadata(context.Background(), store, paths, "orphan"); err != nil {
+		t.Errorf("orphan swept before SECRET_MAX_AGE: %v", err)
+	}
+	if n, _ := s.sweep(context.Background(), now.Add(24*time.Hour)); n != 1 {
+		t.Errorf("sweep() once the orphan is SECRET_MAX_AGE old = %d, want 1", n)
+	}
+	if ids, _ := listSecretIDs(context.Background(), store, paths); len(ids) != 0 {
+		t.Errorf("secrets left after sweeping = %q", ids)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// defaultTokenDisplayName is the display name of the tokens issued to
+// recipients when no VAULT_TOKEN_DISPLAY_NAME is configured.
+const defaultTokenDisplayName = "Secret Share"
+
+// Vault keeps at most this many metadata pairs on a token, with keys and
+// values of at most this many bytes.
+const (
+	maxTokenMetadataPairs = 64
+	maxTokenMetadataKey   = 128
+	maxTokenMetadataValue = 512
+)
+
+// reservedTokenMetadata are the metadata keys the bot sets on every token
+// itself, which VAULT_TOKEN_METADATA cannot override.
+var reservedTokenMetadata = []string{"secret_id", "shared_by", "shared_by_name"}
+
+// tokenNameData is what a token display name template is executed with.
+type tokenNameData struct {
+	SecretID string
+	// SharedBy and SharedByName are the Slack user ID and name of the
+	// sharer.
+	SharedBy     string
+	SharedByName string
+}
+
+// loadTokenDisplayName parses text as a token display name template and
+// checks that it renders to a name against sample data, as
+// loadShareTemplate does for share messages.
+func loadTokenDisplayName(text string) (*template.Template, error) {
+	tmpl, err := template.New("token").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("is not a valid template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, tokenNameData{SecretID: "secret-1", SharedBy: "U123", SharedByName: "alice"}); err != nil {
+		return nil, fmt.Errorf("cannot be rendered: %w", err)
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return nil, fmt.Errorf("renders to an empty name")
+	}
+	return tmpl, nil
+}
+
+// tokenDisplayName renders the display name of a token issued with data
+// from cfg's template, falling back to defaultTokenDisplayName if there is
+// none or it fails.
+func tokenDisplayName(cfg *Config, data tokenNameData) string {
+	if cfg == nil || cfg.TokenDisplayName == nil {
+		return defaultTokenDisplayName
+	}
+	var b strings.Builder
+	if err := cfg.TokenDisplayName.Execute(&b, data); err != nil || strings.TrimSpace(b.String()) == "" {
+		slog.Error("Failed to render token display name", "secret_id", data.SecretID, "error", err)
+		return defaultTokenDisplayName
+	}
+	return b.String()
+}
+
+// tokenDisplayNameEnv loads the token display name template from
+// VAULT_TOKEN_DISPLAY_NAME, returning nil if it is not set.
+func tokenDisplayNameEnv(errs *[]error) *template.Template {
+	text := os.Getenv("VAULT_TOKEN_DISPLAY_NAME")
+	if text == "" {
+		return nil
+	}
+	tmpl, err := loadTokenDisplayName(text)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("VAULT_TOKEN_DISPLAY_NAME %w", err))
+	}
+	return tmpl
+}
+
+// parseTokenMetadata parses text, comma-separated key=value pairs, as
+// metadata to add to every token, checked against Vault's limits.
+func parseTokenMetadata(text string) (map[string]string, error) {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(text, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case !ok || key == "":
+			return nil, fmt.Errorf("entry %q must be key=value", pair)
+		case slices.Contains(reservedTokenMetadata, key):
+			return nil, fmt.Errorf("key %q is set by the bot itself", key)
+		case len(key) > maxTokenMetadataKey:
+			return nil, fmt.Errorf("key %q is longer than %d bytes", key, maxTokenMetadataKey)
+		case len(value) > maxTokenMetadataValue:
+			return nil, fmt.Errorf("value of %q is longer than %d bytes", key, maxTokenMetadataValue)
+		case strings.ContainsFunc(key+value, unicode.IsControl):
+			return nil, fmt.Errorf("entry %q contains a control character", key)
+		}
+		if _, dup := meta[key]; dup {
+			return nil, fmt.Errorf("key %q is given more than once", key)
+		}
+		meta[key] = value
+	}
+	if len(meta)+len(reservedTokenMetadata) > maxTokenMetadataPairs {
+		return nil, fmt.Errorf("has %d keys, more than the %d Vault allows alongside the bot's own", len(meta), maxTokenMetadataPairs-len(reservedTokenMetadata))
+	}
+	return meta, nil
+}
+
+// tokenMetadataEnv loads the extra token metadata from
+// VAULT_TOKEN_METADATA, returning nil if it is not set.
+func tokenMetadataEnv(errs *[]error) map[string]string {
+	text := os.Getenv("VAULT_TOKEN_METADATA")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	meta, err := parseTokenMetadata(text)
+	if err != nil {
+		*errs = append(*errs, fmt.Errorf("VAULT_TOKEN_METADATA %w", err))
+	}
+	return meta
+}
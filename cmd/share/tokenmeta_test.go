@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestParseTokenMetadata(t *testing.T) {
+	tests := []struct {
+		text    string
+		wantErr string
+	}{
+		{"team=platform, env = prod,", ""},
+		{"team", "must be key=value"},
+		{"secret_id=x", "set by the bot itself"},
+		{"team=a,team=b", "more than once"},
+		{strings.Repeat("k", maxTokenMetadataKey+1) + "=v", "longer than 128 bytes"},
+		{"k=" + strings.Repeat("v", maxTokenMetadataValue+1), "longer than 512 bytes"},
+		{"k=a\x00b", "control character"},
+	}
+	for _, tt := range tests {
+		meta, err := parseTokenMetadata(tt.text)
+		if tt.wantErr == "" {
+			if err != nil || meta["team"] != "platform" || meta["env"] != "prod" {
+				t.Errorf("parseTokenMetadata(%q) = %v, %v", tt.text, meta, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("parseTokenMetadata(%q) error = %v, want it to mention %q", tt.text, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigTokenMetadata(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("VAULT_TOKEN_DISPLAY_NAME", "hush-{{.SharedByName}}-{{.SecretID}}")
+	t.Setenv("VAULT_TOKEN_METADATA", "team=platform")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := tokenDisplayName(cfg, tokenNameData{SecretID: "secret-1", SharedByName: "alice"}); got != "hush-alice-secret-1" {
+		t.Errorf("tokenDisplayName() = %q", got)
+	}
+
+	t.Setenv("VAULT_TOKEN_DISPLAY_NAME", "{{.Team}}")
+	t.Setenv("VAULT_TOKEN_METADATA", "shared_by=me")
+	_, err = LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "VAULT_TOKEN_DISPLAY_NAME") || !strings.Contains(err.Error(), "VAULT_TOKEN_METADATA") {
+		t.Errorf("LoadConfig() with bad token settings error = %v, want both named", err)
+	}
+}
+
+func TestShareTokenMetadata(t *testing.T) {
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, newFakeSecretStore(), tokens)
+	tmpl, err := loadTokenDisplayName("hush {{.SharedBy}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.cfg.TokenDisplayName = tmpl
+	b.cfg.TokenMetadata = map[string]string{"team": "platform"}
+
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", UserName: "alice", ResponseURL: responseURL})
+	if len(tokens.created) != 1 {
+		t.Fatalf("tokens created = %d, want 1", len(tokens.created))
+	}
+	req := tokens.created[0]
+	if req.DisplayName != "hush U1" || req.Metadata["team"] != "platform" || req.Metadata["shared_by_name"] != "alice" || req.Metadata["secret_id"] == "" {
+		t.Errorf("token request = %+v, want the configured name and metadata alongside the bot's own", req)
+	}
+}
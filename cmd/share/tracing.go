@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records the bot's spans. It delegates to the provider installed by
+// setupTracing, and is a no-op until then.
+var tracer = otel.Tracer("github.com/vdparikh/hush/cmd/share")
+
+// Span attribute keys. Like metric labels, they never carry a secret's value.
+const (
+	attrSecretID  = attribute.Key("hush.secret_id")
+	attrOutcome   = attribute.Key("hush.outcome")
+	attrUserID    = attribute.Key("slack.user_id")
+	attrTeamID    = attribute.Key("slack.team_id")
+	attrOperation = attribute.Key("vault.operation")
+	attrPath      = attribute.Key("vault.path")
+)
+
+// tracingEnabled reports whether an OTLP endpoint is configured with the
+// standard OpenTelemetry environment variables.
+func tracingEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// setupTracing exports spans over OTLP/HTTP to the endpoint in the
+// OTEL_EXPORTER_OTLP_* environment variables, which also configure its
+// headers, TLS and timeout. It returns a function that flushes the spans
+// still buffered, for shutdown.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the default
+	// service name.
+	res, err := resource.Merge(
+		resource.NewSchemaless(semconv.ServiceName("hush")),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// endSpan records outcome on span, marking it failed for outcomeError, and
+// ends it.
+func endSpan(span trace.Span, outcome string) {
+	span.SetAttributes(attrOutcome.String(outcome))
+	if outcome == outcomeError {
+		span.SetStatus(codes.Error, outcome)
+	}
+	span.End()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestShareSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	saved := tracer
+	tracer = provider.Tracer("test")
+	t.Cleanup(func() { tracer = saved })
+
+	tokens := &fakeTokenCreator{}
+	b, responseURL, _ := newTestBot(t, newFakeSecretStore(), tokens)
+	b.secrets, b.tokens = instrumentedStore{b.secrets}, instrumentedTokens{b.tokens}
+	b.handleShareCommand(context.Background(), slack.SlashCommand{Command: "/share", Text: "--no-notify hunter2", UserID: "U1", ResponseURL: responseURL})
+	secretID := tokens.created[0].Metadata["secret_id"]
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+		for _, a := range s.Attributes() {
+			if strings.Contains(a.Value.Emit(), "hunter2") {
+				t.Errorf("span %s attribute %s carries the secret", s.Name(), a.Key)
+			}
+		}
+	}
+	share, ok := spans["share"]
+	if !ok {
+		t.Fatalf("spans = %v, want a share span", spans)
+	}
+	attrs := map[string]string{}
+	for _, a := range share.Attributes() {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	if attrs["hush.secret_id"] != secretID || attrs["hush.outcome"] != outcomeSuccess {
+		t.Errorf("share span attributes = %v, want the secret ID and a success outcome", attrs)
+	}
+	for _, name := range []string{"vault.write", "vault.token_create", "slack.respond"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Errorf("no %s span", name)
+			continue
+		}
+		if s.Parent().SpanID() != share.SpanContext().SpanID() {
+			t.Errorf("%s span is not a child of the share span", name)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+const transferUsage = "Usage: `/transfer <secretID> @newowner`"
+
+// transferDeniedMessage is the reply to someone who may not transfer a
+// secret.
+const transferDeniedMessage = "Only the person who shared this secret, or an admin, can transfer it."
+
+// errTransferDenied and errAlreadyOwner stop a transfer when the secret's
+// owner has changed since it was first read.
+var (
+	errTransferDenied = errors.New("not the owner of the secret")
+	errAlreadyOwner   = errors.New("already the owner of the secret")
+)
+
+// handleTransferCommand makes another user the owner of a shared secret, such
+// as before its sharer goes on leave, so that they can revoke, extend and
+// rotate it, are told when it is retrieved and see it in /list. The sharer
+// and admins may transfer a secret.
+func (b *bot) handleTransferCommand(ctx context.Context, cmd slack.SlashCommand) {
+	secretID, ref, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	ref = strings.TrimSpace(ref)
+	if secretID == "" || ref == "" || strings.ContainsAny(ref, " ,") {
+		sendSlackResponse(b.slack, cmd.ResponseURL, "Please provide the ID of the secret to transfer and who should own it. "+transferUsage)
+		return
+	}
+	if !validSecretID(secretID) {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found.", secretID))
+		return
+	}
+	ids, err := b.resolveRecipients(cmd.TeamID, []string{ref})
+	if err != nil {
+		transfersTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, err.Error())
+		return
+	}
+	newOwner := ids[0]
+
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	paths := b.cfg.kvPaths(cmd.TeamID)
+	meta, err := readSecretMetadata(ctx, b.secrets, paths, secretID)
+	if errors.Is(err, errSecretNotFound) {
+		transfersTotal.WithLabelValues(outcomeNotFound).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("No secret with ID `%s` was found. It may have already been retrieved, revoked or swept away.", secretID))
+		return
+	}
+	if err != nil {
+		transfersTotal.WithLabelValues(outcomeError).Inc()
+		b.reportFailure(cmd.ResponseURL, "Failed to read secret metadata from Vault", storeFailure("transfer the secret", err), "event", "transfer", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+	if meta.SharedBy != cmd.UserID && !b.cfg.isAdmin(cmd.UserID) {
+		transfersTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, transferDeniedMessage)
+		return
+	}
+	if meta.SharedBy == newOwner {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("<@%s> already owns secret `%s`.", newOwner, secretID))
+		return
+	}
+
+	// Only the owner changes, so that a retrieval since the read above is
+	// not undone. The checks above are made again in case the secret was
+	// transferred in the meantime.
+	var previous string
+	err = updateSecretMetadata(ctx, b.state, b.secrets, paths, secretID, func(m *secretMetadata) error {
+		if m.SharedBy != cmd.UserID && !b.cfg.isAdmin(cmd.UserID) {
+			return errTransferDenied
+		}
+		if m.SharedBy == newOwner {
+			return errAlreadyOwner
+		}
+		previous = m.SharedBy
+		m.SharedBy = newOwner
+		m.SharedByName = ""
+		return nil
+	})
+	if errors.Is(err, errTransferDenied) {
+		transfersTotal.WithLabelValues(outcomeDenied).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, transferDeniedMessage)
+		return
+	}
+	if errors.Is(err, errAlreadyOwner) {
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("<@%s> already owns secret `%s`.", newOwner, secretID))
+		return
+	}
+	if errors.Is(err, errSecretNotFound) {
+		transfersTotal.WithLabelValues(outcomeNotFound).Inc()
+		sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Secret `%s` was retrieved, revoked or swept away before it could be transferred.", secretID))
+		return
+	}
+	if err != nil {
+		transfersTotal.WithLabelValues(outcomeError).Inc()
+		b.reportFailure(cmd.ResponseURL, "Failed to store secret metadata in Vault", storeFailure("transfer the secret", err), "event", "transfer", "secret_id", secretID, "user_id", cmd.UserID)
+		return
+	}
+
+	// The indexes only drive /list and the Home tab, so the transfer stands
+	// even if they cannot be updated.
+	if err := b.updateIndex(ctx, cmd.TeamID, previous, func(ids []string) []string {
+		return slices.DeleteFunc(ids, func(id string) bool { return id == secretID })
+	}); err != nil {
+		slog.Error("Failed to remove transferred secret from index", "event", "transfer", "secret_id", secretID, "user_id", previous, "error", err)
+	}
+	if err := b.updateIndex(ctx, cmd.TeamID, newOwner, func(ids []string) []string { return append(ids, secretID) }); err != nil {
+		slog.Error("Failed to add transferred secret to index", "event", "transfer", "secret_id", secretID, "user_id", newOwner, "error", err)
+	}
+
+	transfersTotal.WithLabelValues(outcomeSuccess).Inc()
+	slog.Info("Secret transferred", "event", "transfer", "secret_id", secretID, "user_id", cmd.UserID, "previous_owner", previous, "new_owner", newOwner)
+	audit(b.audit, AuditEvent{
+		Action:        auditTransfer,
+		SecretID:      secretID,
+		SharedBy:      newOwner,
+		PreviousOwner: previous,
+		Actor:         cmd.UserID,
+	})
+
+	text := fmt.Sprintf("<@%s> made you the owner of secret `%s`, which <@%s> shared. You can now `/revoke`, `/extend` and `/rotate` it, and see it in `/list`.", cmd.UserID, secretID, previous)
+	if meta.Label != "" {
+		text = fmt.Sprintf("<@%s> made you the owner of secret `%s` (%s), which <@%s> shared. You can now `/revoke`, `/extend` and `/rotate` it, and see it in `/list`.", cmd.UserID, secretID, meta.Label, previous)
+	}
+	if err := postSlack(b.api(cmd.TeamID), "transfer_notice", newOwner, slack.MsgOptionText(text, false)); err != nil {
+		slog.Error("Failed to tell new owner about transferred secret", "event", "transfer", "secret_id", secretID, "user_id", newOwner, "error", err)
+	}
+	sendSlackResponse(b.slack, cmd.ResponseURL, fmt.Sprintf("Secret `%s` now belongs to <@%s>, who has been sent a DM. Only they can revoke, extend or rotate it from now on.", secretID, newOwner))
+}
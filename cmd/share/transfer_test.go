@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestTransferCommand(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.AdminUsers = []string{"UADMIN"}
+	paths := b.cfg.kvPaths("")
+
+	var dms []string
+	slackAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/chat.postMessage" {
+			r.ParseForm()
+			dms = append(dms, r.Form.Get("channel")+": "+r.Form.Get("text"))
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer slackAPI.Close()
+	b.workspaces = newWorkspaces(b.cfg, slack.New("xoxb-test", slack.OptionAPIURL(slackAPI.URL+"/")))
+
+	meta := secretMetadata{SharedBy: "U1", SharedByName: "alice", TokenAccessor: "accessor-old", CreatedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), UsesRemaining: 1}
+	if err := writeSecretMetadata(context.Background(), store, paths, "secret-1", meta); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeSecretIndex(context.Background(), store, paths, "U1", []string{"secret-0", "secret-1"}); err != nil {
+		t.Fatal(err)
+	}
+	transfer := func(userID, text string) string {
+		t.Helper()
+		b.handleTransferCommand(context.Background(), slack.SlashCommand{Command: "/transfer", Text: text, UserID: userID, ResponseURL: responseURL})
+		got := replies()
+		return got[len(got)-1]
+	}
+
+	if got := transfer("U1", "secret-1"); !strings.Contains(got, "Usage") {
+		t.Errorf("reply without a new owner = %q, want the usage", got)
+	}
+	if got := transfer("U1", "secret-2 <@U2>"); !strings.Contains(got, "No secret") {
+		t.Errorf("reply for an unknown secret = %q, want not found", got)
+	}
+	if got := transfer("U3", "secret-1 <@U3>"); !strings.Contains(got, "Only the person who shared") {
+		t.Errorf("reply to another user = %q, want a denial", got)
+	}
+	if got := transfer("U1", "secret-1 <@U1>"); !strings.Contains(got, "already owns") {
+		t.Errorf("reply for a transfer to the owner = %q, want it refused", got)
+	}
+
+	if got := transfer("U1", "secret-1 <@U2|bob>"); !strings.Contains(got, "now belongs to <@U2>") {
+		t.Fatalf("reply = %q, want the transfer confirmed", got)
+	}
+	if len(dms) != 1 || !strings.HasPrefix(dms[0], "U2: <@U1> made you the owner of secret `secret-1`") {
+		t.Errorf("DMs = %q, want the new owner told", dms)
+	}
+	updated, err := readSecretMetadata(context.Background(), store, paths, "secret-1")
+	if err != nil || updated.SharedBy != "U2" || updated.SharedByName != "" || updated.TokenAccessor != "accessor-old" {
+		t.Errorf("metadata = %+v, %v, want only the owner changed", updated, err)
+	}
+	if ids, _ := readSecretIndex(context.Background(), store, paths, "U1"); !slices.Equal(ids, []string{"secret-0"}) {
+		t.Errorf("old owner's index = %q, want the secret removed", ids)
+	}
+	if ids, _ := readSecretIndex(context.Background(), store, paths, "U2"); !slices.Equal(ids, []string{"secret-1"}) {
+		t.Errorf("new owner's index = %q, want the secret added", ids)
+	}
+
+	// Permissions follow the new owner.
+	b.handleExtendCommand(context.Background(), slack.SlashCommand{Command: "/extend", Text: "secret-1 2h", UserID: "U1", ResponseURL: responseURL})
+	if got := replies(); !strings.Contains(got[len(got)-1], "Only the person who shared") {
+		t.Errorf("extend by the old owner = %q, want a denial", got[len(got)-1])
+	}
+	if got := transfer("U1", "secret-1 <@U1>"); !strings.Contains(got, "Only the person who shared") {
+		t.Errorf("transfer back by the old owner = %q, want a denial", got)
+	}
+	if got := transfer("UADMIN", "secret-1 <@U3>"); !strings.Contains(got, "now belongs to <@U3>") {
+		t.Fatalf("reply to an admin = %q, want the transfer confirmed", got)
+	}
+	b.handleRevokeCommand(context.Background(), slack.SlashCommand{Command: "/revoke", Text: "secret-1", UserID: "U3", ResponseURL: responseURL})
+	if !slices.Equal(tokens.revoked, []string{"accessor-old"}) {
+		t.Errorf("revoked = %q, want the new owner able to revoke", tokens.revoked)
+	}
+}
+
+func TestTransferKeepsConcurrentRetrieval(t *testing.T) {
+	b, store, responseURL := newRacedSecret(t)
+
+	b.handleTransferCommand(context.Background(), slack.SlashCommand{Command: "/transfer", Text: "secret-1 <@U2>", UserID: "U1", ResponseURL: responseURL})
+	updated, err := readSecretMetadata(context.Background(), store, b.cfg.kvPaths(""), "secret-1")
+	if err != nil || updated.UsesRemaining != 1 || updated.SharedBy != "U2" {
+		t.Errorf("metadata = %+v, %v, want the new owner with the use spent meanwhile kept", updated, err)
+	}
+}
+
+func TestTransferRechecksOwner(t *testing.T) {
+	b, store, responseURL := newRacedSecret(t)
+	paths := b.cfg.kvPaths("")
+	// U1 gives the secret to U3 while U1's own transfer to U2 is under way.
+	b.secrets.(*staleReadStore).during = func() {
+		meta, err := readSecretMetadata(context.Background(), store, paths, "secret-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta.SharedBy = "U3"
+		if err := writeSecretMetadata(context.Background(), store, paths, "secret-1", meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.handleTransferCommand(context.Background(), slack.SlashCommand{Command: "/transfer", Text: "secret-1 <@U2>", UserID: "U1", ResponseURL: responseURL})
+	if meta, err := readSecretMetadata(context.Background(), store, paths, "secret-1"); err != nil || meta.SharedBy != "U3" {
+		t.Errorf("metadata = %+v, %v, want the secret left with U3", meta, err)
+	}
+}
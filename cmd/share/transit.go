@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+)
+
+const (
+	// transitAlgorithm is recorded alongside secrets encrypted by the
+	// transit engine.
+	transitAlgorithm = "vault-transit"
+
+	defaultTransitMount = "transit"
+)
+
+// transitCipher encrypts secrets with a key held by Vault's transit engine,
+// so that only ciphertext is stored in KV and the key never leaves Vault.
+type transitCipher struct {
+	store SecretStore
+	mount string
+	key   string
+}
+
+func (t transitCipher) algorithm() string { return transitAlgorithm }
+
+func (t transitCipher) encrypt(ctx context.Context, plaintext string) (string, error) {
+	resp, err := t.store.WriteWithContext(ctx, path.Join(t.mount, "encrypt", t.key), map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext)),
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("transit encrypt returned no data")
+	}
+	ciphertext, ok := resp.Data["ciphertext"].(string)
+	if !ok {
+		return "", errors.New("transit encrypt returned no ciphertext")
+	}
+	return ciphertext, nil
+}
+
+func (t transitCipher) decrypt(ctx context.Context, ciphertext string) (string, error) {
+	resp, err := t.store.WriteWithContext(ctx, path.Join(t.mount, "decrypt", t.key), map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp == nil {
+		return "", errors.New("transit decrypt returned no data")
+	}
+	encoded, ok := resp.Data["plaintext"].(string)
+	if !ok {
+		return "", errors.New("transit decrypt returned no plaintext")
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decoding transit plaintext: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// fakeTransit is a SecretStore that answers transit encrypt and decrypt
+// requests for the key "hush", reversing the plaintext in place of real
+// encryption, and passes everything else to the wrapped store.
+type fakeTransit struct {
+	SecretStore
+}
+
+func (f fakeTransit) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error) {
+	switch path {
+	case "transit/encrypt/hush":
+		plaintext, _ := data["plaintext"].(string)
+		return &api.Secret{Data: map[string]interface{}{"ciphertext": "vault:v1:" + reverse(plaintext)}}, nil
+	case "transit/decrypt/hush":
+		ciphertext, _ := data["ciphertext"].(string)
+		encoded, ok := strings.CutPrefix(ciphertext, "vault:v1:")
+		if !ok {
+			return nil, &api.ResponseError{StatusCode: 400, Errors: []string{"invalid ciphertext"}}
+		}
+		return &api.Secret{Data: map[string]interface{}{"plaintext": reverse(encoded)}}, nil
+	}
+	return f.SecretStore.WriteWithContext(ctx, path, data)
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func TestTransitRoundTrip(t *testing.T) {
+	fake := newFakeSecretStore()
+	store := fakeTransit{fake}
+	paths := kvPaths{mount: "secrets", version: 2}
+	c := newSecretCipher(&Config{TransitKey: "hush", TransitMount: defaultTransitMount}, store)
+
+	if err := storeSecret(context.Background(), store, paths, "secret-1", secretPayload{Text: "hunter2"}, c); err != nil {
+		t.Fatalf("storeSecret() error = %v", err)
+	}
+	data, _ := fake.data[paths.data("secret-1")]["data"].(map[string]interface{})
+	if want := "vault:v1:" + reverse(base64.StdEncoding.EncodeToString([]byte("hunter2"))); data["secret"] != want || data["encryption"] != transitAlgorithm {
+		t.Errorf("stored data = %v, want transit ciphertext", data)
+	}
+
+	got, err := readSecret(context.Background(), store, paths, "secret-1", c)
+	if err != nil || got.Text != "hunter2" {
+		t.Errorf("readSecret() = %+v, %v, want hunter2", got, err)
+	}
+
+	if _, err := readSecret(context.Background(), store, paths, "secret-1", nil); err == nil {
+		t.Error("readSecret() without transit succeeded")
+	}
+	if _, err := readSecret(context.Background(), store, paths, "secret-1", aesKey(make([]byte, 32))); err == nil || !strings.Contains(err.Error(), transitAlgorithm) {
+		t.Errorf("readSecret() with ENCRYPTION_KEY error = %v, want it to name %s", err, transitAlgorithm)
+	}
+}
+
+func TestLoadConfigTransit(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("VAULT_TRANSIT_KEY", "hush")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.TransitMount != defaultTransitMount || !cfg.encrypted() {
+		t.Errorf("TransitMount = %q, encrypted() = %t", cfg.TransitMount, cfg.encrypted())
+	}
+
+	t.Setenv("ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "VAULT_TRANSIT_KEY") {
+		t.Errorf("LoadConfig() with both kinds of encryption error = %v, want it to name VAULT_TRANSIT_KEY", err)
+	}
+}
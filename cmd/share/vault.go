@@ -0,0 +1,741 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	defaultSecretsMount = "secrets"
+	defaultKVVersion    = 2
+
+	// sharedPrefix is the path under the KV mount where shared secrets live.
+	sharedPrefix = "shared"
+)
+
+// Values of SECRET_PATH_LAYOUT. With layoutTeam, each workspace's secrets are
+// kept under its team ID, as they always are with several workspaces, and
+// with layoutChannel also under the ID of the channel they were shared in.
+const (
+	layoutFlat    = "flat"
+	layoutTeam    = "team"
+	layoutChannel = "channel"
+)
+
+var secretLayouts = []string{layoutFlat, layoutTeam, layoutChannel}
+
+// channelSeparator joins the channel a secret was shared in to the front of
+// its ID under layoutChannel, so that the ID alone locates the secret.
+const channelSeparator = "_"
+
+// channelIDPattern matches the Slack channel IDs secrets are filed under.
+var channelIDPattern = regexp.MustCompile(`^[CDG][A-Z0-9]+$`)
+
+// kvPaths builds the Vault API paths of shared secrets for a KV mount.
+type kvPaths struct {
+	mount   string
+	version int
+	// team, when set, is the Slack workspace whose secrets the paths lead
+	// to. Each workspace's secrets, indexes and policies are kept apart.
+	team string
+	// channels keeps each secret in a directory named after the channel
+	// its ID starts with, as channelSecretID builds them.
+	channels bool
+}
+
+// channelSecretID returns the ID under layoutChannel of secretID, shared in
+// channelID.
+func channelSecretID(channelID, secretID string) string {
+	return channelID + channelSeparator + secretID
+}
+
+// secretChannel returns the channel secretID was shared in under
+// layoutChannel, or "" if its ID does not name one.
+func secretChannel(secretID string) string {
+	channel, _, ok := strings.Cut(secretID, channelSeparator)
+	if !ok {
+		return ""
+	}
+	return channel
+}
+
+// location returns secretID's path below the team's directory.
+func (p kvPaths) location(secretID string) string {
+	if channel := secretChannel(secretID); p.channels && channel != "" {
+		return path.Join(channel, strings.TrimPrefix(secretID, channel+channelSeparator))
+	}
+	return secretID
+}
+
+// data returns the path secretID's value is read from and written to.
+func (p kvPaths) data(secretID string) string {
+	if p.version == 1 {
+		return path.Join(p.mount, sharedPrefix, p.team, p.location(secretID))
+	}
+	return path.Join(p.mount, "data", sharedPrefix, p.team, p.location(secretID))
+}
+
+// metadata returns the path of the bookkeeping kept for secretID. KV v1 has
+// no metadata endpoint, so there it is kept in a sibling secret.
+func (p kvPaths) metadata(secretID string) string {
+	if p.version == 1 {
+		return path.Join(p.mount, sharedPrefix+"-metadata", p.team, p.location(secretID))
+	}
+	return path.Join(p.mount, "metadata", sharedPrefix, p.team, p.location(secretID))
+}
+
+// list returns the directory that lists the IDs of shared secrets, or with
+// channels, the channels they were shared in.
+func (p kvPaths) list() string {
+	if p.version == 1 {
+		return path.Join(p.mount, sharedPrefix, p.team)
+	}
+	return path.Join(p.mount, "metadata", sharedPrefix, p.team)
+}
+
+// dataBody returns the request body that writes fields to a data path. KV v2
+// nests the fields under "data"; KV v1 takes them as they are.
+func (p kvPaths) dataBody(fields map[string]string) map[string]interface{} {
+	if p.version == 1 {
+		return stringMap(fields)
+	}
+	return map[string]interface{}{"data": fields}
+}
+
+// dataFields returns the fields of a data path read.
+func (p kvPaths) dataFields(resp *api.Secret) map[string]interface{} {
+	if p.version == 1 {
+		return resp.Data
+	}
+	fields, _ := resp.Data["data"].(map[string]interface{})
+	return fields
+}
+
+// metadataBody returns the request body that replaces the bot's metadata for
+// a secret. KV v2 keeps it in the secret's custom metadata; on KV v1 it is a
+// plain secret of its own.
+func (p kvPaths) metadataBody(fields map[string]string) map[string]interface{} {
+	if p.version == 1 {
+		return stringMap(fields)
+	}
+	return map[string]interface{}{"custom_metadata": fields}
+}
+
+// metadataFields returns the bot's metadata from a metadata path read.
+func (p kvPaths) metadataFields(resp *api.Secret) map[string]interface{} {
+	if p.version == 1 {
+		return resp.Data
+	}
+	fields, _ := resp.Data["custom_metadata"].(map[string]interface{})
+	return fields
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// index returns the path of the list of secrets shared by userID.
+func (p kvPaths) index(userID string) string {
+	if p.version == 1 {
+		return path.Join(p.mount, "index", p.team, userID)
+	}
+	return path.Join(p.mount, "data", "index", p.team, userID)
+}
+
+// policy returns the path of the Vault policy that lets recipients of
+// secretID read it.
+func (p kvPaths) policy(secretID string) string {
+	return "sys/policies/acl/" + p.policyName(secretID)
+}
+
+// deletes returns the paths to delete to remove every trace of secretID.
+// Deleting the KV v2 metadata removes all versions of the value with it.
+func (p kvPaths) deletes(secretID string) []string {
+	if p.version == 1 {
+		return []string{p.data(secretID), p.metadata(secretID), p.policy(secretID)}
+	}
+	return []string{p.metadata(secretID), p.policy(secretID)}
+}
+
+// policyName names the policy written for secretID.
+func (p kvPaths) policyName(secretID string) string {
+	if p.team != "" {
+		return "hush-" + p.team + "-" + secretID
+	}
+	return "hush-" + secretID
+}
+
+const defaultVaultTimeout = 10 * time.Second
+
+// vaultTimeoutMessage is the reply to a command whose Vault requests did not
+// finish within cfg.VaultTimeout.
+const vaultTimeoutMessage = "Vault did not respond in time. Please try again in a moment."
+
+// vaultSealedMessage is the reply to a command that failed because Vault is
+// sealed. Retrying does not help until an operator unseals it.
+const vaultSealedMessage = "The secret store is temporarily unavailable because Vault is sealed. Please contact an admin."
+
+// vaultPermissionMessage is the reply to a command that failed because the
+// bot's own token is not allowed to do what it asked. That is a
+// misconfiguration, which retrying will not fix.
+const vaultPermissionMessage = "The bot does not have permission to do this in Vault, so this is a configuration problem rather than a temporary failure. Please ask an admin to check the bot's Vault policy."
+
+// vaultContext returns a context that bounds the Vault requests made for one
+// operation, such as handling a command, by cfg.VaultTimeout.
+func vaultContext(parent context.Context, cfg *Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, cfg.VaultTimeout)
+}
+
+// vaultFailure returns the reply to a command that failed with err: the
+// sealed, permission or timeout message if one explains the failure,
+// otherwise msg. Permission failures are also logged on their own, since
+// they need an operator to fix the bot's policy.
+func vaultFailure(err error, msg string) string {
+	switch {
+	case vaultSealed(err):
+		return vaultSealedMessage
+	case vaultPermissionDenied(err):
+		slog.Error("Vault denied the bot's token permission; check its policy", "event", "vault_permission_denied", "error", err)
+		return vaultPermissionMessage
+	case errors.Is(err, context.DeadlineExceeded):
+		return vaultTimeoutMessage
+	case errors.Is(err, errVaultUnavailable):
+		return vaultUnavailableMessage
+	}
+	return msg
+}
+
+// vaultSealed reports whether err is Vault refusing a request because it is
+// sealed, which it answers with a 503 and "Vault is sealed".
+func vaultSealed(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusServiceUnavailable {
+		return false
+	}
+	return slices.ContainsFunc(respErr.Errors, func(e string) bool { return strings.Contains(e, "sealed") })
+}
+
+// vaultPermissionDenied reports whether err is Vault refusing a request with
+// a 403 because the token lacks a capability.
+func vaultPermissionDenied(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden
+}
+
+// errSecretNotFound is returned when a secret does not exist, has been
+// consumed, or has expired.
+var errSecretNotFound = errors.New("secret not found")
+
+// errAccessDenied is returned when a secret shared with --to is opened by
+// someone it was not shared with.
+var errAccessDenied = errors.New("secret was shared with someone else")
+
+// SecretStore reads, writes and lists Vault paths. *api.Logical implements
+// it.
+type SecretStore interface {
+	ReadWithContext(ctx context.Context, path string) (*api.Secret, error)
+	WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*api.Secret, error)
+	DeleteWithContext(ctx context.Context, path string) (*api.Secret, error)
+	ListWithContext(ctx context.Context, path string) (*api.Secret, error)
+}
+
+// TokenCreator issues and revokes Vault tokens. *api.TokenAuth implements
+// it.
+type TokenCreator interface {
+	CreateWithContext(ctx context.Context, opts *api.TokenCreateRequest) (*api.Secret, error)
+	RevokeAccessorWithContext(ctx context.Context, accessor string) error
+}
+
+// newVaultClient creates a Vault client for cfg, with the static VAULT_TOKEN
+// set unless AppRole is configured, in which case loginVault must log it in.
+// It does not contact Vault.
+func newVaultClient(cfg *Config) (*api.Client, error) {
+	config := api.DefaultConfig()
+	config.Address = cfg.VaultAddr
+	// Requests are retried by retryingStore and retryingTokens instead.
+	config.MaxRetries = 0
+	err := config.ConfigureTLS(&api.TLSConfig{
+		CACert:     cfg.VaultCACert,
+		ClientCert: cfg.VaultClientCert,
+		ClientKey:  cfg.VaultClientKey,
+		Insecure:   cfg.VaultSkipVerify,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("configuring TLS: %w", err)
+	}
+
+	// The namespace is a header, which clones made for recipients' tokens
+	// must keep.
+	config.CloneHeaders = true
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.VaultNamespace != "" {
+		client.SetNamespace(cfg.VaultNamespace)
+	}
+	if !cfg.useAppRole() {
+		client.SetToken(cfg.VaultToken)
+	}
+	return client, nil
+}
+
+// loginVault logs client in with the AppRole role and secret ID in cfg, if
+// they are configured, retrying as for any Vault request. The returned
+// secret carries the login's token lease and is nil when a static token is
+// used.
+func loginVault(ctx context.Context, client *api.Client, cfg *Config) (*api.Secret, error) {
+	if !cfg.useAppRole() {
+		return nil, nil
+	}
+	var login *api.Secret
+	err := newRetryPolicy(cfg.VaultMaxAttempts).do(ctx, "approle_login", func() (err error) {
+		login, err = appRoleLogin(ctx, client, cfg.VaultRoleID, cfg.VaultSecretID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AppRole login: %w", err)
+	}
+	return login, nil
+}
+
+// appRoleLogin logs in to the AppRole auth method and sets the resulting
+// token on client.
+func appRoleLogin(ctx context.Context, client *api.Client, roleID, secretID string) (*api.Secret, error) {
+	login, err := client.Logical().WriteWithContext(ctx, "auth/approle/login", map[string]interface{}{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if login == nil || login.Auth == nil || login.Auth.ClientToken == "" {
+		return nil, errors.New("no token returned")
+	}
+	client.SetToken(login.Auth.ClientToken)
+	return login, nil
+}
+
+// secretPayload is the content of a shared secret.
+type secretPayload struct {
+	// Text is the secret value for text secrets.
+	Text string
+	// File is set instead of Text when a file was shared.
+	File *secretFile
+	// Fields are set instead of Text when the secret was given as
+	// name=value pairs.
+	Fields []secretField
+}
+
+// secretFile is a file shared in place of a text secret.
+type secretFile struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// storeSecret writes payload as secretID. Files are stored base64-encoded along
+// with their name and content type, and fields each under their own name
+// with the list of names alongside. When c is non-nil the values are
+// encrypted with it first and its algorithm recorded alongside them.
+func storeSecret(ctx context.Context, store SecretStore, paths kvPaths, secretID string, payload secretPayload, c secretCipher) error {
+	values := map[string]string{"secret": payload.Text}
+	fields := map[string]string{}
+	switch {
+	case payload.File != nil:
+		f := payload.File
+		values["secret"] = base64.StdEncoding.EncodeToString(f.Content)
+		fields["filename"] = f.Name
+		fields["content_type"] = f.ContentType
+	case len(payload.Fields) > 0:
+		values = map[string]string{}
+		names := make([]string, len(payload.Fields))
+		for i, f := range payload.Fields {
+			values[f.Name] = f.Value
+			names[i] = f.Name
+		}
+		fields[fieldsField] = strings.Join(names, ",")
+	}
+	for name, value := range values {
+		if c != nil {
+			ciphertext, err := c.encrypt(ctx, value)
+			if err != nil {
+				return fmt.Errorf("encrypting secret: %w", err)
+			}
+			value = ciphertext
+			fields["encryption"] = c.algorithm()
+		}
+		fields[name] = value
+	}
+
+	_, err := store.WriteWithContext(ctx, paths.data(secretID), paths.dataBody(fields))
+	return err
+}
+
+// readSecret reads secretID, decrypting it with c if it was stored
+// encrypted.
+func readSecret(ctx context.Context, store SecretStore, paths kvPaths, secretID string, c secretCipher) (secretPayload, error) {
+	resp, err := store.ReadWithContext(ctx, paths.data(secretID))
+	if err != nil {
+		return secretPayload{}, err
+	}
+	if resp == nil {
+		return secretPayload{}, errSecretNotFound
+	}
+
+	fields := paths.dataFields(resp)
+	value := func(name string) (string, error) {
+		v, ok := fields[name].(string)
+		if !ok {
+			return "", errSecretNotFound
+		}
+		switch enc := fields["encryption"]; {
+		case enc == nil:
+			return v, nil
+		case c == nil:
+			return "", fmt.Errorf("secret is encrypted with %v but no encryption is configured", enc)
+		case enc != c.algorithm():
+			return "", fmt.Errorf("secret is encrypted with %v but %s is configured", enc, c.algorithm())
+		default:
+			return c.decrypt(ctx, v)
+		}
+	}
+
+	if names, _ := fields[fieldsField].(string); names != "" {
+		var payload secretPayload
+		for _, name := range strings.Split(names, ",") {
+			v, err := value(name)
+			if err != nil {
+				return secretPayload{}, err
+			}
+			payload.Fields = append(payload.Fields, secretField{Name: name, Value: v})
+		}
+		return payload, nil
+	}
+
+	text, err := value("secret")
+	if err != nil {
+		return secretPayload{}, err
+	}
+	name, _ := fields["filename"].(string)
+	if name == "" {
+		return secretPayload{Text: text}, nil
+	}
+	content, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return secretPayload{}, fmt.Errorf("decoding file: %w", err)
+	}
+	contentType, _ := fields["content_type"].(string)
+	return secretPayload{File: &secretFile{Name: name, ContentType: contentType, Content: content}}, nil
+}
+
+// secretMetadata is the bookkeeping the bot keeps for each secret in the KV
+// custom metadata. It is readable without consuming the secret itself.
+type secretMetadata struct {
+	// SharedBy is the Slack user ID of the person who shared the secret.
+	SharedBy string
+	// SharedByName is their Slack user name at the time of sharing.
+	SharedByName string
+	// TokenAccessor identifies the short-lived token issued for the secret
+	// so that it can be revoked without knowing the token itself.
+	TokenAccessor string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	// UsesRemaining is the number of retrievals left, through the retrieval
+	// page or with the token, where zero means unlimited.
+	UsesRemaining int
+	// Notify is set until the sharer has been told of the first retrieval.
+	Notify bool
+	// AllowedUsers, when non-empty, are the only Slack users who may
+	// retrieve the secret.
+	AllowedUsers []string
+	// Burn deletes the secret after its first successful read, whichever
+	// way it is retrieved.
+	Burn bool
+	// Label is the sharer's description of the secret, given with --label.
+	Label string
+	// PassphraseHash, when set, is the bcrypt hash of the --passphrase the
+	// retrieval page asks for before revealing the secret, and
+	// PassphraseFailures how many wrong ones it has been given.
+	PassphraseHash     string
+	PassphraseFailures int
+}
+
+func (m secretMetadata) expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && now.After(m.ExpiresAt)
+}
+
+// maxMetadataValue is the most bytes Vault allows in a KV v2 custom
+// metadata value.
+const maxMetadataValue = 512
+
+func (m secretMetadata) toMap() map[string]string {
+	fields := map[string]string{
+		"shared_by":      m.SharedBy,
+		"shared_by_name": m.SharedByName,
+		"token_accessor": m.TokenAccessor,
+		"created_at":     m.CreatedAt.UTC().Format(time.RFC3339),
+		"expires_at":     m.ExpiresAt.UTC().Format(time.RFC3339),
+		"uses_remaining": strconv.Itoa(m.UsesRemaining),
+		"notify":         strconv.FormatBool(m.Notify),
+		"burn":           strconv.FormatBool(m.Burn),
+		"label":          m.Label,
+
+		"passphrase_hash":     m.PassphraseHash,
+		"passphrase_failures": strconv.Itoa(m.PassphraseFailures),
+	}
+	// The recipients of a large --group do not fit in one value, so they
+	// are spread over allowed_users, allowed_users_2, allowed_users_3 and
+	// so on.
+	n, chunk := 1, ""
+	for _, u := range m.AllowedUsers {
+		if chunk != "" && len(chunk)+1+len(u) > maxMetadataValue {
+			fields[allowedUsersKey(n)] = chunk
+			n, chunk = n+1, ""
+		}
+		if chunk != "" {
+			chunk += ","
+		}
+		chunk += u
+	}
+	fields[allowedUsersKey(n)] = chunk
+	return fields
+}
+
+// allowedUsersKey returns the custom metadata key of the nth group of
+// AllowedUsers, counting from 1.
+func allowedUsersKey(n int) string {
+	if n == 1 {
+		return "allowed_users"
+	}
+	return "allowed_users_" + strconv.Itoa(n)
+}
+
+func parseSecretMetadata(raw map[string]interface{}) (secretMetadata, error) {
+	var m secretMetadata
+	m.SharedBy, _ = raw["shared_by"].(string)
+	m.SharedByName, _ = raw["shared_by_name"].(string)
+	m.TokenAccessor, _ = raw["token_accessor"].(string)
+	for key, dst := range map[string]*time.Time{"created_at": &m.CreatedAt, "expires_at": &m.ExpiresAt} {
+		if v, ok := raw[key].(string); ok {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return m, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*dst = t
+		}
+	}
+	if v, ok := raw["uses_remaining"].(string); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return m, fmt.Errorf("invalid uses_remaining: %w", err)
+		}
+		m.UsesRemaining = n
+	}
+	m.Notify = raw["notify"] == "true"
+	m.Burn = raw["burn"] == "true"
+	m.Label, _ = raw["label"].(string)
+	m.PassphraseHash, _ = raw["passphrase_hash"].(string)
+	if v, ok := raw["passphrase_failures"].(string); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return m, fmt.Errorf("invalid passphrase_failures: %w", err)
+		}
+		m.PassphraseFailures = n
+	}
+	for n := 1; ; n++ {
+		v, _ := raw[allowedUsersKey(n)].(string)
+		if v == "" {
+			break
+		}
+		m.AllowedUsers = append(m.AllowedUsers, strings.Split(v, ",")...)
+	}
+	return m, nil
+}
+
+// writeSecretMetadata replaces the bot's metadata for secretID.
+func writeSecretMetadata(ctx context.Context, store SecretStore, paths kvPaths, secretID string, meta secretMetadata) error {
+	_, err := store.WriteWithContext(ctx, paths.metadata(secretID), paths.metadataBody(meta.toMap()))
+	return err
+}
+
+// updateSecretMetadata applies update to the current metadata of secretID
+// and writes it back, holding the secret's lock, so that an update made from
+// Slack cannot undo a use spent in the meantime, or bring back a secret
+// destroyed since. It returns errSecretNotFound if the secret no longer
+// exists, and leaves it unchanged if update returns an error, which it
+// returns.
+func updateSecretMetadata(ctx context.Context, state StateStore, store SecretStore, paths kvPaths, secretID string, update func(*secretMetadata) error) error {
+	unlock, err := lockSecret(ctx, state, paths, secretID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	meta, err := readSecretMetadata(ctx, store, paths, secretID)
+	if err != nil {
+		return err
+	}
+	if err := update(&meta); err != nil {
+		return err
+	}
+	return writeSecretMetadata(ctx, store, paths, secretID, meta)
+}
+
+// readSecretMetadata returns the bot's metadata for secretID, or
+// errSecretNotFound if the secret no longer exists.
+func readSecretMetadata(ctx context.Context, store SecretStore, paths kvPaths, secretID string) (secretMetadata, error) {
+	resp, err := store.ReadWithContext(ctx, paths.metadata(secretID))
+	if err != nil {
+		return secretMetadata{}, err
+	}
+	if resp == nil {
+		return secretMetadata{}, errSecretNotFound
+	}
+	return parseSecretMetadata(paths.metadataFields(resp))
+}
+
+// deleteSecret permanently removes every version of secretID along with its
+// metadata.
+func deleteSecret(ctx context.Context, store SecretStore, paths kvPaths, secretID string) error {
+	for _, p := range paths.deletes(secretID) {
+		if _, err := store.DeleteWithContext(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSecretPolicy writes the policy attached to the tokens issued for
+// secretID. It grants read on that one secret only, so a leaked token cannot
+// read any other.
+func writeSecretPolicy(ctx context.Context, store SecretStore, paths kvPaths, secretID string) error {
+	policy := fmt.Sprintf("path %q {\n  capabilities = [\"read\"]\n}\n", paths.data(secretID))
+	_, err := store.WriteWithContext(ctx, paths.policy(secretID), map[string]interface{}{"policy": policy})
+	return err
+}
+
+// listSecretIDs returns the IDs of every shared secret in Vault. With
+// channels, the secrets in each channel's directory are listed as well as
+// any whose channel was not known when they were shared.
+func listSecretIDs(ctx context.Context, store SecretStore, paths kvPaths) ([]string, error) {
+	ids, dirs, err := listKeys(ctx, store, paths.list())
+	if err != nil || !paths.channels {
+		return ids, err
+	}
+	for _, channel := range dirs {
+		inChannel, _, err := listKeys(ctx, store, paths.list()+"/"+channel)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range inChannel {
+			ids = append(ids, channelSecretID(channel, id))
+		}
+	}
+	return ids, nil
+}
+
+// listKeys lists dir, returning the names of the secrets and of the
+// subdirectories in it. Only names that could be secret IDs are returned.
+func listKeys(ctx context.Context, store SecretStore, dir string) (ids, dirs []string, err error) {
+	resp, err := store.ListWithContext(ctx, dir)
+	if err != nil || resp == nil {
+		return nil, nil, err
+	}
+	keys, _ := resp.Data["keys"].([]interface{})
+	ids = make([]string, 0, len(keys))
+	for _, k := range keys {
+		name, _ := k.(string)
+		if sub, ok := strings.CutSuffix(name, "/"); ok && validSecretID(sub) {
+			dirs = append(dirs, sub)
+		} else if validSecretID(name) {
+			ids = append(ids, name)
+		}
+	}
+	return ids, dirs, nil
+}
+
+// newSecretID returns a random 128-bit secret ID, so that knowing when a
+// secret was shared does not help to guess its path.
+func newSecretID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "secret-" + strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
+
+// validSecretID reports whether id has the shape of a secret ID, so that
+// user-supplied IDs cannot address other Vault paths.
+func validSecretID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// createVaultToken issues a short-lived token for secretID with policies
+// attached and returns the token along with its accessor. The sharer is
+// recorded in the token metadata so that it shows up when auditing tokens
+// in Vault, along with cfg's TokenMetadata, and the token is named by cfg's
+// TokenDisplayName.
+func createVaultToken(ctx context.Context, tokens TokenCreator, cfg *Config, policies []string, secretID, sharedBy, sharedByName string, ttl time.Duration, uses int) (string, string, error) {
+	var notRenewable bool
+	metadata := map[string]string{}
+	if cfg != nil {
+		maps.Copy(metadata, cfg.TokenMetadata)
+	}
+	metadata["secret_id"] = secretID
+	metadata["shared_by"] = sharedBy
+	metadata["shared_by_name"] = sharedByName
+	tokenRequest := &api.TokenCreateRequest{
+		DisplayName: tokenDisplayName(cfg, tokenNameData{SecretID: secretID, SharedBy: sharedBy, SharedByName: sharedByName}),
+		Policies:    policies,
+		Metadata:    metadata,
+		TTL:         ttl.String(),
+		NumUses:     uses,
+		Renewable:   &notRenewable,
+		NoParent:    true,
+	}
+
+	token, err := tokens.CreateWithContext(ctx, tokenRequest)
+	if err != nil {
+		return "", "", err
+	}
+	return token.Auth.ClientToken, token.Auth.Accessor, nil
+}
+
+// revokeTokenAccessor revokes the token identified by accessor. Tokens that
+// have already expired are treated as revoked.
+func revokeTokenAccessor(ctx context.Context, tokens TokenCreator, accessor string) error {
+	err := tokens.RevokeAccessorWithContext(ctx, accessor)
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusBadRequest {
+		return nil
+	}
+	return err
+}
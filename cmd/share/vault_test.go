@@ -0,0 +1,245 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKVPaths(t *testing.T) {
+	tests := []struct {
+		paths kvPaths
+		// id defaults to secret-1.
+		id       string
+		data     string
+		metadata string
+		list     string
+		deletes  []string
+	}{
+		{
+			paths:    kvPaths{mount: "secrets", version: 2},
+			data:     "secrets/data/shared/secret-1",
+			metadata: "secrets/metadata/shared/secret-1",
+			list:     "secrets/metadata/shared",
+			deletes:  []string{"secrets/metadata/shared/secret-1", "sys/policies/acl/hush-secret-1"},
+		},
+		{
+			paths:    kvPaths{mount: "team/kv", version: 1},
+			data:     "team/kv/shared/secret-1",
+			metadata: "team/kv/shared-metadata/secret-1",
+			list:     "team/kv/shared",
+			deletes:  []string{"team/kv/shared/secret-1", "team/kv/shared-metadata/secret-1", "sys/policies/acl/hush-secret-1"},
+		},
+		{
+			paths:    kvPaths{mount: "secrets", version: 2, team: "T1"},
+			data:     "secrets/data/shared/T1/secret-1",
+			metadata: "secrets/metadata/shared/T1/secret-1",
+			list:     "secrets/metadata/shared/T1",
+			deletes:  []string{"secrets/metadata/shared/T1/secret-1", "sys/policies/acl/hush-T1-secret-1"},
+		},
+		{
+			paths:    kvPaths{mount: "secrets", version: 2, team: "T1", channels: true},
+			id:       "C1_secret-1",
+			data:     "secrets/data/shared/T1/C1/secret-1",
+			metadata: "secrets/metadata/shared/T1/C1/secret-1",
+			list:     "secrets/metadata/shared/T1",
+			deletes:  []string{"secrets/metadata/shared/T1/C1/secret-1", "sys/policies/acl/hush-T1-C1_secret-1"},
+		},
+		{
+			paths:    kvPaths{mount: "team/kv", version: 1, team: "T1", channels: true},
+			id:       "C1_secret-1",
+			data:     "team/kv/shared/T1/C1/secret-1",
+			metadata: "team/kv/shared-metadata/T1/C1/secret-1",
+			list:     "team/kv/shared/T1",
+			deletes:  []string{"team/kv/shared/T1/C1/secret-1", "team/kv/shared-metadata/T1/C1/secret-1", "sys/policies/acl/hush-T1-C1_secret-1"},
+		},
+		{
+			// A secret shared where the channel was not known stays in
+			// the team's directory.
+			paths:    kvPaths{mount: "secrets", version: 2, team: "T1", channels: true},
+			data:     "secrets/data/shared/T1/secret-1",
+			metadata: "secrets/metadata/shared/T1/secret-1",
+			list:     "secrets/metadata/shared/T1",
+			deletes:  []string{"secrets/metadata/shared/T1/secret-1", "sys/policies/acl/hush-T1-secret-1"},
+		},
+		{
+			// Without channels, an underscore is just part of the ID.
+			paths:    kvPaths{mount: "secrets", version: 2, team: "T1"},
+			id:       "C1_secret-1",
+			data:     "secrets/data/shared/T1/C1_secret-1",
+			metadata: "secrets/metadata/shared/T1/C1_secret-1",
+			list:     "secrets/metadata/shared/T1",
+			deletes:  []string{"secrets/metadata/shared/T1/C1_secret-1", "sys/policies/acl/hush-T1-C1_secret-1"},
+		},
+	}
+	for _, tt := range tests {
+		id := cmp.Or(tt.id, "secret-1")
+		if got := tt.paths.data(id); got != tt.data {
+			t.Errorf("%+v data() = %q, want %q", tt.paths, got, tt.data)
+		}
+		if got := tt.paths.metadata(id); got != tt.metadata {
+			t.Errorf("%+v metadata() = %q, want %q", tt.paths, got, tt.metadata)
+		}
+		if got := tt.paths.list(); got != tt.list {
+			t.Errorf("%+v list() = %q, want %q", tt.paths, got, tt.list)
+		}
+		if got := tt.paths.deletes(id); !slices.Equal(got, tt.deletes) {
+			t.Errorf("%+v deletes() = %q, want %q", tt.paths, got, tt.deletes)
+		}
+	}
+}
+
+// fakeKV is a Vault server that stores each write body verbatim and returns
+// it as the data of later reads of the same path, which is how both KV
+// versions respond.
+type fakeKV struct {
+	mu     sync.Mutex
+	writes map[string]map[string]interface{}
+}
+
+func newFakeKV(t *testing.T) (*fakeKV, *Config) {
+	t.Helper()
+	kv := &fakeKV{writes: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(kv)
+	t.Cleanup(srv.Close)
+	return kv, &Config{VaultAddr: srv.URL, VaultToken: "hvs.test", VaultSecretsMount: "secrets"}
+}
+
+func (kv *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		kv.writes[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		body, ok := kv.writes[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": body})
+	}
+}
+
+func TestSecretPayloadShape(t *testing.T) {
+	tests := []struct {
+		version  int
+		dataPath string
+		data     map[string]interface{}
+		metaPath string
+		meta     func(map[string]interface{}) map[string]interface{}
+	}{
+		{
+			version:  2,
+			dataPath: "secrets/data/shared/secret-1",
+			data:     map[string]interface{}{"data": map[string]interface{}{"secret": "hunter2"}},
+			metaPath: "secrets/metadata/shared/secret-1",
+			meta: func(body map[string]interface{}) map[string]interface{} {
+				m, _ := body["custom_metadata"].(map[string]interface{})
+				return m
+			},
+		},
+		{
+			version:  1,
+			dataPath: "secrets/shared/secret-1",
+			data:     map[string]interface{}{"secret": "hunter2"},
+			metaPath: "secrets/shared-metadata/secret-1",
+			meta:     func(body map[string]interface{}) map[string]interface{} { return body },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("v%d", tt.version), func(t *testing.T) {
+			kv, cfg := newFakeKV(t)
+			cfg.VaultKVVersion = tt.version
+			client, err := newVaultClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store, paths := client.Logical(), cfg.kvPaths("")
+
+			if err := storeSecret(context.Background(), store, paths, "secret-1", secretPayload{Text: "hunter2"}, nil); err != nil {
+				t.Fatalf("storeSecret(context.Background(), ) error = %v", err)
+			}
+			if got := kv.writes[tt.dataPath]; !reflect.DeepEqual(got, tt.data) {
+				t.Errorf("stored %s = %v, want %v", tt.dataPath, got, tt.data)
+			}
+			got, err := readSecret(context.Background(), store, paths, "secret-1", nil)
+			if err != nil || got.Text != "hunter2" {
+				t.Errorf("readSecret(context.Background(), ) = %+v, %v, want hunter2", got, err)
+			}
+
+			meta := secretMetadata{SharedBy: "U1", ExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), UsesRemaining: 2}
+			if err := writeSecretMetadata(context.Background(), store, paths, "secret-1", meta); err != nil {
+				t.Fatalf("writeSecretMetadata(context.Background(), ) error = %v", err)
+			}
+			if stored := tt.meta(kv.writes[tt.metaPath]); stored["shared_by"] != "U1" || stored["uses_remaining"] != "2" {
+				t.Errorf("stored %s = %v", tt.metaPath, kv.writes[tt.metaPath])
+			}
+			gotMeta, err := readSecretMetadata(context.Background(), store, paths, "secret-1")
+			if err != nil || !reflect.DeepEqual(gotMeta, meta) {
+				t.Errorf("readSecretMetadata(context.Background(), ) = %+v, %v, want %+v", gotMeta, err, meta)
+			}
+		})
+	}
+}
+
+func TestNewSecretID(t *testing.T) {
+	a, err := newSecretID()
+	if err != nil {
+		t.Fatalf("newSecretID() error = %v", err)
+	}
+	b, _ := newSecretID()
+	if a == b {
+		t.Errorf("newSecretID() returned %q twice", a)
+	}
+	// 128 bits is 26 base32 characters.
+	if !validSecretID(a) || !strings.HasPrefix(a, "secret-") || len(a) != len("secret-")+26 {
+		t.Errorf("newSecretID() = %q, want secret- and 26 base32 characters", a)
+	}
+}
+
+func TestVaultNamespace(t *testing.T) {
+	var mu sync.Mutex
+	var namespaces []string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		namespaces = append(namespaces, r.Header.Get("X-Vault-Namespace"))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{}}`)
+	}))
+	defer vault.Close()
+
+	client, err := newVaultClient(&Config{VaultAddr: vault.URL, VaultToken: "hvs.test", VaultNamespace: "admin/team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Read("secrets/data/shared/secret-1"); err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient.SetToken("hvs.recipient")
+	if _, err := recipient.Logical().Read("secrets/data/shared/secret-1"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"admin/team-a", "admin/team-a"}; !slices.Equal(namespaces, want) {
+		t.Errorf("namespaces = %q, want %q for the bot and a recipient's token", namespaces, want)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// handleWhoamiCommand replies with diagnostics for checking the bot's setup:
+// who is asking, where Vault is, the share limits, uptime and whether Vault
+// can be reached. It never includes a token, key or secret.
+func (b *bot) handleWhoamiCommand(ctx context.Context, cmd slack.SlashCommand) {
+	vaultStatus := "reachable"
+	if err := checkVault(ctx, b.vault); err != nil {
+		slog.Warn("Vault check failed", "event", "whoami", "user_id", cmd.UserID, "error", err)
+		vaultStatus = "unreachable: " + vaultProblem(err)
+	}
+	sendSlackResponse(b.slack, cmd.ResponseURL, b.diagnostics(cmd, vaultStatus, time.Now()))
+}
+
+// diagnostics renders the /whoami reply.
+func (b *bot) diagnostics(cmd slack.SlashCommand, vaultStatus string, now time.Time) string {
+	var sb strings.Builder
+	sb.WriteString("*Hush diagnostics*\n")
+	fmt.Fprintf(&sb, "• You: <@%s>", cmd.UserID)
+	switch {
+	case cmd.TeamID != "" && cmd.TeamID == cmd.EnterpriseID:
+		fmt.Fprintf(&sb, " in organization `%s`", cmd.TeamID)
+	case cmd.TeamID != "":
+		fmt.Fprintf(&sb, " in workspace `%s`", cmd.TeamID)
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "• Vault: `%s`, %s\n", vaultHost(b.vault.Address()), vaultStatus)
+	fmt.Fprintf(&sb, "• KV mount: `%s` (version %d)\n", b.cfg.VaultSecretsMount, b.cfg.VaultKVVersion)
+	uses := fmt.Sprintf("%d", b.cfg.MaxUses)
+	if b.cfg.AllowUnlimitedUses {
+		uses += ", or unlimited"
+	}
+	fmt.Fprintf(&sb, "• Limits: TTL up to %s, uses up to %s\n", formatDuration(b.cfg.MaxTTL), uses)
+	switch {
+	case b.cfg.TransitKey != "":
+		sb.WriteString("• Encryption: Vault transit\n")
+	case b.cfg.EncryptionKey != nil:
+		sb.WriteString("• Encryption: client-side AES-GCM\n")
+	default:
+		sb.WriteString("• Encryption: none beyond Vault's own\n")
+	}
+	if b.cfg.DryRun {
+		sb.WriteString("• Dry run is on: secrets are not written to Vault\n")
+	}
+	fmt.Fprintf(&sb, "• Uptime: %s\n", formatDuration(now.Sub(b.started).Truncate(time.Second)))
+	return sb.String()
+}
+
+// vaultHost returns the host of addr, leaving out any scheme, path or
+// credentials in the URL.
+func vaultHost(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// vaultProblem summarises why a Vault check failed without echoing the
+// request it made.
+func vaultProblem(err error) string {
+	switch {
+	case errors.Is(err, errVaultSealed) || vaultSealed(err):
+		return "Vault is sealed"
+	case vaultPermissionDenied(err):
+		return "the bot's token was rejected"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	default:
+		return "could not connect"
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestWhoamiCommand(t *testing.T) {
+	for _, sealed := range []bool{false, true} {
+		vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v1/sys/seal-status" {
+				fmt.Fprintf(w, `{"sealed":%t}`, sealed)
+				return
+			}
+			w.Write([]byte(`{"data":{"id":"hvs.bot"}}`))
+		}))
+		defer vault.Close()
+
+		b, responseURL, replies := newTestBot(t, newFakeSecretStore(), &fakeTokenCreator{})
+		client, err := newVaultClient(&Config{VaultAddr: vault.URL, VaultToken: "hvs.bot-token"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.vault = client
+		b.cfg.EncryptionKey = make([]byte, 32)
+		b.started = time.Now().Add(-90 * time.Minute)
+
+		b.handleWhoamiCommand(context.Background(), slack.SlashCommand{Command: "/whoami", UserID: "U1", TeamID: "T1", ResponseURL: responseURL})
+		got := replies()
+		if len(got) != 1 {
+			t.Fatalf("replies = %q, want one", got)
+		}
+		want := []string{"<@U1>", "`T1`", "`" + strings.TrimPrefix(vault.URL, "http://") + "`", "`secrets` (version 2)", "TTL up to 24h", "AES-GCM", "Uptime: 1h30m"}
+		if sealed {
+			want = append(want, "unreachable: Vault is sealed")
+		} else {
+			want = append(want, "reachable")
+		}
+		for _, w := range want {
+			if !strings.Contains(got[0], w) {
+				t.Errorf("reply %q does not include %q", got[0], w)
+			}
+		}
+		if strings.Contains(got[0], "hvs.") || strings.Contains(got[0], "http://") {
+			t.Errorf("reply %q exposes a token or the full Vault URL", got[0])
+		}
+	}
+}
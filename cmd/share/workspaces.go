@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/slack-go/slack"
+)
+
+// teamIDPattern matches Slack workspace IDs, and the enterprise IDs of
+// Enterprise Grid organizations that the app is installed in org-wide, which
+// name directories in Vault.
+var teamIDPattern = regexp.MustCompile(`^[TE][A-Z0-9]+$`)
+
+// loadWorkspaceTokens reads a JSON object mapping Slack team IDs to the bot
+// token of each workspace the app is installed in. An Enterprise Grid
+// organization the app is installed in org-wide is listed under its
+// enterprise ID instead.
+func loadWorkspaceTokens(file string) (map[string]string, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot be read: %w", err)
+	}
+	var tokens map[string]string
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return nil, fmt.Errorf("must be a JSON object of team IDs to bot tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("names no workspaces")
+	}
+	for team, token := range tokens {
+		if !teamIDPattern.MatchString(team) {
+			return nil, fmt.Errorf("%q is not a Slack team or enterprise ID", team)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("has no bot token for %s", team)
+		}
+	}
+	return tokens, nil
+}
+
+// workspaces holds the Slack API client of each workspace the bot serves.
+// With a single workspace every team uses the default client.
+type workspaces struct {
+	fallback *slack.Client
+	teams    map[string]*slack.Client
+}
+
+// newWorkspaces returns the clients for cfg's workspaces, built with opts.
+// fallback serves every team unless cfg lists workspaces of its own.
+func newWorkspaces(cfg *Config, fallback *slack.Client, opts ...slack.Option) *workspaces {
+	w := &workspaces{fallback: fallback}
+	if cfg.multiWorkspace() {
+		w.teams = make(map[string]*slack.Client, len(cfg.SlackWorkspaces))
+		for team, token := range cfg.SlackWorkspaces {
+			w.teams[team] = slack.New(token, opts...)
+		}
+	}
+	return w
+}
+
+// client returns the API client for teamID, reporting false if the bot is
+// not configured for that workspace.
+func (w *workspaces) client(teamID string) (*slack.Client, bool) {
+	if w.teams == nil {
+		return w.fallback, true
+	}
+	c, ok := w.teams[teamID]
+	return c, ok
+}
+
+// installation returns the ID that the bot knows teamID's workspace by, in
+// Enterprise Grid organization enterpriseID if it belongs to one: its own
+// team ID if the app is installed in it, otherwise the organization's
+// enterprise ID if the app is installed org-wide there. Every workspace of
+// an org-wide installation then shares its token and its directory in
+// Vault. Interactions outside any one workspace, such as in an org-wide
+// modal, carry no team ID and are keyed by the organization.
+func (w *workspaces) installation(enterpriseID, teamID string) string {
+	if w.teams == nil || enterpriseID == "" {
+		return teamID
+	}
+	if _, ok := w.teams[teamID]; ok && teamID != "" {
+		return teamID
+	}
+	if _, ok := w.teams[enterpriseID]; ok || teamID == "" {
+		return enterpriseID
+	}
+	return teamID
+}
+
+// all returns every workspace's client by team ID, keyed by "" when the bot
+// serves a single workspace.
+func (w *workspaces) all() map[string]*slack.Client {
+	if w.teams == nil {
+		return map[string]*slack.Client{"": w.fallback}
+	}
+	return w.teams
+}
+
+// teamIDs returns the configured team IDs in order, or a single "" when the
+// bot serves one workspace.
+func (c *Config) teamIDs() []string {
+	if !c.multiWorkspace() {
+		return []string{""}
+	}
+	ids := make([]string, 0, len(c.SlackWorkspaces))
+	for id := range c.SlackWorkspaces {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// storedTeams returns the teams whose secrets the sweep and reconciler go
+// through: teamIDs, unless a single workspace's secrets are kept under its
+// team ID, which the configuration does not name. Then it is found from the
+// directories Vault holds.
+func (b *bot) storedTeams(ctx context.Context) ([]string, error) {
+	if b.cfg.multiWorkspace() || !b.cfg.teamScoped() {
+		return b.cfg.teamIDs(), nil
+	}
+	ctx, cancel := vaultContext(ctx, b.cfg)
+	defer cancel()
+	_, dirs, err := listKeys(ctx, b.secrets, b.cfg.kvPaths("").list())
+	return slices.DeleteFunc(dirs, func(team string) bool { return !teamIDPattern.MatchString(team) }), err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestLoadWorkspaceTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", `{"T1": "xoxb-1", "T2": "xoxb-2"}`, ""},
+		{"not JSON", `T1=xoxb-1`, "JSON object"},
+		{"empty", `{}`, "no workspaces"},
+		{"org-wide", `{"E1": "xoxb-1", "T2": "xoxb-2"}`, ""},
+		{"bad team ID", `{"acme": "xoxb-1"}`, "not a Slack team or enterprise ID"},
+		{"no token", `{"T1": ""}`, "no bot token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "workspaces.json")
+			if err := os.WriteFile(file, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := loadWorkspaceTokens(file)
+			if tt.wantErr == "" {
+				if err != nil || len(tokens) != 2 || tokens["T2"] != "xoxb-2" {
+					t.Errorf("loadWorkspaceTokens() = %v, %v", tokens, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadWorkspaceTokens() error = %v, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigWorkspacesFile(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SLACK_BOT_TOKEN", "")
+	file := filepath.Join(t.TempDir(), "workspaces.json")
+	if err := os.WriteFile(file, []byte(`{"T2": "xoxb-2", "T1": "xoxb-1"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SLACK_WORKSPACES_FILE", file)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.teamIDs(); len(got) != 2 || got[0] != "T1" || got[1] != "T2" {
+		t.Errorf("teamIDs() = %q, want [T1 T2]", got)
+	}
+	if got := cfg.kvPaths("T1").data("secret-1"); got != "secrets/data/shared/T1/secret-1" {
+		t.Errorf("kvPaths(T1).data() = %q", got)
+	}
+
+	t.Setenv("SLACK_WORKSPACES_FILE", filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "SLACK_WORKSPACES_FILE") {
+		t.Errorf("LoadConfig() with a missing file error = %v, want it to name SLACK_WORKSPACES_FILE", err)
+	}
+}
+
+func TestShareMultiWorkspace(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.SlackWorkspaces = map[string]string{"T1": "xoxb-1"}
+	b.workspaces = newWorkspaces(b.cfg, b.workspaces.fallback)
+
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", TeamID: "T2", ResponseURL: responseURL})
+	b.inflight.Wait()
+	if got := replies(); len(got) != 1 || got[0] != unknownWorkspaceMessage {
+		t.Fatalf("replies to an unknown workspace = %q, want %q", got, unknownWorkspaceMessage)
+	}
+
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", TeamID: "T1", ResponseURL: responseURL})
+	b.inflight.Wait()
+	ids, err := listSecretIDs(context.Background(), store, b.cfg.kvPaths("T1"))
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("secrets stored for T1 = %q, %v, want one", ids, err)
+	}
+	if got := tokens.created[0].Policies[0]; got != "hush-T1-"+ids[0] {
+		t.Errorf("token policy = %q, want hush-T1-%s", got, ids[0])
+	}
+	if got := replies(); !strings.Contains(got[len(got)-1], "/s/T1/"+ids[0]) {
+		t.Errorf("reply %q does not link to /s/T1/%s", got[len(got)-1], ids[0])
+	}
+}
+
+func TestEnterpriseGridInstallation(t *testing.T) {
+	store := newFakeSecretStore()
+	tokens := &fakeTokenCreator{}
+	b, responseURL, replies := newTestBot(t, store, tokens)
+	b.cfg.SlackWorkspaces = map[string]string{"E1": "xoxb-org", "T9": "xoxb-9"}
+	b.workspaces = newWorkspaces(b.cfg, b.workspaces.fallback)
+
+	for _, tt := range []struct{ enterpriseID, teamID, want string }{
+		{"E1", "T5", "E1"},
+		{"E1", "T9", "T9"},
+		{"E1", "", "E1"},
+		{"E2", "T5", "T5"},
+		{"", "T9", "T9"},
+	} {
+		if got := b.workspaces.installation(tt.enterpriseID, tt.teamID); got != tt.want {
+			t.Errorf("installation(%q, %q) = %q, want %q", tt.enterpriseID, tt.teamID, got, tt.want)
+		}
+	}
+
+	// Any workspace of the organization shares its secrets under the
+	// enterprise ID.
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", TeamID: "T5", EnterpriseID: "E1", ResponseURL: responseURL})
+	b.inflight.Wait()
+	ids, err := listSecretIDs(context.Background(), store, b.cfg.kvPaths("E1"))
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("secrets stored for E1 = %q, %v, want one", ids, err)
+	}
+	if got := replies(); !strings.Contains(got[len(got)-1], "/s/E1/"+ids[0]) {
+		t.Errorf("reply %q does not link to /s/E1/%s", got[len(got)-1], ids[0])
+	}
+
+	b.dispatchCommand(slack.SlashCommand{Command: "/share", Text: "hunter2", UserID: "U1", TeamID: "T5", EnterpriseID: "E2", ResponseURL: responseURL})
+	b.inflight.Wait()
+	if got := replies(); got[len(got)-1] != unknownWorkspaceMessage {
+		t.Errorf("reply to another organization = %q, want %q", got[len(got)-1], unknownWorkspaceMessage)
+	}
+}
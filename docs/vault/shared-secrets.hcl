@@ -1,3 +0,0 @@
-path "secrets/data/shared/*" {
-  capabilities = ["read"]
-}